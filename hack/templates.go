@@ -0,0 +1,66 @@
+// +build ignore
+
+// templates.go compiles the app templates under pkg/cmd/templates into
+// pkg/cmd/app_templates_gen.go, run it with go generate ./pkg/cmd/ after
+// changing a template.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatal("usage: go run templates.go <templates dir> <output file>")
+	}
+	dir, output := os.Args[1], os.Args[2]
+
+	templates := map[string][]byte{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(path)
+		templates[filepath.ToSlash(name)] = data
+		return err
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names := []string{}
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("// Code generated by hack/templates.go from templates/; DO NOT EDIT.\n\npackage cmd\n\n")
+	buf.WriteString("var appTemplates = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(buf, "\t%q: %q,\n", name, templates[name])
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
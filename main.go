@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+	"time"
+
 	"github.com/alexellis/k3sup/pkg/cmd"
 	"github.com/spf13/cobra"
 )
@@ -15,6 +18,28 @@ func main() {
 
 	cmdApps := cmd.MakeApps()
 
+	cmdPlan := cmd.MakePlan()
+
+	cmdUninstall := cmd.MakeUninstall()
+
+	cmdUpgrade := cmd.MakeUpgrade()
+
+	cmdNodeToken := cmd.MakeNodeToken()
+
+	cmdGetConfig := cmd.MakeGetConfig()
+
+	cmdCerts := cmd.MakeCerts()
+
+	cmdSnapshot := cmd.MakeSnapshot()
+
+	cmdNode := cmd.MakeNode()
+
+	cmdCompletion := cmd.MakeCompletion()
+
+	cmdPreflight := cmd.MakePreflight()
+
+	cmdStatus := cmd.MakeStatus()
+
 	printk3supASCIIArt := cmd.PrintK3supASCIIArt
 
 	var rootCmd = &cobra.Command{
@@ -29,6 +54,30 @@ func main() {
 	rootCmd.AddCommand(cmdVersion)
 	rootCmd.AddCommand(cmdJoin)
 	rootCmd.AddCommand(cmdApps)
+	rootCmd.AddCommand(cmdPlan)
+	rootCmd.AddCommand(cmdUninstall)
+	rootCmd.AddCommand(cmdUpgrade)
+	rootCmd.AddCommand(cmdNodeToken)
+	rootCmd.AddCommand(cmdGetConfig)
+	rootCmd.AddCommand(cmdCerts)
+	rootCmd.AddCommand(cmdSnapshot)
+	rootCmd.AddCommand(cmdNode)
+	rootCmd.AddCommand(cmdCompletion)
+	rootCmd.AddCommand(cmdPreflight)
+	rootCmd.AddCommand(cmdStatus)
 
-	rootCmd.Execute()
+	cmd.AddConfigFlag(rootCmd)
+	cmd.AddOutputFlag(rootCmd)
+	cmd.AddLogFlags(rootCmd)
+	cmd.AddRetryFlags(rootCmd)
+	cmd.AddVerifyFlags(rootCmd)
+	cmd.AddConfirmFlags(rootCmd)
+
+	start := time.Now()
+	command, err := rootCmd.ExecuteC()
+	cmd.PrintResult(command, err, start)
+
+	if err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,65 @@
+// Package apps records what "k3sup app install" applies to a cluster, so
+// that other Go programs can list, check and uninstall the apps which k3sup
+// installed. It does not run kubectl or helm itself, give it a Cluster which
+// runs them against the cluster of the apps.
+package apps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result is the output of a kubectl command
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Cluster runs the commands which inspect and remove apps
+type Cluster interface {
+	// Kubectl runs kubectl with args
+	Kubectl(args ...string) (Result, error)
+
+	// HelmManifest returns the manifest which was applied for a helm 3 release
+	HelmManifest(release, namespace string) (string, error)
+
+	// HelmUninstall removes a helm 3 release
+	HelmUninstall(release, namespace string) error
+}
+
+// KubectlError is returned when kubectl exits with a non-zero code
+type KubectlError struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *KubectlError) Error() string {
+	if stderr := strings.TrimSpace(e.Stderr); len(stderr) > 0 {
+		return fmt.Sprintf("exit code %d: %s", e.ExitCode, stderr)
+	}
+	return fmt.Sprintf("exit code %d", e.ExitCode)
+}
+
+// NotInstalledError is returned for an app which has no state on the cluster
+type NotInstalledError struct {
+	App string
+}
+
+func (e *NotInstalledError) Error() string {
+	return fmt.Sprintf("no record of %s being installed", e.App)
+}
+
+// kubectl runs kubectl on cluster, a non-zero exit code is a *KubectlError
+func kubectl(cluster Cluster, args ...string) (Result, error) {
+	res, err := cluster.Kubectl(args...)
+	if err != nil {
+		return res, err
+	}
+
+	if res.ExitCode != 0 {
+		return res, &KubectlError{Args: args, ExitCode: res.ExitCode, Stderr: res.Stderr}
+	}
+	return res, nil
+}
@@ -0,0 +1,250 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// InventoryConfigMap holds an entry for each app installed with k3sup, so
+// that "k3sup app status" can report what is installed on a cluster.
+const InventoryConfigMap = "k3sup-apps"
+
+// InventoryNamespace is the namespace of InventoryConfigMap
+const InventoryNamespace = "kube-system"
+
+// InventoryEntry is saved as JSON under the app's name in the ConfigMap
+type InventoryEntry struct {
+	Version   string     `json:"version,omitempty"`
+	Installed time.Time  `json:"installed"`
+	Workloads []Resource `json:"workloads,omitempty"`
+}
+
+// RecordInstalled adds app to the cluster's inventory, along with the
+// Deployments and DaemonSets recorded for it, or found in its manifests and
+// releases. The version is also saved in the app's state.
+func RecordInstalled(cluster Cluster, store Store, app, version string) error {
+	if err := store.RecordVersion(app, version); err != nil {
+		return err
+	}
+
+	state, err := store.Load(app)
+	if err != nil {
+		return err
+	}
+
+	sources := append([]Manifest{}, state.Manifests...)
+
+	for _, release := range state.Releases {
+		manifest, err := cluster.HelmManifest(release.Name, release.Namespace)
+		if err != nil {
+			return err
+		}
+
+		manifestPath := filepath.Join(os.TempDir(), "k3sup-"+release.Name+"-manifest.yaml")
+		if err := ioutil.WriteFile(manifestPath, []byte(manifest), 0600); err != nil {
+			return err
+		}
+		defer os.Remove(manifestPath)
+
+		sources = append(sources, Manifest{Path: manifestPath, Namespace: release.Namespace})
+	}
+
+	entry := InventoryEntry{Version: version, Installed: time.Now().UTC()}
+
+	for _, resource := range state.Resources {
+		if resource.Kind == "deployment" || resource.Kind == "daemonset" {
+			entry.Workloads = append(entry.Workloads, resource)
+		}
+	}
+
+	for _, source := range sources {
+		parts := []string{"get", "-R", "-f", source.Path, "--output", "json"}
+		if len(source.Namespace) > 0 {
+			parts = append(parts, "--namespace", source.Namespace)
+		}
+
+		res, err := kubectl(cluster, parts...)
+		if err != nil {
+			return fmt.Errorf("unable to find workloads for %s: %s", app, err)
+		}
+
+		workloads, err := ParseWorkloads([]byte(res.Stdout))
+		if err != nil {
+			return err
+		}
+
+		entry.Workloads = append(entry.Workloads, workloads...)
+	}
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return patchInventory(cluster, app, string(value))
+}
+
+// RemoveInstalled removes app from the cluster's inventory
+func RemoveInstalled(cluster Cluster, app string) error {
+	return patchInventory(cluster, app, nil)
+}
+
+func patchInventory(cluster Cluster, app string, value interface{}) error {
+	res, err := cluster.Kubectl("get", "configmap", InventoryConfigMap, "--namespace", InventoryNamespace)
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		if value == nil {
+			return nil
+		}
+
+		if _, err := kubectl(cluster, "create", "configmap", InventoryConfigMap, "--namespace", InventoryNamespace); err != nil {
+			return fmt.Errorf("unable to create the %s ConfigMap: %s", InventoryConfigMap, err)
+		}
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{app: value},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := kubectl(cluster, "patch", "configmap", InventoryConfigMap, "--namespace", InventoryNamespace,
+		"--type", "merge", "--patch", string(patch)); err != nil {
+		return fmt.Errorf("unable to update the %s ConfigMap: %s", InventoryConfigMap, err)
+	}
+
+	return nil
+}
+
+// LoadInventory reads the inventory from the cluster, it is empty when no
+// apps have been installed
+func LoadInventory(cluster Cluster) (map[string]InventoryEntry, error) {
+	res, err := kubectl(cluster, "get", "configmap", InventoryConfigMap, "--namespace", InventoryNamespace,
+		"--ignore-not-found", "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the %s ConfigMap: %s", InventoryConfigMap, err)
+	}
+
+	return ParseInventory([]byte(res.Stdout))
+}
+
+// ParseInventory parses the InventoryConfigMap from the output of kubectl
+// get --output json, which is empty when the ConfigMap does not exist
+func ParseInventory(data []byte) (map[string]InventoryEntry, error) {
+	inventory := map[string]InventoryEntry{}
+
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return inventory, nil
+	}
+
+	configMap := struct {
+		Data map[string]string `json:"data"`
+	}{}
+
+	if err := json.Unmarshal(data, &configMap); err != nil {
+		return nil, fmt.Errorf("unable to parse the %s ConfigMap: %s", InventoryConfigMap, err)
+	}
+
+	for name, value := range configMap.Data {
+		entry := InventoryEntry{}
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, fmt.Errorf("unable to parse the inventory for %s: %s", name, err)
+		}
+		inventory[name] = entry
+	}
+
+	return inventory, nil
+}
+
+type kubeObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Items []kubeObject `json:"items"`
+}
+
+// ParseWorkloads returns the Deployments and DaemonSets from the output of
+// kubectl get --output json, which is a List when there is more than one
+// object
+func ParseWorkloads(data []byte) ([]Resource, error) {
+	object := kubeObject{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("unable to parse kubectl output: %s", err)
+	}
+
+	objects := []kubeObject{object}
+	if object.Kind == "List" {
+		objects = object.Items
+	}
+
+	workloads := []Resource{}
+	for _, o := range objects {
+		if o.Kind == "Deployment" || o.Kind == "DaemonSet" {
+			workloads = append(workloads, Resource{
+				Kind:      strings.ToLower(o.Kind),
+				Name:      o.Metadata.Name,
+				Namespace: o.Metadata.Namespace,
+			})
+		}
+	}
+
+	return workloads, nil
+}
+
+// WorkloadReady reports whether a Deployment or DaemonSet on the cluster has
+// all of its Pods ready, it is not ready when it cannot be found
+func WorkloadReady(cluster Cluster, workload Resource) (bool, error) {
+	res, err := cluster.Kubectl("get", workload.Kind, workload.Name, "--namespace", workload.Namespace, "--output", "json")
+	if err != nil {
+		return false, err
+	}
+
+	if res.ExitCode != 0 {
+		return false, nil
+	}
+
+	ready, err := parseWorkloadReady([]byte(res.Stdout))
+	return err == nil && ready, nil
+}
+
+// parseWorkloadReady reports whether a Deployment or DaemonSet has all of
+// its Pods ready, from the output of kubectl get --output json
+func parseWorkloadReady(data []byte) (bool, error) {
+	workload := struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Replicas *int `json:"replicas"`
+		} `json:"spec"`
+		Status struct {
+			ReadyReplicas          int `json:"readyReplicas"`
+			NumberReady            int `json:"numberReady"`
+			DesiredNumberScheduled int `json:"desiredNumberScheduled"`
+		} `json:"status"`
+	}{}
+
+	if err := json.Unmarshal(data, &workload); err != nil {
+		return false, fmt.Errorf("unable to parse kubectl output: %s", err)
+	}
+
+	if workload.Kind == "DaemonSet" {
+		return workload.Status.NumberReady == workload.Status.DesiredNumberScheduled, nil
+	}
+
+	replicas := 1
+	if workload.Spec.Replicas != nil {
+		replicas = *workload.Spec.Replicas
+	}
+
+	return workload.Status.ReadyReplicas >= replicas, nil
+}
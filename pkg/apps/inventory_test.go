@@ -0,0 +1,105 @@
+package apps
+
+import (
+	"testing"
+)
+
+func Test_ParseInventory(t *testing.T) {
+	data := `{"kind": "ConfigMap", "data": {"openfaas": "{\"version\":\"5.4.0\",\"installed\":\"2019-11-20T10:00:00Z\",\"workloads\":[{\"kind\":\"deployment\",\"name\":\"gateway\",\"namespace\":\"openfaas\"}]}"}}`
+
+	inventory, err := ParseInventory([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := inventory["openfaas"]
+	if !ok {
+		t.Fatalf("want openfaas in the inventory, got: %v", inventory)
+	}
+
+	if entry.Version != "5.4.0" {
+		t.Errorf("want version 5.4.0, got: %q", entry.Version)
+	}
+
+	want := Resource{Kind: "deployment", Name: "gateway", Namespace: "openfaas"}
+	if len(entry.Workloads) != 1 || entry.Workloads[0] != want {
+		t.Errorf("want workloads: [%v], got: %v", want, entry.Workloads)
+	}
+}
+
+func Test_parseInventory_missing_configmap(t *testing.T) {
+	inventory, err := ParseInventory([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inventory) != 0 {
+		t.Errorf("want an empty inventory, got: %v", inventory)
+	}
+}
+
+func Test_ParseWorkloads_list(t *testing.T) {
+	data := `{"kind": "List", "items": [
+	{"kind": "Deployment", "metadata": {"name": "gateway", "namespace": "openfaas"}},
+	{"kind": "Service", "metadata": {"name": "gateway", "namespace": "openfaas"}},
+	{"kind": "DaemonSet", "metadata": {"name": "nginx-ingress-controller", "namespace": "default"}}
+]}`
+
+	workloads, err := ParseWorkloads([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Resource{
+		{Kind: "deployment", Name: "gateway", Namespace: "openfaas"},
+		{Kind: "daemonset", Name: "nginx-ingress-controller", Namespace: "default"},
+	}
+
+	if len(workloads) != len(want) {
+		t.Fatalf("want %d workloads, got: %v", len(want), workloads)
+	}
+
+	for i := range want {
+		if workloads[i] != want[i] {
+			t.Errorf("want: %v, got: %v", want[i], workloads[i])
+		}
+	}
+}
+
+func Test_ParseWorkloads_single_object(t *testing.T) {
+	data := `{"kind": "Deployment", "metadata": {"name": "metrics-server", "namespace": "kube-system"}}`
+
+	workloads, err := ParseWorkloads([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workloads) != 1 || workloads[0].Name != "metrics-server" {
+		t.Errorf("want metrics-server, got: %v", workloads)
+	}
+}
+
+func Test_parseWorkloadReady(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"deployment ready", `{"kind": "Deployment", "spec": {"replicas": 2}, "status": {"readyReplicas": 2}}`, true},
+		{"deployment not ready", `{"kind": "Deployment", "spec": {"replicas": 2}, "status": {"readyReplicas": 1}}`, false},
+		{"deployment default replicas", `{"kind": "Deployment", "spec": {}, "status": {}}`, false},
+		{"daemonset ready", `{"kind": "DaemonSet", "status": {"desiredNumberScheduled": 3, "numberReady": 3}}`, true},
+		{"daemonset not ready", `{"kind": "DaemonSet", "status": {"desiredNumberScheduled": 3, "numberReady": 2}}`, false},
+	}
+
+	for _, c := range cases {
+		got, err := parseWorkloadReady([]byte(c.data))
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+
+		if got != c.want {
+			t.Errorf("%s: want %t, got %t", c.name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// State records what "k3sup app install" applied to the cluster so that
+// "k3sup app uninstall" can remove it again. Version is the chart or
+// manifest version last installed.
+type State struct {
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	Manifests  []Manifest `json:"manifests,omitempty"`
+	Releases   []Release  `json:"releases,omitempty"`
+	Resources  []Resource `json:"resources,omitempty"`
+	Secrets    []Resource `json:"secrets,omitempty"`
+	Namespaces []string   `json:"namespaces,omitempty"`
+	Install    *Install   `json:"install,omitempty"`
+}
+
+// Install is the app command and flags which an app was installed with,
+// so that "k3sup app upgrade" can run the install again. Omitted lists the
+// flags which may hold secrets, which are given without their values.
+type Install struct {
+	App     string   `json:"app"`
+	Flags   []string `json:"flags,omitempty"`
+	Omitted []string `json:"omitted,omitempty"`
+}
+
+// Manifest is a file, folder or URL which was passed to kubectl apply.
+// Local files are copied into the state folder, so that they can still be
+// deleted after the temporary copy has been removed. Namespace is given to
+// kubectl for objects which do not set their own.
+type Manifest struct {
+	Source    string `json:"source"`
+	Path      string `json:"path"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Release is a helm 3 release installed with --helm3
+type Release struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// Resource is an object created imperatively i.e. with kubectl create
+type Resource struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Store saves the State of each app installed on one cluster as JSON in Dir
+type Store struct {
+	Dir string
+}
+
+// NewStore returns the Store for the cluster served at server, which is
+// kept under apps/ in userDir, i.e. ~/.k3sup
+func NewStore(userDir, server string) Store {
+	return Store{Dir: filepath.Join(userDir, "apps", ClusterDirName(server))}
+}
+
+var clusterDirRegex = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// ClusterDirName returns the folder name for the state of the cluster served
+// at server, i.e. 192.168.0.100-6443 for https://192.168.0.100:6443
+func ClusterDirName(server string) string {
+	if i := strings.Index(server, "://"); i >= 0 {
+		server = server[i+3:]
+	}
+
+	return strings.Trim(clusterDirRegex.ReplaceAllString(server, "-"), "-")
+}
+
+func (s Store) statePath(name string) string {
+	return filepath.Join(s.Dir, name+".json")
+}
+
+// Load reads the state of app, which is empty when it has not been installed
+func (s Store) Load(name string) (*State, error) {
+	statePath := s.statePath(name)
+
+	data, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &State{Name: name}, nil
+		}
+		return nil, err
+	}
+
+	state := State{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", statePath, err)
+	}
+
+	return &state, nil
+}
+
+// Find reads the state of an installed app, it is a *NotInstalledError when
+// there is none
+func (s Store) Find(name string) (*State, error) {
+	names, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range names {
+		if n == name {
+			return s.Load(name)
+		}
+	}
+	return nil, &NotInstalledError{App: name}
+}
+
+// Save writes state, creating the folder of the Store if needed
+func (s Store) Save(state *State) error {
+	statePath := s.statePath(state.Name)
+
+	if err := os.MkdirAll(filepath.Dir(statePath), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(statePath, data, 0600)
+}
+
+// Remove deletes the state of app along with its copied manifests
+func (s Store) Remove(name string) error {
+	statePath := s.statePath(name)
+
+	if err := os.RemoveAll(strings.TrimSuffix(statePath, ".json")); err != nil {
+		return err
+	}
+
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns the names of the apps which have been installed
+func (s Store) List() ([]string, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	names := []string{}
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, strings.TrimSuffix(file.Name(), ".json"))
+		}
+	}
+	return names, nil
+}
+
+// RecordManifest records a manifest which was applied to namespace for app,
+// local files and folders are copied into the app's state folder.
+func (s Store) RecordManifest(app, namespace, source string) error {
+	state, err := s.Load(app)
+	if err != nil {
+		return err
+	}
+
+	index := len(state.Manifests)
+	for i, manifest := range state.Manifests {
+		if manifest.Source == source {
+			index = i
+		}
+	}
+
+	manifest := Manifest{Source: source, Path: source, Namespace: namespace}
+
+	if !IsURL(source) {
+		dest := filepath.Join(strings.TrimSuffix(s.statePath(app), ".json"), fmt.Sprintf("%02d-%s", index, filepath.Base(source)))
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+
+		if err := copyPath(source, dest); err != nil {
+			return err
+		}
+		manifest.Path = dest
+	}
+
+	if index == len(state.Manifests) {
+		state.Manifests = append(state.Manifests, manifest)
+	} else {
+		state.Manifests[index] = manifest
+	}
+
+	return s.Save(state)
+}
+
+// RecordInstall records the command and flags which app was installed with
+func (s Store) RecordInstall(app string, install Install) error {
+	state, err := s.Load(app)
+	if err != nil {
+		return err
+	}
+
+	state.Install = &install
+	return s.Save(state)
+}
+
+// RecordVersion records the version of app which was installed
+func (s Store) RecordVersion(app, version string) error {
+	state, err := s.Load(app)
+	if err != nil {
+		return err
+	}
+
+	state.Version = version
+	return s.Save(state)
+}
+
+// RecordRelease records a helm 3 release installed for app
+func (s Store) RecordRelease(app, name, namespace string) error {
+	state, err := s.Load(app)
+	if err != nil {
+		return err
+	}
+
+	release := Release{Name: name, Namespace: namespace}
+	for _, r := range state.Releases {
+		if r == release {
+			return nil
+		}
+	}
+
+	state.Releases = append(state.Releases, release)
+	return s.Save(state)
+}
+
+// RecordResource records an object created for app with kubectl create
+func (s Store) RecordResource(app, kind, name, namespace string) error {
+	state, err := s.Load(app)
+	if err != nil {
+		return err
+	}
+
+	resource := Resource{Kind: kind, Name: name, Namespace: namespace}
+	for _, r := range state.Resources {
+		if r == resource {
+			return nil
+		}
+	}
+
+	state.Resources = append(state.Resources, resource)
+	return s.Save(state)
+}
+
+// RecordSecret records a secret generated for app, it is only removed
+// by Uninstall with purge
+func (s Store) RecordSecret(app, name, namespace string) error {
+	state, err := s.Load(app)
+	if err != nil {
+		return err
+	}
+
+	secret := Resource{Kind: "secret", Name: name, Namespace: namespace}
+	for _, r := range state.Secrets {
+		if r == secret {
+			return nil
+		}
+	}
+
+	state.Secrets = append(state.Secrets, secret)
+	return s.Save(state)
+}
+
+// RecordNamespace records a namespace created for app, it is only removed
+// by Uninstall with purge
+func (s Store) RecordNamespace(app string, namespaces ...string) error {
+	state, err := s.Load(app)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces {
+		found := false
+		for _, ns := range state.Namespaces {
+			if ns == namespace {
+				found = true
+			}
+		}
+		if !found {
+			state.Namespaces = append(state.Namespaces, namespace)
+		}
+	}
+
+	return s.Save(state)
+}
+
+// IsURL reports whether a manifest is fetched from a URL, rather than read
+// from a local file or folder
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+func copyPath(src, dest string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return err
+		}
+
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		_, err = io.Copy(out, in)
+		return err
+	})
+}
@@ -0,0 +1,142 @@
+package apps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ClusterDirName(t *testing.T) {
+	cases := map[string]string{
+		"https://192.168.0.100:6443":   "192.168.0.100-6443",
+		"https://k3s.example.com:6443": "k3s.example.com-6443",
+		"https://[fd00::1]:6443":       "fd00-1-6443",
+		"https://127.0.0.1:6443/":      "127.0.0.1-6443",
+	}
+
+	for server, want := range cases {
+		if got := ClusterDirName(server); got != want {
+			t.Errorf("%s: want %q, got %q", server, want, got)
+		}
+	}
+}
+
+func Test_Store_RecordManifest_copies_local_files(t *testing.T) {
+	home, err := ioutil.TempDir("", "k3sup-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	store := NewStore(home, "https://192.168.0.100:6443")
+
+	manifest := filepath.Join(home, "app.yaml")
+	if err := ioutil.WriteFile(manifest, []byte("kind: Namespace"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RecordManifest("test-app", "", manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordManifest("test-app", "", "https://example.com/crds.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordSecret("test-app", "basic-auth", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordSecret("test-app", "basic-auth", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := store.Load("test-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(state.Manifests) != 2 {
+		t.Fatalf("want 2 manifests, got %d", len(state.Manifests))
+	}
+
+	got, err := ioutil.ReadFile(state.Manifests[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "kind: Namespace" {
+		t.Errorf("want copied manifest, got: %q", string(got))
+	}
+
+	if state.Manifests[1].Path != "https://example.com/crds.yaml" {
+		t.Errorf("want URL to be recorded as-is, got: %q", state.Manifests[1].Path)
+	}
+
+	if len(state.Secrets) != 1 {
+		t.Errorf("want 1 secret, got %d", len(state.Secrets))
+	}
+
+	names, _ := store.List()
+	if len(names) != 1 || names[0] != "test-app" {
+		t.Errorf("want [test-app], got %v", names)
+	}
+
+	if err := store.Remove("test-app"); err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ = store.List()
+	if len(names) != 0 {
+		t.Errorf("want no apps after removal, got %v", names)
+	}
+}
+
+func Test_Store_RecordRelease_is_recorded_once(t *testing.T) {
+	home, err := ioutil.TempDir("", "k3sup-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	store := NewStore(home, "https://192.168.0.100:6443")
+
+	for i := 0; i < 2; i++ {
+		if err := store.RecordRelease("openfaas", "openfaas", "openfaas"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state, err := store.Load("openfaas")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(state.Releases) != 1 {
+		t.Fatalf("want 1 release, got %d", len(state.Releases))
+	}
+
+	if state.Releases[0] != (Release{Name: "openfaas", Namespace: "openfaas"}) {
+		t.Errorf("unexpected release: %v", state.Releases[0])
+	}
+}
+
+func Test_Store_RecordManifest_records_namespace(t *testing.T) {
+	home, err := ioutil.TempDir("", "k3sup-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	store := NewStore(home, "https://192.168.0.100:6443")
+
+	if err := store.RecordManifest("test-app", "argocd", "https://example.com/install.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := store.Load("test-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(state.Manifests) != 1 || state.Manifests[0].Namespace != "argocd" {
+		t.Errorf("want the manifest recorded with its namespace, got: %v", state.Manifests)
+	}
+}
@@ -0,0 +1,63 @@
+package apps
+
+import "fmt"
+
+// Uninstall deletes, in reverse order, everything recorded in the state of
+// app, then removes it from the inventory and the store. Generated secrets
+// and namespaces are only deleted when purge is set.
+func Uninstall(cluster Cluster, store Store, app string, purge bool) error {
+	state, err := store.Find(app)
+	if err != nil {
+		return err
+	}
+
+	for i := len(state.Releases) - 1; i >= 0; i-- {
+		release := state.Releases[i]
+		if err := cluster.HelmUninstall(release.Name, release.Namespace); err != nil {
+			return fmt.Errorf("unable to uninstall release %s: %s", release.Name, err)
+		}
+	}
+
+	for i := len(state.Manifests) - 1; i >= 0; i-- {
+		manifest := state.Manifests[i]
+		parts := []string{"delete", "--ignore-not-found", "-R", "-f", manifest.Path}
+		if len(manifest.Namespace) > 0 {
+			parts = append(parts, "--namespace", manifest.Namespace)
+		}
+
+		if _, err := kubectl(cluster, parts...); err != nil {
+			return fmt.Errorf("unable to delete %s: %s", manifest.Source, err)
+		}
+	}
+
+	resources := state.Resources
+	if purge {
+		resources = append(resources, state.Secrets...)
+	}
+
+	for i := len(resources) - 1; i >= 0; i-- {
+		resource := resources[i]
+		parts := []string{"delete", "--ignore-not-found", resource.Kind, resource.Name}
+		if len(resource.Namespace) > 0 {
+			parts = append(parts, "--namespace", resource.Namespace)
+		}
+
+		if _, err := kubectl(cluster, parts...); err != nil {
+			return fmt.Errorf("unable to delete %s/%s: %s", resource.Kind, resource.Name, err)
+		}
+	}
+
+	if purge {
+		for i := len(state.Namespaces) - 1; i >= 0; i-- {
+			if _, err := kubectl(cluster, "delete", "--ignore-not-found", "namespace", state.Namespaces[i]); err != nil {
+				return fmt.Errorf("unable to delete namespace %s: %s", state.Namespaces[i], err)
+			}
+		}
+	}
+
+	if err := RemoveInstalled(cluster, app); err != nil {
+		return err
+	}
+
+	return store.Remove(app)
+}
@@ -0,0 +1,74 @@
+package apps
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeCluster records the commands it is given, kubectl get of the
+// inventory ConfigMap exits with 1 as though it was not found
+type fakeCluster struct {
+	commands []string
+}
+
+func (f *fakeCluster) Kubectl(args ...string) (Result, error) {
+	f.commands = append(f.commands, "kubectl "+strings.Join(args, " "))
+	if args[0] == "get" {
+		return Result{ExitCode: 1}, nil
+	}
+	return Result{}, nil
+}
+
+func (f *fakeCluster) HelmManifest(release, namespace string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeCluster) HelmUninstall(release, namespace string) error {
+	f.commands = append(f.commands, "helm uninstall "+release+" --namespace "+namespace)
+	return nil
+}
+
+func Test_Uninstall_deletes_in_reverse_order(t *testing.T) {
+	home, err := ioutil.TempDir("", "k3sup-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	store := NewStore(home, "https://192.168.0.100:6443")
+	store.RecordRelease("openfaas", "openfaas", "openfaas")
+	store.RecordManifest("openfaas", "", "https://example.com/namespaces.yml")
+	store.RecordSecret("openfaas", "basic-auth", "openfaas")
+	store.RecordNamespace("openfaas", "openfaas-fn")
+
+	cluster := &fakeCluster{}
+	if err := Uninstall(cluster, store, "openfaas", true); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"helm uninstall openfaas --namespace openfaas",
+		"kubectl delete --ignore-not-found -R -f https://example.com/namespaces.yml",
+		"kubectl delete --ignore-not-found secret basic-auth --namespace openfaas",
+		"kubectl delete --ignore-not-found namespace openfaas-fn",
+		"kubectl get configmap k3sup-apps --namespace kube-system",
+	}
+	if strings.Join(cluster.commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(cluster.commands, "\n"))
+	}
+
+	if names, _ := store.List(); len(names) != 0 {
+		t.Errorf("want the state to be removed, got: %v", names)
+	}
+}
+
+func Test_Uninstall_not_installed(t *testing.T) {
+	store := Store{Dir: "/does/not/exist"}
+
+	err := Uninstall(&fakeCluster{}, store, "openfaas", false)
+	if _, ok := err.(*NotInstalledError); !ok {
+		t.Errorf("want a *NotInstalledError, got: %v", err)
+	}
+}
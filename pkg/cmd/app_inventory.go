@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/apps"
+)
+
+// recordInstalled adds app to the cluster's inventory, along with the
+// Deployments and DaemonSets recorded for it, or found in its manifests and
+// releases. The version is also saved in the app's state.
+func recordInstalled(app, version string) error {
+	store, err := appStore()
+	if err != nil {
+		return err
+	}
+	return apps.RecordInstalled(kubectlCluster{}, store, app, version)
+}
+
+// loadInventory reads the inventory from the cluster, it is empty when no
+// apps have been installed
+func loadInventory() (map[string]apps.InventoryEntry, error) {
+	return apps.LoadInventory(kubectlCluster{})
+}
+
+// chartVersion reads the version from a chart's Chart.yaml, it is empty
+// when the file cannot be read
+func chartVersion(basePath, chart string) string {
+	file, err := os.Open(path.Join(basePath, chart, "Chart.yaml"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "version:") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "version:")), `"'`)
+		}
+	}
+
+	return ""
+}
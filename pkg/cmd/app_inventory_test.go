@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_chartVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k3sup-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(path.Join(dir, "openfaas"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	chart := "apiVersion: v1\nappVersion: 0.18.6\nname: openfaas\nversion: 5.4.0\n"
+	if err := ioutil.WriteFile(path.Join(dir, "openfaas", "Chart.yaml"), []byte(chart), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := chartVersion(dir, "openfaas"); got != "5.4.0" {
+		t.Errorf("want 5.4.0, got: %q", got)
+	}
+
+	if got := chartVersion(dir, "missing"); got != "" {
+		t.Errorf("want no version for a missing chart, got: %q", got)
+	}
+}
@@ -0,0 +1,705 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	execute "github.com/alexellis/go-execute/pkg/v1"
+	"github.com/spf13/cobra"
+)
+
+// appRepo is a catalog of app definitions, from a git repo or an OCI
+// artifact. The catalogs which were added are saved in ~/.k3sup/repos.json
+// and fetched to a folder of the same name under ~/.k3sup/repos/.
+type appRepo struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Ref  string `json:"ref,omitempty"`
+}
+
+// appDefinition is read from the app.json of each folder in a catalog. The
+// folder's manifest.yaml is a template which is given the app's Namespace,
+// Version and Flags, a map of each flag's name to its value.
+type appDefinition struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Version     string    `json:"version,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Info        string    `json:"info,omitempty"`
+	Flags       []appFlag `json:"flags,omitempty"`
+}
+
+// appFlag is a flag of an app from a catalog, its Type is string, bool or
+// int. A Required flag has to be given unless it has a Default. A Sensitive
+// flag may hold a secret, so its value is not saved for "k3sup app upgrade".
+type appFlag struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Sensitive   bool   `json:"sensitive,omitempty"`
+}
+
+// repoApp is an app definition along with the folder it was loaded from
+type repoApp struct {
+	Repo       string
+	Dir        string
+	Definition appDefinition
+}
+
+var appNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// reservedAppFlags are added to every app by addInstallFlags or MakeApps,
+// so they can not be declared by an app from a catalog
+var reservedAppFlags = []string{"set", "helm3", "dry-run", "output-file", "values", "skip-preflight", "wait", "timeout", "kubeconfig"}
+
+func makeAppRepo() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "repo",
+		Short: "Manage catalogs of apps from other authors",
+		Long: `Manage catalogs of app definitions from a git repo or an OCI artifact. Each
+folder of a catalog with an app.json and a manifest.yaml template is an app
+which can be installed with "k3sup app install".`,
+		Example: `  k3sup app repo add https://github.com/example/k3sup-apps
+  k3sup app repo add oci://ghcr.io/example/k3sup-apps:v1
+  k3sup app repo list`,
+		SilenceUsage: false,
+	}
+
+	var add = &cobra.Command{
+		Use:   "add URL",
+		Short: "Add a catalog of apps",
+		Long: `Add a catalog of apps from a git repo, which is cloned with git, or from an
+OCI artifact given as oci://, which is pulled with oras.`,
+		Example: `  k3sup app repo add https://github.com/example/k3sup-apps
+  k3sup app repo add https://github.com/example/k3sup-apps --name example --ref v1.0.0
+  k3sup app repo add oci://ghcr.io/example/k3sup-apps:v1`,
+		SilenceUsage: true,
+	}
+
+	add.Flags().String("name", "", "The name of the catalog (Default to the last part of the URL)")
+	add.Flags().String("ref", "", "The branch or tag to clone from a git repo (Default to the default branch)")
+
+	add.RunE = func(command *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("give the URL of a git repo or an OCI artifact, i.e. k3sup app repo add https://github.com/example/k3sup-apps")
+		}
+
+		name, _ := command.Flags().GetString("name")
+		ref, _ := command.Flags().GetString("ref")
+
+		repo := appRepo{Name: name, URL: args[0], Ref: ref}
+		if len(repo.Name) == 0 {
+			repo.Name = repoNameFromURL(repo.URL)
+		}
+
+		if err := validAppRepo(repo); err != nil {
+			return err
+		}
+
+		repos, err := loadAppRepos()
+		if err != nil {
+			return err
+		}
+
+		for _, existing := range repos {
+			if existing.Name == repo.Name {
+				return fmt.Errorf("a catalog named %s has already been added from %s, remove it first or give --name", repo.Name, existing.URL)
+			}
+		}
+
+		apps, err := fetchAppRepo(repo)
+		if err != nil {
+			return err
+		}
+
+		if err := saveAppRepos(append(repos, repo)); err != nil {
+			return err
+		}
+
+		names := []string{}
+		for _, app := range apps {
+			names = append(names, app.Definition.Name)
+		}
+
+		logInfof("Added %s with %d apps: %s\n", repo.Name, len(apps), strings.Join(names, ", "))
+		return nil
+	}
+
+	var list = &cobra.Command{
+		Use:          "list",
+		Short:        "List the catalogs which have been added",
+		Example:      `  k3sup app repo list`,
+		SilenceUsage: true,
+	}
+
+	list.RunE = func(command *cobra.Command, args []string) error {
+		repos, err := loadAppRepos()
+		if err != nil {
+			return err
+		}
+
+		if len(repos) == 0 {
+			fmt.Fprintln(textOutput, "No catalogs have been added, add one with \"k3sup app repo add\"")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(textOutput, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tURL\tREF\tAPPS")
+
+		for _, repo := range repos {
+			apps, err := loadRepoApps(repo.Name, appRepoDir(repo.Name))
+			count := strconv.Itoa(len(apps))
+			if err != nil {
+				count = "error: " + err.Error()
+			}
+
+			ref := repo.Ref
+			if len(ref) == 0 {
+				ref = "-"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", repo.Name, repo.URL, ref, count)
+		}
+
+		return w.Flush()
+	}
+
+	var update = &cobra.Command{
+		Use:          "update [NAME]",
+		Short:        "Fetch the latest apps of each catalog, or of one",
+		Example:      `  k3sup app repo update`,
+		SilenceUsage: true,
+	}
+
+	update.RunE = func(command *cobra.Command, args []string) error {
+		repos, err := loadAppRepos()
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, repo := range repos {
+			if len(args) > 0 && repo.Name != args[0] {
+				continue
+			}
+			found = true
+
+			apps, err := fetchAppRepo(repo)
+			if err != nil {
+				return err
+			}
+			logInfof("Updated %s, %d apps\n", repo.Name, len(apps))
+		}
+
+		if len(args) > 0 && !found {
+			return fmt.Errorf("no catalog named %s, run \"k3sup app repo list\" to see them", args[0])
+		}
+		return nil
+	}
+
+	var remove = &cobra.Command{
+		Use:   "remove NAME",
+		Short: "Remove a catalog",
+		Long: `Remove a catalog, apps which were installed from it are left on the
+cluster and can still be removed with "k3sup app uninstall".`,
+		Example:      `  k3sup app repo remove example`,
+		SilenceUsage: true,
+	}
+
+	remove.RunE = func(command *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("give the name of a catalog, run \"k3sup app repo list\" to see them")
+		}
+
+		repos, err := loadAppRepos()
+		if err != nil {
+			return err
+		}
+
+		kept := []appRepo{}
+		for _, repo := range repos {
+			if repo.Name != args[0] {
+				kept = append(kept, repo)
+			}
+		}
+
+		if len(kept) == len(repos) {
+			return fmt.Errorf("no catalog named %s, run \"k3sup app repo list\" to see them", args[0])
+		}
+
+		if err := os.RemoveAll(appRepoDir(args[0])); err != nil {
+			return err
+		}
+
+		if err := saveAppRepos(kept); err != nil {
+			return err
+		}
+
+		logInfof("Removed %s\n", args[0])
+		return nil
+	}
+
+	command.AddCommand(add)
+	command.AddCommand(list)
+	command.AddCommand(update)
+	command.AddCommand(remove)
+
+	return command
+}
+
+// appReposPath is read each time k3sup starts, so it is found from HOME as
+// for localBinary rather than with InitUserDir, which creates folders
+func appReposPath() string {
+	return path.Join(os.Getenv("HOME"), ".k3sup", "repos.json")
+}
+
+func appRepoDir(name string) string {
+	return path.Join(os.Getenv("HOME"), ".k3sup", "repos", name)
+}
+
+func loadAppRepos() ([]appRepo, error) {
+	repos := []appRepo{}
+
+	data, err := ioutil.ReadFile(appReposPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return repos, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &repos); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", appReposPath(), err)
+	}
+
+	return repos, nil
+}
+
+func saveAppRepos(repos []appRepo) error {
+	if err := os.MkdirAll(path.Dir(appReposPath()), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(repos, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(appReposPath(), data, 0600)
+}
+
+// repoNameFromURL returns the last part of a git URL or OCI reference,
+// without .git or a tag
+func repoNameFromURL(url string) string {
+	name := strings.TrimRight(url, "/")
+	if index := strings.LastIndex(name, "/"); index > -1 {
+		name = name[index+1:]
+	}
+	if strings.HasPrefix(url, "oci://") {
+		if index := strings.IndexAny(name, ":@"); index > -1 {
+			name = name[:index]
+		}
+	}
+	return strings.TrimSuffix(name, ".git")
+}
+
+func validAppRepo(repo appRepo) error {
+	if !appNameRegex.MatchString(repo.Name) {
+		return fmt.Errorf("invalid catalog name %q, give --name with lower-case letters, digits and hyphens", repo.Name)
+	}
+
+	if strings.HasPrefix(repo.URL, "oci://") {
+		if len(repo.Ref) > 0 {
+			return fmt.Errorf("--ref is only for a git repo, give the tag of an OCI artifact in its URL")
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(repo.URL, "-") {
+		return fmt.Errorf("invalid URL: %q", repo.URL)
+	}
+	return nil
+}
+
+// repoFetchTask returns the command to fetch repo to dir, an OCI artifact
+// is pulled with oras and a git repo is cloned without its history
+func repoFetchTask(repo appRepo, dir string) execute.ExecTask {
+	if strings.HasPrefix(repo.URL, "oci://") {
+		return execute.ExecTask{
+			Command: "oras",
+			Args:    []string{"pull", strings.TrimPrefix(repo.URL, "oci://"), "--output", dir},
+			Env:     os.Environ(),
+		}
+	}
+
+	args := []string{"clone", "--depth", "1"}
+	if len(repo.Ref) > 0 {
+		args = append(args, "--branch", repo.Ref)
+	}
+
+	return execute.ExecTask{
+		Command: "git",
+		Args:    append(args, "--", repo.URL, dir),
+		Env:     os.Environ(),
+	}
+}
+
+// fetchAppRepo fetches repo to a new folder, which only replaces the last
+// copy once its apps have been loaded
+func fetchAppRepo(repo appRepo) ([]repoApp, error) {
+	dir := appRepoDir(repo.Name)
+	tmp := dir + ".download"
+
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(path.Dir(dir), 0700); err != nil {
+		return nil, err
+	}
+
+	task := repoFetchTask(repo, tmp)
+	logInfof("Fetching %s from %s\n", repo.Name, repo.URL)
+
+	res, err := runTask(task)
+	if err != nil {
+		return nil, fmt.Errorf("unable to run %s, is it installed? %s", task.Command, err)
+	}
+
+	if res.ExitCode != 0 {
+		os.RemoveAll(tmp)
+		return nil, fmt.Errorf("unable to fetch %s: %s", repo.URL, strings.TrimSpace(res.Stderr))
+	}
+
+	apps, err := loadRepoApps(repo.Name, tmp)
+	if err == nil && len(apps) == 0 {
+		err = fmt.Errorf("no apps found in %s, each app needs a folder with an app.json and manifest.yaml", repo.URL)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return nil, err
+	}
+
+	for i := range apps {
+		apps[i].Dir = path.Join(dir, path.Base(apps[i].Dir))
+	}
+	return apps, nil
+}
+
+// loadRepoApps reads the app definitions of each folder in dir, folders
+// without an app.json are skipped
+func loadRepoApps(repo, dir string) ([]repoApp, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := []repoApp{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		appDir := path.Join(dir, entry.Name())
+		data, err := ioutil.ReadFile(path.Join(appDir, "app.json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		definition := appDefinition{}
+		if err := json.Unmarshal(data, &definition); err != nil {
+			return nil, fmt.Errorf("unable to parse %s/app.json: %s", entry.Name(), err)
+		}
+
+		if err := validAppDefinition(definition); err != nil {
+			return nil, fmt.Errorf("%s/app.json: %s", entry.Name(), err)
+		}
+
+		if _, err := os.Stat(path.Join(appDir, "manifest.yaml")); err != nil {
+			return nil, fmt.Errorf("%s has no manifest.yaml", entry.Name())
+		}
+
+		apps = append(apps, repoApp{Repo: repo, Dir: appDir, Definition: definition})
+	}
+
+	return apps, nil
+}
+
+func validAppDefinition(definition appDefinition) error {
+	if !appNameRegex.MatchString(definition.Name) {
+		return fmt.Errorf("invalid name %q, use lower-case letters, digits and hyphens", definition.Name)
+	}
+
+	declared := map[string]bool{}
+	for _, flag := range definition.Flags {
+		if !appNameRegex.MatchString(flag.Name) {
+			return fmt.Errorf("invalid flag name %q", flag.Name)
+		}
+
+		for _, reserved := range reservedAppFlags {
+			if flag.Name == reserved {
+				return fmt.Errorf("--%s is added to every app, so it can not be declared", flag.Name)
+			}
+		}
+
+		if declared[flag.Name] {
+			return fmt.Errorf("--%s is declared twice", flag.Name)
+		}
+		declared[flag.Name] = true
+
+		switch flag.Type {
+		case "", "string":
+		case "bool":
+			if _, err := strconv.ParseBool(flag.Default); len(flag.Default) > 0 && err != nil {
+				return fmt.Errorf("invalid default for --%s: %q", flag.Name, flag.Default)
+			}
+		case "int":
+			if _, err := strconv.Atoi(flag.Default); len(flag.Default) > 0 && err != nil {
+				return fmt.Errorf("invalid default for --%s: %q", flag.Name, flag.Default)
+			}
+		default:
+			return fmt.Errorf("unknown type %q for --%s, use string, bool or int", flag.Type, flag.Name)
+		}
+	}
+
+	return nil
+}
+
+// addRepoApps adds the apps of each catalog to install. A built-in app, or
+// one from a catalog added before, keeps its name. A catalog which can not
+// be read is skipped with a warning, so that it does not stop k3sup.
+func addRepoApps(install *cobra.Command) {
+	repos, err := loadAppRepos()
+	if err != nil {
+		logWarnf("Unable to load the app catalogs: %s\n", err)
+		return
+	}
+
+	taken := map[string]bool{}
+	for _, app := range install.Commands() {
+		taken[app.Name()] = true
+	}
+
+	for _, repo := range repos {
+		apps, err := loadRepoApps(repo.Name, appRepoDir(repo.Name))
+		if err != nil {
+			logWarnf("Unable to load the apps of %s, run \"k3sup app repo update %s\": %s\n", repo.Name, repo.Name, err)
+			continue
+		}
+
+		for _, app := range apps {
+			if taken[app.Definition.Name] {
+				logDebugf("Skipping %s from %s, an app of the same name is already installable\n", app.Definition.Name, repo.Name)
+				continue
+			}
+
+			taken[app.Definition.Name] = true
+			install.AddCommand(makeInstallRepoApp(app))
+		}
+	}
+}
+
+// repoAppInfo returns the Info of each app from a catalog
+func repoAppInfo() map[string]string {
+	info := map[string]string{}
+
+	repos, _ := loadAppRepos()
+	for _, repo := range repos {
+		apps, _ := loadRepoApps(repo.Name, appRepoDir(repo.Name))
+		for _, app := range apps {
+			if _, ok := info[app.Definition.Name]; !ok {
+				info[app.Definition.Name] = app.Definition.Info
+			}
+		}
+	}
+
+	return info
+}
+
+func makeInstallRepoApp(app repoApp) *cobra.Command {
+	definition := app.Definition
+
+	var command = &cobra.Command{
+		Use:          definition.Name,
+		Short:        fmt.Sprintf("%s (from %s)", definition.Description, app.Repo),
+		Long:         definition.Description,
+		Example:      fmt.Sprintf("  k3sup app install %s", definition.Name),
+		SilenceUsage: true,
+	}
+
+	declared := map[string]bool{}
+	for _, flag := range definition.Flags {
+		declared[flag.Name] = true
+
+		description := flag.Description
+		if flag.Required {
+			description += " (required)"
+		}
+
+		switch flag.Type {
+		case "bool":
+			value, _ := strconv.ParseBool(flag.Default)
+			command.Flags().Bool(flag.Name, value, description)
+		case "int":
+			value, _ := strconv.Atoi(flag.Default)
+			command.Flags().Int(flag.Name, value, description)
+		default:
+			command.Flags().String(flag.Name, flag.Default, description)
+		}
+
+		if flag.Sensitive {
+			markSensitive(command, flag.Name)
+		}
+	}
+
+	if !declared["namespace"] && len(definition.Namespace) > 0 {
+		command.Flags().StringP("namespace", "n", definition.Namespace, "The namespace to install the app")
+	}
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		manifest, namespace, version, err := renderRepoApp(app, command)
+		if err != nil {
+			return err
+		}
+
+		manifestFile, err := writeTempFile(definition.Name, manifest)
+		if err != nil {
+			return err
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return printManifests(command, manifestFile)
+		}
+
+		if len(namespace) > 0 {
+			if err := createNamespace(definition.Name, namespace); err != nil {
+				return err
+			}
+		}
+
+		if err := applyNamespacedManifests(definition.Name, namespace, manifestFile); err != nil {
+			return err
+		}
+
+		if err := recordInstalled(definition.Name, version); err != nil {
+			return err
+		}
+
+		logInfo(`=======================================================================
+= ` + definition.Name + ` has been installed from ` + app.Repo + `.
+=======================================================================
+
+` + definition.Info + `
+
+` + thanksForUsing)
+
+		return nil
+	}
+
+	return command
+}
+
+// renderRepoApp executes the app's manifest.yaml with the values of its
+// flags, and returns it with the namespace and version to record
+func renderRepoApp(app repoApp, command *cobra.Command) ([]byte, string, string, error) {
+	definition := app.Definition
+
+	flags := map[string]interface{}{}
+	for _, flag := range definition.Flags {
+		if flag.Required && len(flag.Default) == 0 && !command.Flags().Changed(flag.Name) {
+			return nil, "", "", fmt.Errorf("--%s is required for %s", flag.Name, definition.Name)
+		}
+
+		switch flag.Type {
+		case "bool":
+			flags[flag.Name], _ = command.Flags().GetBool(flag.Name)
+		case "int":
+			flags[flag.Name], _ = command.Flags().GetInt(flag.Name)
+		default:
+			flags[flag.Name], _ = command.Flags().GetString(flag.Name)
+		}
+	}
+
+	namespace := definition.Namespace
+	if command.Flags().Lookup("namespace") != nil {
+		namespace, _ = command.Flags().GetString("namespace")
+	}
+
+	version := definition.Version
+	if value, ok := flags["version"].(string); ok && len(value) > 0 {
+		version = value
+	}
+
+	text, err := ioutil.ReadFile(path.Join(app.Dir, "manifest.yaml"))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	tmpl, err := template.New(definition.Name).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("unable to parse the manifest.yaml of %s: %s", definition.Name, err)
+	}
+
+	var manifest bytes.Buffer
+	err = tmpl.Execute(&manifest, struct {
+		Namespace string
+		Version   string
+		Flags     map[string]interface{}
+	}{namespace, version, flags})
+	if err != nil {
+		return nil, "", "", fmt.Errorf("unable to render the manifest.yaml of %s: %s", definition.Name, err)
+	}
+
+	return manifest.Bytes(), namespace, version, nil
+}
+
+// repoAppNames returns the names of the apps from catalogs, for the list
+// printed by "k3sup app install"
+func repoAppNames(install *cobra.Command) []string {
+	builtin := map[string]bool{}
+	for _, name := range getApps() {
+		builtin[name] = true
+	}
+
+	names := []string{}
+	for _, app := range install.Commands() {
+		if !builtin[app.Name()] {
+			names = append(names, app.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
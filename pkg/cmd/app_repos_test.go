@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_repoNameFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/example/k3sup-apps":     "k3sup-apps",
+		"https://github.com/example/k3sup-apps.git": "k3sup-apps",
+		"https://github.com/example/k3sup-apps/":    "k3sup-apps",
+		"git@github.com:example/k3sup-apps.git":     "k3sup-apps",
+		"oci://ghcr.io/example/k3sup-apps:v1":       "k3sup-apps",
+		"oci://ghcr.io/example/apps@sha256:abcd":    "apps",
+	}
+
+	for url, want := range tests {
+		if got := repoNameFromURL(url); got != want {
+			t.Errorf("%s: want %q, got %q", url, want, got)
+		}
+	}
+}
+
+func Test_validAppRepo(t *testing.T) {
+	if err := validAppRepo(appRepo{Name: "apps", URL: "https://github.com/example/apps", Ref: "v1"}); err != nil {
+		t.Errorf("want no error, got %s", err)
+	}
+
+	invalid := []appRepo{
+		{Name: "Apps", URL: "https://github.com/example/Apps"},
+		{Name: "apps", URL: "oci://ghcr.io/example/apps:v1", Ref: "v1"},
+		{Name: "apps", URL: "--upload-pack=touch"},
+	}
+	for _, repo := range invalid {
+		if err := validAppRepo(repo); err == nil {
+			t.Errorf("%v: want an error", repo)
+		}
+	}
+}
+
+func Test_repoFetchTask_Git(t *testing.T) {
+	task := repoFetchTask(appRepo{Name: "apps", URL: "https://github.com/example/apps", Ref: "v1"}, "/tmp/apps")
+
+	want := []string{"clone", "--depth", "1", "--branch", "v1", "--", "https://github.com/example/apps", "/tmp/apps"}
+	if task.Command != "git" || !reflect.DeepEqual(task.Args, want) {
+		t.Errorf("want git %v, got %s %v", want, task.Command, task.Args)
+	}
+}
+
+func Test_repoFetchTask_OCI(t *testing.T) {
+	task := repoFetchTask(appRepo{Name: "apps", URL: "oci://ghcr.io/example/apps:v1"}, "/tmp/apps")
+
+	want := []string{"pull", "ghcr.io/example/apps:v1", "--output", "/tmp/apps"}
+	if task.Command != "oras" || !reflect.DeepEqual(task.Args, want) {
+		t.Errorf("want oras %v, got %s %v", want, task.Command, task.Args)
+	}
+}
+
+func Test_validAppDefinition(t *testing.T) {
+	valid := appDefinition{Name: "hello", Flags: []appFlag{
+		{Name: "replicas", Type: "int", Default: "2"},
+		{Name: "debug", Type: "bool"},
+		{Name: "image"},
+	}}
+	if err := validAppDefinition(valid); err != nil {
+		t.Errorf("want no error, got %s", err)
+	}
+
+	invalid := map[string]appDefinition{
+		"name":     {Name: "Hello"},
+		"reserved": {Name: "hello", Flags: []appFlag{{Name: "dry-run"}}},
+		"twice":    {Name: "hello", Flags: []appFlag{{Name: "image"}, {Name: "image"}}},
+		"type":     {Name: "hello", Flags: []appFlag{{Name: "size", Type: "float"}}},
+		"default":  {Name: "hello", Flags: []appFlag{{Name: "replicas", Type: "int", Default: "two"}}},
+	}
+	for name, definition := range invalid {
+		if err := validAppDefinition(definition); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
+
+func writeTestCatalog(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "k3sup-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"hello/app.json": `{"name": "hello", "description": "Say hello", "version": "1.0", "namespace": "hello",
+  "flags": [{"name": "replicas", "type": "int", "default": "2"}, {"name": "message", "required": true}]}`,
+		"hello/manifest.yaml": `namespace: {{.Namespace}}
+version: {{.Version}}
+replicas: {{.Flags.replicas}}
+message: {{.Flags.message}}
+`,
+		"docs/README.md": "not an app",
+	}
+
+	for name, data := range files {
+		os.MkdirAll(path.Join(dir, path.Dir(name)), 0700)
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func Test_loadRepoApps(t *testing.T) {
+	dir := writeTestCatalog(t)
+	defer os.RemoveAll(dir)
+
+	apps, err := loadRepoApps("example", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(apps) != 1 || apps[0].Definition.Name != "hello" || apps[0].Repo != "example" {
+		t.Errorf("want the hello app from example, got %v", apps)
+	}
+}
+
+func Test_loadRepoApps_NoManifest(t *testing.T) {
+	dir := writeTestCatalog(t)
+	defer os.RemoveAll(dir)
+
+	os.Remove(path.Join(dir, "hello", "manifest.yaml"))
+
+	if _, err := loadRepoApps("example", dir); err == nil || !strings.Contains(err.Error(), "manifest.yaml") {
+		t.Errorf("want an error for the missing manifest.yaml, got %v", err)
+	}
+}
+
+func Test_renderRepoApp(t *testing.T) {
+	dir := writeTestCatalog(t)
+	defer os.RemoveAll(dir)
+
+	apps, err := loadRepoApps("example", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	command := makeInstallRepoApp(apps[0])
+	command.Flags().Set("message", "hi")
+	command.Flags().Set("namespace", "other")
+
+	manifest, namespace, version, err := renderRepoApp(apps[0], command)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "namespace: other\nversion: 1.0\nreplicas: 2\nmessage: hi\n"
+	if string(manifest) != want {
+		t.Errorf("want manifest:\n%s\ngot:\n%s", want, manifest)
+	}
+	if namespace != "other" || version != "1.0" {
+		t.Errorf("want namespace other and version 1.0, got %q and %q", namespace, version)
+	}
+}
+
+func Test_renderRepoApp_Required(t *testing.T) {
+	dir := writeTestCatalog(t)
+	defer os.RemoveAll(dir)
+
+	apps, err := loadRepoApps("example", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, err = renderRepoApp(apps[0], makeInstallRepoApp(apps[0]))
+	if err == nil || !strings.Contains(err.Error(), "--message is required") {
+		t.Errorf("want an error for --message, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alexellis/k3sup/pkg/apps"
+	"github.com/alexellis/k3sup/pkg/config"
+)
+
+// appCluster returns the API server of the current context, which is given
+// by the KUBECONFIG of the app command. It is looked up once per kubeconfig.
+var appCluster = func() func() (string, error) {
+	var (
+		lock    sync.Mutex
+		servers = map[string]string{}
+	)
+
+	return func() (string, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		kubeconfig := getDefaultKubeconfig()
+		if server, ok := servers[kubeconfig]; ok {
+			return server, nil
+		}
+
+		res, err := kubectlTask("config", "view", "--minify", "--output", "jsonpath={.clusters[0].cluster.server}")
+		if err != nil {
+			return "", err
+		}
+
+		server := strings.TrimSpace(res.Stdout)
+		if res.ExitCode != 0 || len(server) == 0 {
+			return "", fmt.Errorf("unable to find the cluster of the current context in %s: %s", kubeconfig, strings.TrimSpace(res.Stderr))
+		}
+
+		servers[kubeconfig] = server
+		return server, nil
+	}
+}()
+
+// appStore returns the store for the apps of the cluster of the current
+// context, their state is kept under ~/.k3sup/apps/<cluster>/
+func appStore() (apps.Store, error) {
+	userPath, err := config.InitUserDir()
+	if err != nil {
+		return apps.Store{}, err
+	}
+
+	server, err := appCluster()
+	if err != nil {
+		return apps.Store{}, err
+	}
+
+	return apps.NewStore(userPath, server), nil
+}
+
+// kubectlCluster runs kubectl and helm for pkg/apps, against the
+// KUBECONFIG of the app command
+type kubectlCluster struct{}
+
+func (kubectlCluster) Kubectl(args ...string) (apps.Result, error) {
+	res, err := kubectlTask(args...)
+	return apps.Result{Stdout: res.Stdout, Stderr: res.Stderr, ExitCode: res.ExitCode}, err
+}
+
+func (kubectlCluster) HelmManifest(release, namespace string) (string, error) {
+	return helm3Manifest(release, namespace)
+}
+
+func (kubectlCluster) HelmUninstall(release, namespace string) error {
+	return helm3Uninstall(release, namespace)
+}
+
+func loadAppState(name string) (*apps.State, error) {
+	store, err := appStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Load(name)
+}
+
+// listAppStates returns the names of apps which have been installed on the
+// cluster of the current context
+func listAppStates() ([]string, error) {
+	store, err := appStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.List()
+}
+
+// recordManifest records a manifest applied for app, local files and
+// folders are copied into the app's state folder.
+func recordManifest(app, source string) error {
+	return recordNamespacedManifest(app, "", source)
+}
+
+// recordNamespacedManifest records a manifest which was applied to namespace
+func recordNamespacedManifest(app, namespace, source string) error {
+	store, err := appStore()
+	if err != nil {
+		return err
+	}
+	return store.RecordManifest(app, namespace, source)
+}
+
+// recordInstall records the command and flags which app was installed with
+func recordInstall(app string, install apps.Install) error {
+	store, err := appStore()
+	if err != nil {
+		return err
+	}
+	return store.RecordInstall(app, install)
+}
+
+// recordRelease records a helm 3 release installed for app
+func recordRelease(app, name, namespace string) error {
+	store, err := appStore()
+	if err != nil {
+		return err
+	}
+	return store.RecordRelease(app, name, namespace)
+}
+
+// recordResource records an object created for app with kubectl create
+func recordResource(app, kind, name, namespace string) error {
+	store, err := appStore()
+	if err != nil {
+		return err
+	}
+	return store.RecordResource(app, kind, name, namespace)
+}
+
+// recordSecret records a secret generated for app, it is only removed
+// with "k3sup app uninstall --purge"
+func recordSecret(app, name, namespace string) error {
+	store, err := appStore()
+	if err != nil {
+		return err
+	}
+	return store.RecordSecret(app, name, namespace)
+}
+
+// recordNamespace records a namespace created for app, it is only removed
+// with "k3sup app uninstall --purge"
+func recordNamespace(app string, namespaces ...string) error {
+	store, err := appStore()
+	if err != nil {
+		return err
+	}
+	return store.RecordNamespace(app, namespaces...)
+}
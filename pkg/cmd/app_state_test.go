@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// useAppCluster makes server the cluster of the current context, it returns
+// a func which restores the lookup from the kubeconfig
+func useAppCluster(server string) func() {
+	lookup := appCluster
+	appCluster = func() (string, error) { return server, nil }
+	return func() { appCluster = lookup }
+}
+
+func Test_loadAppState_is_kept_for_each_cluster(t *testing.T) {
+	home, err := ioutil.TempDir("", "k3sup-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", oldHome)
+
+	restore := useAppCluster("https://192.168.0.100:6443")
+	defer restore()
+
+	if err := recordManifest("test-app", "https://example.com/first.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	useAppCluster("https://192.168.0.200:6443")
+
+	names, _ := listAppStates()
+	if len(names) != 0 {
+		t.Errorf("want no apps on the second cluster, got %v", names)
+	}
+
+	state, err := loadAppState("test-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Manifests) != 0 {
+		t.Errorf("want the manifests of the first cluster to be left out, got: %v", state.Manifests)
+	}
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"fmt"
+	"text/template"
+)
+
+//go:generate go run ../../hack/templates.go templates app_templates_gen.go
+
+// appTemplate parses the manifest template of an app from templates/, such
+// as ingress/ingress.yaml. The templates are compiled into appTemplates by
+// go generate, so that they can be edited as plain YAML.
+func appTemplate(name string) (*template.Template, error) {
+	text, ok := appTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("no app template named %q, run go generate ./pkg/cmd/ after adding one", name)
+	}
+
+	return template.New(name).Parse(text)
+}
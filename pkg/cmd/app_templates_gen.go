@@ -0,0 +1,14 @@
+// Code generated by hack/templates.go from templates/; DO NOT EDIT.
+
+package cmd
+
+var appTemplates = map[string]string{
+	"crossplane/providers.yaml": "{{- range . }}\n---\napiVersion: pkg.crossplane.io/v1\nkind: Provider\nmetadata:\n  name: {{.Name}}\nspec:\n  package: {{.Package}}\n{{- end }}\n",
+	"flux/sync.yaml":            "apiVersion: source.toolkit.fluxcd.io/v1beta1\nkind: GitRepository\nmetadata:\n  name: flux-system\n  namespace: flux-system\nspec:\n  interval: 1m\n  url: {{.URL}}\n  ref:\n    branch: {{.Branch}}\n---\napiVersion: kustomize.toolkit.fluxcd.io/v1beta1\nkind: Kustomization\nmetadata:\n  name: flux-system\n  namespace: flux-system\nspec:\n  interval: 10m\n  path: {{.Path}}\n  prune: true\n  sourceRef:\n    kind: GitRepository\n    name: flux-system\n",
+	"ingress/ingress.yaml":      "\napiVersion: extensions/v1beta1 \nkind: Ingress\nmetadata:\n  name: {{.IngressName}}\n  namespace: {{.Namespace}}\n  annotations:\n    cert-manager.io/cluster-issuer: {{.IssuerName}}\n    kubernetes.io/ingress.class: {{.IngressClass}}\n{{- range $k, $v := .Annotations }}\n    {{$k}}: {{printf \"%q\" $v}}\n{{- end }}\nspec:\n  rules:\n  - host: {{.IngressDomain}}\n    http:\n      paths:\n      - backend:\n          serviceName: {{.ServiceName}}\n          servicePort: {{.ServicePort}}\n        path: /\n  tls:\n  - hosts:\n    - {{.IngressDomain}}\n    secretName: {{.TLSSecret}}\n---\napiVersion: cert-manager.io/v1alpha2\nkind: ClusterIssuer\nmetadata:\n  name: {{.IssuerName}}\nspec:\n  acme:\n    email: {{.CertmanagerEmail}}\n    server: {{.ACMEServer}}\n    privateKeySecretRef:\n      name: {{.AccountKeySecret}}\n    solvers:\n{{- if eq .DNS01.Provider \"cloudflare\" }}\n    - dns01:\n        cloudflare:\n          email: {{.CertmanagerEmail}}\n          apiTokenSecretRef:\n            name: {{.DNS01Secret}}\n            key: {{.DNS01SecretKey}}\n{{- else if eq .DNS01.Provider \"route53\" }}\n    - dns01:\n        route53:\n          region: {{.DNS01.AWSRegion}}\n          accessKeyID: {{.DNS01.AWSAccessKeyID}}\n          secretAccessKeySecretRef:\n            name: {{.DNS01Secret}}\n            key: {{.DNS01SecretKey}}\n{{- else if eq .DNS01.Provider \"digitalocean\" }}\n    - dns01:\n        digitalocean:\n          tokenSecretRef:\n            name: {{.DNS01Secret}}\n            key: {{.DNS01SecretKey}}\n{{- else if eq .DNS01.Provider \"google\" }}\n    - dns01:\n        clouddns:\n          project: {{.DNS01.GCPProject}}\n          serviceAccountSecretRef:\n            name: {{.DNS01Secret}}\n            key: {{.DNS01SecretKey}}\n{{- else }}\n    - http01:\n        ingress:\n          class: {{.IngressClass}}\n{{- end }}",
+	"kafka/kafka.yaml":          "apiVersion: kafka.strimzi.io/v1beta1\nkind: Kafka\nmetadata:\n  name: {{.Name}}\n  namespace: {{.Namespace}}\nspec:\n  kafka:\n    replicas: {{.Replicas}}\n    listeners:\n      plain: {}\n      tls: {}\n    config:\n      offsets.topic.replication.factor: {{.ReplicationFactor}}\n      transaction.state.log.replication.factor: {{.ReplicationFactor}}\n      transaction.state.log.min.isr: {{.MinISR}}\n    storage:\n{{- if .Persistence }}\n      type: persistent-claim\n      size: {{.Size}}\n      deleteClaim: false\n{{- else }}\n      type: ephemeral\n{{- end }}\n  zookeeper:\n    replicas: {{.ZookeeperReplicas}}\n    storage:\n{{- if .Persistence }}\n      type: persistent-claim\n      size: {{.Size}}\n      deleteClaim: false\n{{- else }}\n      type: ephemeral\n{{- end }}\n  entityOperator:\n    topicOperator: {}\n    userOperator: {}\n",
+	"knative/config.yaml":       "{{/* Routes services through kourier, and sets their domain when one is given */ -}}\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config-network\n  namespace: knative-serving\ndata:\n  ingress.class: kourier.ingress.networking.knative.dev\n{{- if .Domain }}\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config-domain\n  namespace: knative-serving\ndata:\n  {{.Domain}}: \"\"\n{{- end }}\n",
+	"loki/datasource.yaml":      "{{/* The datasource is found by grafana's sidecar from the label */ -}}\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: loki-datasource\n  namespace: {{.GrafanaNamespace}}\n  labels:\n    grafana_datasource: \"1\"\ndata:\n  loki-datasource.yaml: |-\n    apiVersion: 1\n    datasources:\n    - name: Loki\n      type: loki\n      access: proxy\n      url: {{.LokiURL}}\n",
+	"metallb/config.yaml":       "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  namespace: metallb-system\n  name: config\ndata:\n  config: |\n    address-pools:\n    - name: default\n      protocol: layer2\n      addresses:\n      - {{.}}\n",
+	"mosquitto/mosquitto.yaml":  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: mosquitto\n  namespace: {{.Namespace}}\ndata:\n  mosquitto.conf: |\n    listener 1883\n    persistence false\n{{- if .Auth }}\n    allow_anonymous false\n    password_file /mosquitto/auth/passwd\n{{- else }}\n    allow_anonymous true\n{{- end }}\n{{- if .Auth }}\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: mosquitto-auth\n  namespace: {{.Namespace}}\ntype: Opaque\nstringData:\n  username: {{printf \"%q\" .Username}}\n  password: {{printf \"%q\" .Password}}\n  passwd: {{printf \"%q\" .Passwd}}\n{{- end }}\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: mosquitto\n  namespace: {{.Namespace}}\n  labels:\n    app: mosquitto\nspec:\n  replicas: 1\n  selector:\n    matchLabels:\n      app: mosquitto\n  template:\n    metadata:\n      labels:\n        app: mosquitto\n    spec:\n      containers:\n      - name: mosquitto\n        image: eclipse-mosquitto:{{.Version}}\n        ports:\n        - name: mqtt\n          containerPort: 1883\n        readinessProbe:\n          tcpSocket:\n            port: mqtt\n        volumeMounts:\n        - name: config\n          mountPath: /mosquitto/config\n{{- if .Auth }}\n        - name: auth\n          mountPath: /mosquitto/auth\n{{- end }}\n      volumes:\n      - name: config\n        configMap:\n          name: mosquitto\n{{- if .Auth }}\n      - name: auth\n        secret:\n          secretName: mosquitto-auth\n          items:\n          - key: passwd\n            path: passwd\n{{- end }}\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: mosquitto\n  namespace: {{.Namespace}}\nspec:\n  type: {{.ServiceType}}\n  selector:\n    app: mosquitto\n  ports:\n  - name: mqtt\n    port: 1883\n    targetPort: mqtt\n{{- if .NodePort }}\n    nodePort: {{.NodePort}}\n{{- end }}\n",
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_appTemplates_MatchTemplatesDir(t *testing.T) {
+	found := map[string]bool{}
+	err := filepath.Walk("templates", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		name, _ := filepath.Rel("templates", path)
+		name = filepath.ToSlash(name)
+		found[name] = true
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if appTemplates[name] != string(data) {
+			t.Errorf("%s has changed since app_templates_gen.go was generated, run go generate ./pkg/cmd/", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range appTemplates {
+		if !found[name] {
+			t.Errorf("%s was removed from templates/, run go generate ./pkg/cmd/", name)
+		}
+	}
+}
+
+func Test_appTemplate_Parses(t *testing.T) {
+	for name := range appTemplates {
+		if _, err := appTemplate(name); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func Test_appTemplate_Unknown(t *testing.T) {
+	if _, err := appTemplate("missing/missing.yaml"); err == nil {
+		t.Errorf("want an error for an unknown template")
+	}
+}
@@ -2,11 +2,22 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alexellis/k3sup/pkg/apps"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+const thanksForUsing = `Thank you for using k3sup!`
+
 func MakeApps() *cobra.Command {
 	var command = &cobra.Command{
 		Use:          "app",
@@ -25,30 +36,587 @@ func MakeApps() *cobra.Command {
 	}
 
 	install.Flags().String("kubeconfig", "kubeconfig", "Local path for your kubeconfig file")
+	addInstallFlags(install)
+	install.PersistentPreRunE = preflightChecks
+	install.PersistentPostRunE = finishInstall
 
 	install.RunE = func(command *cobra.Command, args []string) error {
 
 		if len(args) == 0 {
-			fmt.Printf("You can install: %s\n", strings.TrimRight(strings.Join(getApps(), ", "), ", "))
+			fmt.Fprintf(textOutput, "You can install: %s\n", strings.TrimRight(strings.Join(getApps(), ", "), ", "))
+			if names := repoAppNames(command); len(names) > 0 {
+				fmt.Fprintf(textOutput, "From catalogs: %s\n", strings.Join(names, ", "))
+			}
 			return nil
 		}
 
 		return nil
 	}
 
+	var uninstall = &cobra.Command{
+		Use:   "uninstall",
+		Short: "Uninstall a Kubernetes app",
+		Long:  `Uninstall a Kubernetes app which was installed with k3sup`,
+		Example: `  k3sup app uninstall [APP]
+  k3sup app uninstall openfaas --purge`,
+		SilenceUsage: true,
+	}
+
+	uninstall.Flags().String("kubeconfig", "kubeconfig", "Local path for your kubeconfig file")
+	uninstall.Flags().Bool("purge", false, "Also remove generated secrets and namespaces created for the app")
+
+	uninstall.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+			kubeConfigPath = expandPath(kubeConfigPath)
+			os.Setenv("KUBECONFIG", kubeConfigPath)
+		}
+
+		store, err := appStore()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			installed, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			if len(installed) == 0 {
+				fmt.Fprintln(textOutput, "No apps have been installed with k3sup on this cluster")
+				return nil
+			}
+			fmt.Fprintf(textOutput, "You can uninstall: %s\n", strings.Join(installed, ", "))
+			return nil
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		purge, _ := command.Flags().GetBool("purge")
+
+		name := args[0]
+		if _, err := store.Find(name); err != nil {
+			if _, ok := err.(*apps.NotInstalledError); ok {
+				return fmt.Errorf("%s, run \"k3sup app uninstall\" to see installed apps", err)
+			}
+			return err
+		}
+
+		deleted := ""
+		if purge {
+			deleted = ", deleting its secrets and namespaces"
+		}
+		if err := confirm("Uninstall %s from the cluster%s?", name, deleted); err != nil {
+			return err
+		}
+
+		if err := apps.Uninstall(kubectlCluster{}, store, name, purge); err != nil {
+			return err
+		}
+
+		logInfo(`=======================================================================
+= ` + name + ` has been uninstalled.
+=======================================================================
+
+` + thanksForUsing)
+
+		return nil
+	}
+
+	var upgrade = &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade an app installed with k3sup",
+		Long: `Upgrade an app by installing it again with the flags it was installed with,
+so that generated secrets and values are kept. The chart is upgraded as a
+helm release, or its manifests are applied over the existing ones.
+
+Flags which may hold secrets, such as passwords and --set values for keys
+named like a password, are not saved. Give them again after --, which passes
+any flags to the app's install.`,
+		Example: `  k3sup app upgrade openfaas
+  k3sup app upgrade cert-manager --version v1.0.4
+  k3sup app upgrade minio -- --secret-key $SECRET_KEY`,
+		SilenceUsage: true,
+	}
+
+	upgrade.Flags().String("kubeconfig", "kubeconfig", "Local path for your kubeconfig file")
+	upgrade.Flags().String("version", "", "The version to upgrade to (Default to the version given at install, or the app's default)")
+
+	upgrade.RunE = func(command *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("give the name of an app, run \"k3sup app uninstall\" to see installed apps")
+		}
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ := command.Flags().GetString("kubeconfig")
+			os.Setenv("KUBECONFIG", expandPath(kubeConfigPath))
+		}
+
+		name := args[0]
+		version, _ := command.Flags().GetString("version")
+
+		state, err := loadAppState(name)
+		if err != nil {
+			return err
+		}
+
+		if state.Install == nil {
+			return fmt.Errorf("no record of how %s was installed, install it again with \"k3sup app install\"", name)
+		}
+
+		var app *cobra.Command
+		for _, c := range install.Commands() {
+			if c.Name() == state.Install.App {
+				app = c
+			}
+		}
+
+		if app == nil {
+			return fmt.Errorf("%s was installed with %q, which is no longer an app", name, state.Install.App)
+		}
+
+		if len(version) > 0 && app.Flags().Lookup("version") == nil {
+			return fmt.Errorf("--version is not supported for %s", state.Install.App)
+		}
+
+		if len(state.Install.Omitted) > 0 && len(args) == 1 {
+			logWarnf("%s was installed with %s, which are not saved as they may hold secrets. Give them again after -- unless the app keeps them in the cluster\n", name, strings.Join(state.Install.Omitted, ", "))
+		}
+
+		logInfof("Upgrading %s from version: %q\n", name, state.Version)
+
+		install.RemoveCommand(app)
+		return runInstall(app, append(upgradeFlags(state.Install.Flags, version), args[1:]...))
+	}
+
+	var list = &cobra.Command{
+		Use:          "list",
+		Short:        "List the apps which can be installed",
+		Long:         `List the apps which can be installed, with the flags each one supports`,
+		Example:      `  k3sup app list`,
+		SilenceUsage: true,
+	}
+
+	list.RunE = func(command *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(textOutput, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tDESCRIPTION\tFLAGS")
+
+		for _, app := range install.Commands() {
+			flags := []string{}
+			app.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+				flags = append(flags, "--"+flag.Name)
+			})
+
+			fmt.Fprintf(w, "%s\t%s\t%s\n", app.Name(), app.Short, strings.Join(flags, " "))
+		}
+
+		return w.Flush()
+	}
+
+	var info = &cobra.Command{
+		Use:          "info",
+		Short:        "Show the post-install instructions for an app",
+		Long:         `Show the instructions which were printed when an app was installed`,
+		Example:      `  k3sup app info openfaas`,
+		ValidArgs:    getApps(),
+		SilenceUsage: true,
+	}
+
+	info.RunE = func(command *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("give the name of an app, i.e. k3sup app info openfaas")
+		}
+
+		msg, ok := getAppInfo()[args[0]]
+		if !ok {
+			msg, ok = repoAppInfo()[args[0]]
+		}
+		if !ok {
+			return fmt.Errorf("no information for %q, run \"k3sup app list\" to see the available apps", args[0])
+		}
+
+		fmt.Fprintln(textOutput, msg)
+		return nil
+	}
+
+	var status = &cobra.Command{
+		Use:          "status",
+		Short:        "Show the apps installed with k3sup on the cluster",
+		Long:         `Show the apps installed with k3sup on the cluster, their versions and whether their Deployments and DaemonSets are Ready`,
+		Example:      `  k3sup app status`,
+		SilenceUsage: true,
+	}
+
+	status.Flags().String("kubeconfig", "kubeconfig", "Local path for your kubeconfig file")
+
+	status.RunE = func(command *cobra.Command, args []string) error {
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ := command.Flags().GetString("kubeconfig")
+			os.Setenv("KUBECONFIG", expandPath(kubeConfigPath))
+		}
+
+		inventory, err := loadInventory()
+		if err != nil {
+			return err
+		}
+
+		if len(inventory) == 0 {
+			fmt.Fprintln(textOutput, "No apps have been installed with k3sup on this cluster")
+			return nil
+		}
+
+		names := []string{}
+		for name := range inventory {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(textOutput, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tVERSION\tREADY\tINSTALLED")
+
+		for _, name := range names {
+			entry := inventory[name]
+
+			ready := 0
+			for _, workload := range entry.Workloads {
+				ok, err := apps.WorkloadReady(kubectlCluster{}, workload)
+				if err != nil {
+					return err
+				}
+
+				if ok {
+					ready++
+				}
+			}
+
+			version := entry.Version
+			if len(version) == 0 {
+				version = "-"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\n", name, version, ready, len(entry.Workloads), entry.Installed.Format(time.RFC3339))
+		}
+
+		return w.Flush()
+	}
+
 	command.AddCommand(install)
+	command.AddCommand(uninstall)
+	command.AddCommand(upgrade)
+	command.AddCommand(list)
+	command.AddCommand(info)
+	command.AddCommand(status)
+	command.AddCommand(makeAppRepo())
 	install.AddCommand(makeInstallOpenFaaS())
 	install.AddCommand(makeInstallMetricsServer())
 	install.AddCommand(makeInstallInletsOperator())
 	install.AddCommand(makeInstallCertManager())
 	install.AddCommand(makeInstallOpenFaaSIngress())
+	install.AddCommand(makeInstallIngress())
 	install.AddCommand(makeInstallNginx())
 	install.AddCommand(makeInstallChart())
 	install.AddCommand(makeInstallTiller())
+	install.AddCommand(makeInstallLonghorn())
+	install.AddCommand(makeInstallMetalLB())
+	install.AddCommand(makeInstallDashboard())
+	install.AddCommand(makeInstallPostgresql())
+	install.AddCommand(makeInstallMinio())
+	install.AddCommand(makeInstallRegistry())
+	install.AddCommand(makeInstallLinkerd())
+	install.AddCommand(makeInstallIstio())
+	install.AddCommand(makeInstallKafka())
+	install.AddCommand(makeInstallMonitoring())
+	install.AddCommand(makeInstallLoki())
+	install.AddCommand(makeInstallRancher())
+	install.AddCommand(makeInstallArgoCD())
+	install.AddCommand(makeInstallFlux())
+	install.AddCommand(makeInstallTekton())
+	install.AddCommand(makeInstallSealedSecrets())
+	install.AddCommand(makeInstallExternalDNS())
+	install.AddCommand(makeInstallKnativeServing())
+	install.AddCommand(makeInstallCrossplane())
+	install.AddCommand(makeInstallNfsProvisioner())
+	install.AddCommand(makeInstallVelero())
+	install.AddCommand(makeInstallGitea())
+	install.AddCommand(makeInstallMosquitto())
+	install.AddCommand(makeInstallHarbor())
+
+	addRepoApps(install)
 
 	return command
 }
 
+// addInstallFlags adds the flags shared by every app to install
+func addInstallFlags(install *cobra.Command) {
+	install.PersistentFlags().StringArray("set", []string{}, "Set individual values in the app's helm chart i.e. --set key=value, can be repeated")
+	install.PersistentFlags().Bool("helm3", false, "Install helm charts as releases with helm 3, instead of rendering them with helm 2")
+	install.PersistentFlags().Bool("dry-run", false, "Print the manifests for the app instead of applying them to the cluster")
+	install.PersistentFlags().String("output-file", "", "Write the manifests from --dry-run to a file instead of stdout")
+	install.PersistentFlags().StringArray("values", []string{}, "Local path to a values.yaml file for the app's helm chart, can be repeated")
+	install.PersistentFlags().Bool("skip-preflight", false, "Skip the checks of the cluster which are made before an app is installed")
+	install.PersistentFlags().Bool("wait", false, "Wait for the app's Deployments and DaemonSets to be Ready before returning")
+	install.PersistentFlags().Duration("timeout", 5*time.Minute, "How long to wait with --wait")
+}
+
+// installDependency installs an app which another app needs, with the
+// default values for its flags and the same choice of --helm3
+func installDependency(command *cobra.Command, dependency *cobra.Command) error {
+	args := []string{}
+	if helm3, _ := command.Flags().GetBool("helm3"); helm3 {
+		args = append(args, "--helm3")
+	}
+
+	return runInstall(dependency, args)
+}
+
+// runInstall runs the install of app with args, from a new install command
+// so that it can be run from within another command
+func runInstall(app *cobra.Command, args []string) error {
+	install := &cobra.Command{Use: "install", SilenceUsage: true}
+	addInstallFlags(install)
+	install.PersistentPreRunE = preflightChecks
+	install.PersistentPostRunE = finishInstall
+	install.AddCommand(app)
+	install.SetArgs(append([]string{app.Name()}, args...))
+
+	return install.Execute()
+}
+
+// finishInstall runs after an app has been installed, to record the flags
+// it was installed with and then to wait for it when --wait is given
+func finishInstall(command *cobra.Command, args []string) error {
+	if command.Name() == "install" {
+		return nil
+	}
+
+	if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+		return nil
+	}
+
+	app := installedAppName(command)
+
+	if err := recordInstallFlags(app, command); err != nil {
+		return err
+	}
+
+	if wait, _ := command.Flags().GetBool("wait"); wait {
+		timeout, _ := command.Flags().GetDuration("timeout")
+		if err := waitForApp(app, timeout); err != nil {
+			return err
+		}
+	}
+
+	return recordAppResult(app)
+}
+
+// recordInstallFlags saves the flags an app was installed with, so that
+// "k3sup app upgrade" can install it again with the same values
+func recordInstallFlags(app string, command *cobra.Command) error {
+	flags, omitted := installFlags(command)
+
+	return recordInstall(app, apps.Install{
+		App:     command.Name(),
+		Flags:   flags,
+		Omitted: omitted,
+	})
+}
+
+// waitForApp polls the Deployments and DaemonSets recorded for app in the
+// inventory until they are all Ready
+func waitForApp(app string, timeout time.Duration) (err error) {
+	inventory, err := loadInventory()
+	if err != nil {
+		return err
+	}
+
+	workloads := inventory[app].Workloads
+	if len(workloads) == 0 {
+		logInfof("No Deployments or DaemonSets to wait for: %s\n", app)
+		return nil
+	}
+
+	p := startProgress("Waiting up to %s for %d Deployments and DaemonSets of %s to be Ready", timeout, len(workloads), app)
+	defer func() { p.Stop(err) }()
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		pending := []string{}
+		for _, workload := range workloads {
+			ok, err := apps.WorkloadReady(kubectlCluster{}, workload)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				continue
+			}
+
+			pending = append(pending, fmt.Sprintf("%s/%s -n %s", workload.Kind, workload.Name, workload.Namespace))
+		}
+
+		if len(pending) == 0 {
+			p.Stop(nil)
+			logInfof("%s is Ready\n", app)
+			return nil
+		}
+
+		p.Update("%d/%d Ready", len(workloads)-len(pending), len(workloads))
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s to be Ready, check: %s", timeout, app, strings.Join(pending, ", "))
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// installedAppName returns the name an app is recorded under, which is the
+// name of its command apart from the chart and ingress apps
+func installedAppName(command *cobra.Command) string {
+	switch command.Name() {
+	case "chart":
+		repoName, _ := command.Flags().GetString("repo-name")
+		return chartNameFromRepo(repoName)
+	case "ingress":
+		name, _ := command.Flags().GetString("name")
+		if len(name) == 0 {
+			name, _ = command.Flags().GetString("service")
+		}
+		return "ingress-" + name
+	}
+	return command.Name()
+}
+
+// sensitiveFlag annotates the flags of an app which may hold a secret, so
+// that their values are not saved with the flags of its install
+const sensitiveFlag = "k3sup-sensitive"
+
+// sensitiveKeyRegex matches the keys of --set values which may be secrets
+var sensitiveKeyRegex = regexp.MustCompile(`(?i)(password|passwd|secret|token|key|credential)`)
+
+// markSensitive marks the flags of an app which may hold a secret
+func markSensitive(command *cobra.Command, names ...string) {
+	for _, name := range names {
+		command.Flags().SetAnnotation(name, sensitiveFlag, []string{"true"})
+	}
+}
+
+// sensitiveSetKey returns the key named like a secret which is set by a
+// --set value, which may set several keys separated by commas
+func sensitiveSetKey(value string) string {
+	for _, part := range strings.Split(value, ",") {
+		if key := strings.SplitN(part, "=", 2)[0]; sensitiveKeyRegex.MatchString(key) {
+			return key
+		}
+	}
+	return ""
+}
+
+// installFlags returns the flags given to an app's install, without those
+// which only affect the output. Paths to --values files are made absolute,
+// so that an upgrade can be run from another folder. Flags which may hold
+// secrets are left out, and returned by name as omitted.
+func installFlags(command *cobra.Command) (flags []string, omitted []string) {
+	flags = []string{}
+
+	command.Flags().Visit(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case "dry-run", "output-file", "kubeconfig", "skip-preflight", "wait", "timeout", "output", "verbose", "quiet", "config":
+			return
+		}
+
+		if _, ok := flag.Annotations[sensitiveFlag]; ok {
+			omitted = append(omitted, "--"+flag.Name)
+			return
+		}
+
+		values := []string{flag.Value.String()}
+		switch flag.Value.Type() {
+		case "stringArray":
+			values, _ = command.Flags().GetStringArray(flag.Name)
+		case "stringSlice":
+			values, _ = command.Flags().GetStringSlice(flag.Name)
+		}
+
+		for _, value := range values {
+			if key := sensitiveSetKey(value); flag.Name == "set" && len(key) > 0 {
+				omitted = append(omitted, "--set "+key)
+				continue
+			}
+
+			if flag.Name == "values" {
+				if abs, err := filepath.Abs(expandPath(value)); err == nil {
+					value = abs
+				}
+			}
+			flags = append(flags, fmt.Sprintf("--%s=%s", flag.Name, value))
+		}
+	})
+
+	return flags, omitted
+}
+
+// upgradeFlags returns the recorded install flags, with --version replaced
+// when a version is given
+func upgradeFlags(flags []string, version string) []string {
+	upgraded := []string{}
+	for _, flag := range flags {
+		if len(version) > 0 && strings.HasPrefix(flag, "--version=") {
+			continue
+		}
+		upgraded = append(upgraded, flag)
+	}
+
+	if len(version) > 0 {
+		upgraded = append(upgraded, "--version="+version)
+	}
+
+	return upgraded
+}
+
 func getApps() []string {
-	return []string{"openfaas", "nginx-ingress", "cert-manager", "openfaas-ingress", "inlets-operator", "metrics-server", "chart", "tiller"}
+	return []string{"openfaas", "nginx-ingress", "cert-manager", "openfaas-ingress", "ingress", "inlets-operator", "metrics-server", "chart", "tiller", "longhorn", "metallb", "kubernetes-dashboard", "postgresql", "minio", "registry", "linkerd", "istio", "kafka", "monitoring", "loki", "rancher", "argocd", "flux", "tekton", "sealed-secrets", "external-dns", "knative-serving", "crossplane", "nfs-provisioner", "velero", "gitea", "mosquitto", "harbor"}
+}
+
+// getAppInfo returns the instructions printed after each app is installed
+func getAppInfo() map[string]string {
+	return map[string]string{
+		"openfaas":             openfaasInfoMsg,
+		"nginx-ingress":        nginxIngressInfoMsg,
+		"cert-manager":         certManagerInfoMsg,
+		"openfaas-ingress":     openfaasIngressInfoMsg,
+		"ingress":              ingressInfoMsg,
+		"inlets-operator":      inletsOperatorInfoMsg,
+		"metrics-server":       metricsServerInfoMsg,
+		"chart":                "# The chart app installs any helm chart, see the chart's own documentation",
+		"tiller":               tillerInfoMsg,
+		"longhorn":             longhornInfoMsg,
+		"metallb":              metallbInfoMsg,
+		"kubernetes-dashboard": dashboardInfoMsg,
+		"postgresql":           postgresqlInfoMsg,
+		"minio":                minioInfoMsg,
+		"registry":             registryInfoMsg,
+		"linkerd":              linkerdInfoMsg,
+		"istio":                istioInfoMsg,
+		"kafka":                kafkaInfoMsg,
+		"monitoring":           monitoringInfoMsg,
+		"loki":                 lokiInfoMsg,
+		"rancher":              rancherInfoMsg,
+		"argocd":               argocdInfoMsg,
+		"flux":                 fluxInfoMsg,
+		"tekton":               tektonInfoMsg,
+		"sealed-secrets":       sealedSecretsInfoMsg,
+		"external-dns":         externalDNSInfoMsg,
+		"knative-serving":      knativeInfoMsg,
+		"crossplane":           crossplaneInfoMsg,
+		"nfs-provisioner":      nfsProvisionerInfoMsg,
+		"velero":               veleroInfoMsg,
+		"gitea":                giteaInfoMsg,
+		"mosquitto":            mosquittoInfoMsg,
+		"harbor":               harborInfoMsg,
+	}
 }
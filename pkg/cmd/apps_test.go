@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_getAppInfo_covers_every_app(t *testing.T) {
+	info := getAppInfo()
+
+	for _, app := range getApps() {
+		if len(info[app]) == 0 {
+			t.Errorf("no info for app: %s", app)
+		}
+	}
+}
+
+func Test_installFlags_records_changed_flags(t *testing.T) {
+	command := &cobra.Command{Use: "minio"}
+	command.Flags().String("namespace", "default", "")
+	command.Flags().Bool("persistence", false, "")
+	command.Flags().Bool("dry-run", false, "")
+	command.Flags().StringArray("set", []string{}, "")
+	command.Flags().Set("persistence", "true")
+	command.Flags().Set("set", "replicas=2")
+	command.Flags().Set("set", "mode=standalone")
+
+	flags, omitted := installFlags(command)
+	got := strings.Join(flags, " ")
+	want := "--persistence=true --set=replicas=2 --set=mode=standalone"
+	if got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+	if len(omitted) != 0 {
+		t.Errorf("want no flags omitted, got: %v", omitted)
+	}
+}
+
+func Test_installFlags_leaves_out_secrets(t *testing.T) {
+	command := &cobra.Command{Use: "minio"}
+	command.Flags().String("namespace", "default", "")
+	command.Flags().String("secret-key", "", "")
+	command.Flags().StringArray("set", []string{}, "")
+	markSensitive(command, "secret-key")
+	command.Flags().Set("namespace", "minio")
+	command.Flags().Set("secret-key", "s3cr3t")
+	command.Flags().Set("set", "replicas=2")
+	command.Flags().Set("set", "mode=standalone,auth.rootPassword=s3cr3t")
+	command.Flags().Set("set", "apiToken=s3cr3t")
+
+	flags, omitted := installFlags(command)
+	if got := strings.Join(flags, " "); strings.Contains(got, "s3cr3t") || got != "--namespace=minio --set=replicas=2" {
+		t.Errorf("want the secrets left out, got: %q", got)
+	}
+
+	want := "--secret-key, --set auth.rootPassword, --set apiToken"
+	if got := strings.Join(omitted, ", "); got != want {
+		t.Errorf("want omitted: %q, got: %q", want, got)
+	}
+}
+
+func Test_upgradeFlags_replaces_version(t *testing.T) {
+	got := strings.Join(upgradeFlags([]string{"--version=v0.11.0", "--helm3=true"}, "v1.0.4"), " ")
+	want := "--helm3=true --version=v1.0.4"
+	if got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+
+	got = strings.Join(upgradeFlags([]string{"--version=v0.11.0"}, ""), " ")
+	if got != "--version=v0.11.0" {
+		t.Errorf("want the installed version kept, got: %q", got)
+	}
+}
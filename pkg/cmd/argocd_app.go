@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallArgoCD() *cobra.Command {
+	var argocd = &cobra.Command{
+		Use:   "argocd",
+		Short: "Install argocd",
+		Long: `Install argocd for GitOps, to deploy apps to the cluster from a git
+repository.
+
+Give --domain and --email to expose the argocd UI with an Ingress and a TLS
+certificate from Let's Encrypt, which requires cert-manager. The TLS
+connection then ends at the IngressController, so argocd-server is run
+with --insecure.`,
+		Example: `  k3sup app install argocd
+  k3sup app install argocd --domain argocd.example.com --email admin@example.com`,
+		SilenceUsage: true,
+	}
+
+	argocd.Flags().StringP("namespace", "n", "argocd", "The namespace used for installation")
+	argocd.Flags().String("version", "v2.3.0", "The version of argocd to install")
+	addIngressFlags(argocd)
+
+	argocd.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		version, _ := command.Flags().GetString("version")
+		domain, _ := command.Flags().GetString("domain")
+		namespace, _ := command.Flags().GetString("namespace")
+
+		// The role bindings in install.yaml are for the argocd namespace
+		manifests, err := setManifestNamespace("argocd", namespace,
+			fmt.Sprintf("https://raw.githubusercontent.com/argoproj/argo-cd/%s/manifests/install.yaml", version))
+		if err != nil {
+			return err
+		}
+
+		var inputData InputData
+		if len(domain) > 0 {
+			inputData, err = ingressFromFlags(command)
+			if err != nil {
+				return err
+			}
+			inputData.IngressName = "argocd-server"
+			inputData.Namespace = namespace
+			inputData.ServiceName = "argocd-server"
+			inputData.ServicePort = 80
+			inputData.TLSSecret = "argocd-server-tls"
+
+			paramsFile, err := writeTempFile("argocd_params", []byte(argocdInsecureParams))
+			if err != nil {
+				return err
+			}
+			manifests = append(manifests, paramsFile)
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			sources := manifests
+			if len(domain) > 0 {
+				ingressYaml, err := buildYaml(inputData)
+				if err != nil {
+					return err
+				}
+
+				ingressFile, err := writeTempFile("argocd", ingressYaml)
+				if err != nil {
+					return err
+				}
+				sources = append(sources, ingressFile)
+			}
+
+			logWarnf("Apply the manifests to the %s namespace\n", namespace)
+			return printManifests(command, sources...)
+		}
+
+		if err := createNamespace("argocd", namespace); err != nil {
+			return err
+		}
+
+		if err := applyNamespacedManifests("argocd", namespace, manifests...); err != nil {
+			return err
+		}
+
+		if len(domain) > 0 {
+			// argocd-server only reads its parameters when it starts
+			err := kubectl("rollout", "restart", "deploy/argocd-server", "--namespace", namespace)
+			if err != nil {
+				return fmt.Errorf("unable to restart argocd-server: %s", err)
+			}
+
+			if err := applyIngress(command, "argocd", inputData); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("argocd", version); err != nil {
+			return err
+		}
+
+		logInfo(argocdInstallMsg)
+
+		return nil
+	}
+
+	return argocd
+}
+
+// argocdInsecureParams serves the argocd UI and API over HTTP, for when TLS
+// ends at the IngressController
+const argocdInsecureParams = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: argocd-cmd-params-cm
+  labels:
+    app.kubernetes.io/name: argocd-cmd-params-cm
+    app.kubernetes.io/part-of: argocd
+data:
+  server.insecure: "true"
+`
+
+const argocdInfoMsg = `# Get the initial password for the admin user
+kubectl -n argocd get secret argocd-initial-admin-secret \
+  -o jsonpath="{.data.password}" | base64 --decode; echo
+
+# Forward the argocd UI to your machine, then open https://127.0.0.1:8443
+# or use your domain if you installed with --domain
+kubectl port-forward -n argocd svc/argocd-server 8443:443 &
+
+# Log in with the argocd CLI, then change the password
+argocd login 127.0.0.1:8443 --username admin --insecure
+argocd account update-password
+
+# Find out more at:
+# https://argo-cd.readthedocs.io/en/stable/getting_started/`
+
+const argocdInstallMsg = `=======================================================================
+= argocd has been installed.                                          =
+=======================================================================
+
+` + argocdInfoMsg + `
+
+` + thanksForUsing
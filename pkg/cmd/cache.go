@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/alexellis/k3sup/pkg/config"
+	"github.com/alexellis/k3sup/pkg/provision"
+	"github.com/spf13/cobra"
+)
+
+const k3sReleasesURL = "https://github.com/rancher/k3s/releases/download"
+
+// cacheLock serialises downloads to the cache, as the agents in a plan are
+// joined at the same time and would otherwise fetch the same binary
+var cacheLock sync.Mutex
+
+// addCacheFlags adds the flags to install from binaries cached on this
+// machine
+func addCacheFlags(command *cobra.Command) {
+	command.Flags().Bool("cache", false, "Download k3s and its install script to ~/.k3sup/cache once, then upload them to each node instead of downloading on the node")
+	command.Flags().Bool("no-cache", false, "Download k3s on the node, even when cache is set in the config file or K3SUP_CACHE")
+}
+
+// cacheFromFlags reports whether the cache should be used, --no-cache wins
+// so that it can override a default from the config file
+func cacheFromFlags(command *cobra.Command) bool {
+	cache, _ := command.Flags().GetBool("cache")
+	noCache, _ := command.Flags().GetBool("no-cache")
+	return cache && !noCache
+}
+
+// validCacheFlags returns an error for flags which can not be used with
+// --cache. The binary is cached by version, so a channel can not be used.
+func validCacheFlags(command *cobra.Command) error {
+	if !cacheFromFlags(command) {
+		return nil
+	}
+
+	if airgap, _ := command.Flags().GetBool("airgap"); airgap {
+		return fmt.Errorf("give either --cache or --airgap, not both")
+	}
+	if printCommand, _ := command.Flags().GetBool("print-command"); printCommand {
+		return fmt.Errorf("--cache can not be used with --print-command, as the architecture of the node is not known")
+	}
+	if command.Flags().Changed("k3s-channel") {
+		return fmt.Errorf("--cache needs a --k3s-version rather than a --k3s-channel")
+	}
+
+	return nil
+}
+
+// k3sCacheDir returns the folder for the binaries of version, with a file
+// for each architecture
+func k3sCacheDir(version string) (string, error) {
+	userPath, err := config.InitUserDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(userPath, "cache", version), nil
+}
+
+// cachedAirgap downloads the k3s binary for arch, as returned by
+// remoteArch, and the install script to the cache, unless they are already
+// there, and returns them as an air-gapped install
+func cachedAirgap(arch, version string) (provision.Airgap, error) {
+	dir, err := k3sCacheDir(version)
+	if err != nil {
+		return provision.Airgap{}, err
+	}
+
+	binary := path.Join(dir, k3sBinaryName(arch))
+	installScript := path.Join(dir, "install.sh")
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	releaseURL := fmt.Sprintf("%s/%s", k3sReleasesURL, version)
+	if err := downloadToCache(releaseURL+"/"+k3sBinaryName(arch), fmt.Sprintf("%s/sha256sum-%s.txt", releaseURL, arch), binary); err != nil {
+		return provision.Airgap{}, err
+	}
+	// No checksum is published for the install script
+	if err := downloadToCache("https://get.k3s.io", "", installScript); err != nil {
+		return provision.Airgap{}, err
+	}
+
+	return provision.Airgap{
+		Enabled:       true,
+		Binary:        binary,
+		InstallScript: installScript,
+	}, nil
+}
+
+// downloadToCache saves url to dest, unless dest already exists. It is
+// verified and written as for downloadFile, so that an interrupted or
+// corrupt download is not used by the next install.
+func downloadToCache(url, checksumURL, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		logDebugf("Using %s from the cache\n", dest)
+		return nil
+	}
+
+	if err := os.MkdirAll(path.Dir(dest), 0700); err != nil {
+		return err
+	}
+
+	logInfof("Downloading %s to %s\n", url, dest)
+
+	return downloadFile(url, checksumURL, dest, 0700)
+}
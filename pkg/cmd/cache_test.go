@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_downloadToCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "binary")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "k3sup-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, "v0.9.1", "k3s")
+	for i := 0; i < 2; i++ {
+		if err := downloadToCache(server.URL+"/k3s", "", dest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if data, _ := ioutil.ReadFile(dest); string(data) != "binary" || requests != 1 {
+		t.Errorf("want one download of the binary, got %d: %q", requests, data)
+	}
+
+	if err := downloadToCache(server.URL+"/missing", "", filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("want an error for a missing file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("want nothing cached for a failed download")
+	}
+}
+
+func Test_cacheFromFlags_NoCache(t *testing.T) {
+	command := MakeInstall()
+	command.Flags().Set("cache", "true")
+	if !cacheFromFlags(command) {
+		t.Errorf("want the cache with --cache")
+	}
+
+	command.Flags().Set("no-cache", "true")
+	if cacheFromFlags(command) {
+		t.Errorf("want --no-cache to override --cache")
+	}
+}
@@ -2,9 +2,10 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/alexellis/k3sup/pkg/config"
 	"github.com/spf13/cobra"
@@ -15,12 +16,13 @@ func makeInstallCertManager() *cobra.Command {
 		Use:          "cert-manager",
 		Short:        "Install cert-manager",
 		Long:         "Install cert-manager for obtaining TLS certificates from LetsEncrypt",
-		Example:      "k3sup install cert-manager",
+		Example:      "  k3sup app install cert-manager --version v1.0.4",
 		SilenceUsage: true,
 	}
 
 	certManager.Flags().StringP("namespace", "n", "cert-manager", "The namespace to install cert-manager")
 	certManager.Flags().Bool("update-repo", true, "Update the helm repo")
+	certManager.Flags().String("version", "v0.11.0", "The version of cert-manager to install, v0.11.0 or newer")
 
 	certManager.RunE = func(command *cobra.Command, args []string) error {
 		kubeConfigPath := getDefaultKubeconfig()
@@ -29,14 +31,25 @@ func makeInstallCertManager() *cobra.Command {
 			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
 		}
 
-		fmt.Printf("Using kubeconfig: %s\n", kubeConfigPath)
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
 
 		namespace, _ := command.Flags().GetString("namespace")
 
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
 		if namespace != "cert-manager" {
 			return fmt.Errorf(`To override the "cert-manager" namespace, install cert-manager via helm manually`)
 		}
 
+		version, _ := command.Flags().GetString("version")
+		crdURL, err := getCertManagerCRDURL(version)
+		if err != nil {
+			return err
+		}
+
 		userPath, err := config.InitUserDir()
 		if err != nil {
 			return err
@@ -44,75 +57,151 @@ func makeInstallCertManager() *cobra.Command {
 
 		clientArch, clientOS := getClientArch()
 
-		fmt.Printf("Client: %s, %s\n", clientArch, clientOS)
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
 
-		log.Printf("User dir established as: %s\n", userPath)
+		logDebugf("User dir established as: %s\n", userPath)
 
 		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
 
-		_, err = tryDownloadHelm(userPath, clientArch, clientOS)
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
 		if err != nil {
 			return err
 		}
 
-		err = addHelmRepo("jetstack", "https://charts.jetstack.io")
+		err = addHelmRepo("jetstack", "https://charts.jetstack.io", helm3)
 		if err != nil {
 			return err
 		}
 		updateRepo, _ := certManager.Flags().GetBool("update-repo")
 
 		if updateRepo {
-			err = updateHelmRepos()
+			err = updateHelmRepos(helm3)
 			if err != nil {
 				return err
 			}
 		}
 
-		err = kubectl("create", "namespace", namespace)
-		if err != nil {
-			return err
+		if !dryRun {
+			if err := createNamespace("cert-manager", namespace); err != nil {
+				return err
+			}
 		}
 
 		chartPath := path.Join(os.TempDir(), "charts")
 
-		err = fetchChart(chartPath, "jetstack/cert-manager")
+		err = fetchChart(chartPath, "jetstack/cert-manager", version, helm3)
 		if err != nil {
 			return err
 		}
 
 		outputPath := path.Join(chartPath, "cert-manager/rendered")
 
-		err = templateChart(chartPath, "cert-manager", namespace, outputPath, "values.yaml", nil)
+		overrides, userValues, err := chartValuesFromFlags(command, nil)
 		if err != nil {
 			return err
 		}
 
-		log.Printf("Applying CRD\n")
+		if !helm3 {
+			err = templateChart(chartPath, "cert-manager", namespace, outputPath, "values.yaml", overrides, userValues)
+			if err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return printManifests(command, crdURL, outputPath)
+		}
+
+		logDebugf("Applying CRD\n")
 
-		res, err := kubectlTask("apply", "--validate=false", "-f", "https://raw.githubusercontent.com/jetstack/cert-manager/release-0.11/deploy/manifests/00-crds.yaml")
+		res, err := kubectlTask("apply", "--validate=false", "-f", crdURL)
 		if err != nil {
 			return err
 		}
-		if len(res.Stderr) > 0 {
+		if res.ExitCode != 0 {
 			return fmt.Errorf("Error applying CRD: %s", res.Stderr)
 		}
 
-		err = kubectl("apply", "-R", "-f", outputPath)
-		if err != nil {
+		if err := recordManifest("cert-manager", crdURL); err != nil {
 			return err
 		}
 
-		fmt.Println(`=======================================================================
-= cert-manager has been installed.                                    =
-=======================================================================
+		if helm3 {
+			err = helm3Install(chartPath, "cert-manager", namespace, "values.yaml", overrides, userValues)
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("cert-manager", "cert-manager", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = kubectl("apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("cert-manager", outputPath); err != nil {
+				return err
+			}
+		}
 
-# Get started with cert-manager here:
-# https://docs.cert-manager.io/en/latest/tutorials/acme/http-validation.html
-		
-Thank you for using k3sup!`)
+		logInfo("Waiting for the cert-manager webhook to become ready")
+		err = kubectl("rollout", "status", "--namespace", namespace, "deploy/cert-manager-webhook", "--timeout", "5m")
+		if err != nil {
+			return fmt.Errorf("the cert-manager webhook did not become ready: %s", err)
+		}
+
+		if err := recordInstalled("cert-manager", chartVersion(chartPath, "cert-manager")); err != nil {
+			return err
+		}
+
+		logInfo(certManagerInstallMsg)
 
 		return nil
 	}
 
 	return certManager
 }
+
+// getCertManagerCRDURL returns the CRDs for a version of cert-manager, they
+// are published as a release asset from v0.15.0 onwards
+func getCertManagerCRDURL(version string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) < 2 {
+		return "", fmt.Errorf("give the cert-manager --version as vMAJOR.MINOR.PATCH, not: %q", version)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid cert-manager --version: %q", version)
+	}
+
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid cert-manager --version: %q", version)
+	}
+
+	if major == 0 && minor < 11 {
+		return "", fmt.Errorf("cert-manager %s is not supported, give v0.11.0 or newer", version)
+	}
+
+	if major == 0 && minor < 15 {
+		return fmt.Sprintf("https://raw.githubusercontent.com/jetstack/cert-manager/release-0.%d/deploy/manifests/00-crds.yaml", minor), nil
+	}
+
+	return fmt.Sprintf("https://github.com/jetstack/cert-manager/releases/download/%s/cert-manager.crds.yaml", version), nil
+}
+
+const certManagerInfoMsg = `# Get started with cert-manager here:
+# https://docs.cert-manager.io/en/latest/tutorials/acme/http-validation.html`
+
+const certManagerInstallMsg = `=======================================================================
+= cert-manager has been installed.                                    =
+=======================================================================
+
+` + certManagerInfoMsg + `
+
+` + thanksForUsing
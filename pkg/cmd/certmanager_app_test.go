@@ -0,0 +1,37 @@
+package cmd
+
+import "testing"
+
+func Test_getCertManagerCRDURL_v0_11(t *testing.T) {
+	want := "https://raw.githubusercontent.com/jetstack/cert-manager/release-0.11/deploy/manifests/00-crds.yaml"
+	got, err := getCertManagerCRDURL("v0.11.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("CRD URL, want: %s, got: %s", want, got)
+	}
+}
+
+func Test_getCertManagerCRDURL_v1(t *testing.T) {
+	want := "https://github.com/jetstack/cert-manager/releases/download/v1.0.4/cert-manager.crds.yaml"
+	got, err := getCertManagerCRDURL("v1.0.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("CRD URL, want: %s, got: %s", want, got)
+	}
+}
+
+func Test_getCertManagerCRDURL_too_old(t *testing.T) {
+	if _, err := getCertManagerCRDURL("v0.10.1"); err == nil {
+		t.Errorf("want error for cert-manager v0.10.1")
+	}
+}
+
+func Test_getCertManagerCRDURL_invalid(t *testing.T) {
+	if _, err := getCertManagerCRDURL("latest"); err == nil {
+		t.Errorf("want error for an invalid version")
+	}
+}
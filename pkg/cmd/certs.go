@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+func MakeCerts() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "certs",
+		Short: "Manage the certificates of a k3s server",
+		Long: `Manage the certificates of a k3s server. k3s issues certificates which
+are valid for one year, so a cluster which is not upgraded or restarted in
+that time stops accepting its own kubeconfig.`,
+		Example:      `  k3sup certs rotate --ip 192.168.0.100 --user root`,
+		SilenceUsage: false,
+	}
+
+	command.AddCommand(makeCertsRotate())
+
+	return command
+}
+
+func makeCertsRotate() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the certificates of a k3s server via SSH",
+		Long: `Rotate the certificates of a k3s server via SSH. The k3s service is stopped,
+its certificates are rotated with "k3s certificate rotate", then it is started
+again and the kubeconfig, which has a client certificate of its own, is fetched
+again as for k3sup get-config.
+
+k3s before v1.21.8 has no "k3s certificate rotate", it is restarted instead,
+which renews the certificates that expire within 90 days.`,
+		Example: `  k3sup certs rotate --ip 192.168.0.100 --user root
+  k3sup certs rotate --ip 192.168.0.100 --merge --context k3s-prod --wait-ready`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+	command.Flags().String("local-path", "kubeconfig", "Local path to save the kubeconfig file")
+	command.Flags().String("context", "default", "Set the name of the kubeconfig context.")
+	command.Flags().String("cluster-name", "", "Set the name of the cluster in the kubeconfig (Default to --context)")
+	command.Flags().String("user-name", "", "Set the name of the user in the kubeconfig (Default to --context)")
+	command.Flags().Bool("merge", false, "Merge the config into an existing kubeconfig under the name given by --context.\nDefaults to $KUBECONFIG or ~/.kube/config, unless --local-path is given")
+	command.Flags().Bool("no-backup", false, "Do not copy an existing kubeconfig to a backup with a timestamp before it is overwritten or merged")
+	command.Flags().Bool("print-kubeconfig", false, "Write the kubeconfig to stdout instead of saving it, i.e. to pipe it into a secret store, the progress is written to stderr")
+	addWaitReadyFlags(command)
+
+	command.Flags().SetNormalizeFunc(contextNameAlias)
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		printKubeconfig, _ := command.Flags().GetBool("print-kubeconfig")
+		if printKubeconfig {
+			progressToStderr()
+		}
+
+		sshOpts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		merge, _ := command.Flags().GetBool("merge")
+		noBackup, _ := command.Flags().GetBool("no-backup")
+		context, _ := command.Flags().GetString("context")
+		clusterName, _ := command.Flags().GetString("cluster-name")
+		userName, _ := command.Flags().GetString("user-name")
+		waitReady, _ := command.Flags().GetBool("wait-ready")
+		timeout, _ := command.Flags().GetDuration("timeout")
+
+		opts := installOptions{
+			SSH:                 sshOpts,
+			PrivilegeEscalation: escalation,
+			LocalPath:           localKubeconfigFromFlags(command),
+			Names: kubeconfigNames{
+				Context: context,
+				Cluster: clusterName,
+				User:    userName,
+			},
+			Merge:           merge,
+			NoBackup:        noBackup,
+			PrintKubeconfig: printKubeconfig,
+			WaitReady:       waitReady,
+			Timeout:         timeout,
+		}
+
+		// Both are asked before k3s is stopped
+		absPath, _ := filepath.Abs(opts.LocalPath)
+		if err := confirmOverwriteKubeconfig(absPath, opts); err != nil {
+			return err
+		}
+		if err := confirm("Rotate the certificates of k3s on %s? The k3s service will be restarted.", sshOpts.Host); err != nil {
+			return err
+		}
+
+		operator, err := connectSSH(sshOpts)
+		if err != nil {
+			return err
+		}
+		defer operator.Close()
+
+		if err := rotateCerts(operator, sshOpts.Host, escalationPrefix(escalation)); err != nil {
+			return err
+		}
+
+		if err := fetchKubeconfig(operator, absPath, opts); err != nil {
+			return err
+		}
+
+		updateResult(func(r *commandResult) {
+			if !opts.PrintKubeconfig {
+				r.Kubeconfig = absPath
+			}
+			r.Context = opts.Names.Context
+		})
+
+		logInfof("Rotated the certificates of k3s on %s\n", sshOpts.Host)
+
+		return nil
+	}
+
+	command.PreRunE = func(command *cobra.Command, args []string) error {
+		if err := validPrintKubeconfigFlags(command); err != nil {
+			return err
+		}
+
+		return validWaitReadyFlags(command)
+	}
+
+	return command
+}
+
+// rotateCerts rotates the certificates of the k3s server on operator, or
+// restarts it when its k3s is too old to rotate them
+func rotateCerts(operator kssh.Operator, host, sudoPrefix string) error {
+	if _, err := operator.ExecuteQuiet(sudoPrefix + "test -d /var/lib/rancher/k3s/server/tls"); err != nil {
+		return fmt.Errorf("no k3s server certificates were found on %s, is it a k3s server?", host)
+	}
+
+	stop, rotate, start := sudoPrefix+"systemctl stop k3s", sudoPrefix+"k3s certificate rotate", sudoPrefix+"systemctl start k3s"
+	if _, err := operator.ExecuteQuiet(rotate + " --help"); err != nil {
+		logWarnf("k3s on %s has no \"k3s certificate rotate\", which needs v1.21.8 or newer. Restarting it instead, which only renews the certificates that expire within 90 days\n", host)
+		stop, rotate = "", ""
+		start = sudoPrefix + "systemctl restart k3s"
+	}
+
+	return runInstallerWithProgress(operator, fmt.Sprintf("Rotating the certificates of k3s on %s", host), func() error {
+		for _, command := range []string{stop, rotate, start} {
+			if len(command) == 0 {
+				continue
+			}
+
+			logDebugf("ssh: %s\n", command)
+			if _, err := operator.Execute(command); err != nil {
+				if command == rotate {
+					// Start k3s again with the certificates it had
+					operator.Execute(start)
+				}
+				return fmt.Errorf("unable to rotate the certificates of k3s on %s, %q failed: %s", host, command, err)
+			}
+		}
+		return nil
+	})
+}
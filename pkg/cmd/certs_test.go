@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_rotateCerts(t *testing.T) {
+	operator := &nodeOperator{replies: map[string]string{
+		"sudo test -d":         "",
+		"sudo k3s certificate": "",
+	}}
+
+	if err := rotateCerts(operator, "192.168.0.100", "sudo "); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sudo systemctl stop k3s", "sudo k3s certificate rotate", "sudo systemctl start k3s"}
+	if !reflect.DeepEqual(operator.lines, want) {
+		t.Errorf("want %v, got %v", want, operator.lines)
+	}
+}
+
+func Test_rotateCerts_OldK3sIsRestarted(t *testing.T) {
+	operator := &nodeOperator{replies: map[string]string{"test -d": ""}}
+
+	if err := rotateCerts(operator, "192.168.0.100", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"systemctl restart k3s"}
+	if !reflect.DeepEqual(operator.lines, want) {
+		t.Errorf("want %v, got %v", want, operator.lines)
+	}
+}
+
+func Test_rotateCerts_NotAServer(t *testing.T) {
+	operator := &nodeOperator{}
+
+	err := rotateCerts(operator, "192.168.0.101", "")
+	if err == nil || !strings.Contains(err.Error(), "is it a k3s server") {
+		t.Errorf("want an error for a node which is not a server, got: %v", err)
+	}
+	if len(operator.lines) > 0 {
+		t.Errorf("want nothing run on the node, got %v", operator.lines)
+	}
+}
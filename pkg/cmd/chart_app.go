@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"strings"
@@ -26,21 +25,17 @@ before using the generic helm chart installer command.`,
 	}
 
 	chartCmd.Flags().StringP("namespace", "n", "default", "The namespace to install the chart")
+	chartCmd.Flags().String("version", "", "The version of the chart (Default to the latest)")
 	chartCmd.Flags().String("repo", "", "The chart repo to install from")
 	chartCmd.Flags().String("values-file", "", "Give the values.yaml file to use from the upstream chart repo")
 	chartCmd.Flags().String("repo-name", "", "Chart name")
 	chartCmd.Flags().String("repo-url", "", "Chart repo")
 
-	chartCmd.Flags().StringArray("set", []string{}, "Set individual values in the helm chart")
-
 	chartCmd.RunE = func(command *cobra.Command, args []string) error {
 		chartRepoName, _ := command.Flags().GetString("repo-name")
 		chartRepoURL, _ := command.Flags().GetString("repo-url")
 
-		chartName := chartRepoName
-		if index := strings.Index(chartRepoName, "/"); index > -1 {
-			chartName = chartRepoName[index+1:]
-		}
+		chartName := chartNameFromRepo(chartRepoName)
 
 		chartPrefix := chartRepoName
 		if index := strings.Index(chartRepoName, "/"); index > -1 {
@@ -57,10 +52,15 @@ before using the generic helm chart installer command.`,
 			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
 		}
 
-		fmt.Printf("Using kubeconfig: %s\n", kubeConfigPath)
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
 
 		namespace, _ := command.Flags().GetString("namespace")
 
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
 		userPath, err := config.InitUserDir()
 		if err != nil {
 			return err
@@ -68,76 +68,107 @@ before using the generic helm chart installer command.`,
 
 		clientArch, clientOS := getClientArch()
 
-		fmt.Printf("Client: %s, %s\n", clientArch, clientOS)
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
 
-		log.Printf("User dir established as: %s\n", userPath)
+		logDebugf("User dir established as: %s\n", userPath)
 
 		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
 
-		_, err = tryDownloadHelm(userPath, clientArch, clientOS)
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
 		if err != nil {
 			return err
 		}
 
 		if len(chartRepoURL) > 0 {
-			err = addHelmRepo(chartPrefix, chartRepoURL)
+			err = addHelmRepo(chartPrefix, chartRepoURL, helm3)
 			if err != nil {
 				return err
 			}
 		}
 
-		err = updateHelmRepos()
+		err = updateHelmRepos(helm3)
 		if err != nil {
 			return err
 		}
 
-		err = kubectl("create", "namespace", namespace)
-		if err != nil {
-			return err
+		if !dryRun {
+			if err := createNamespace(chartName, namespace); err != nil {
+				return err
+			}
 		}
 
+		version, _ := command.Flags().GetString("version")
 		chartPath := path.Join(os.TempDir(), "charts")
 
-		err = fetchChart(chartPath, chartRepoName)
+		err = fetchChart(chartPath, chartRepoName, version, helm3)
 		if err != nil {
 			return err
 		}
 
 		outputPath := path.Join(chartPath, "chart/rendered")
 
-		setMap := map[string]string{}
-		setVals, _ := chartCmd.Flags().GetStringArray("set")
+		setMap, userValues, err := chartValuesFromFlags(command, nil)
+		if err != nil {
+			return err
+		}
 
-		for _, setV := range setVals {
-			var k string
-			var v string
+		valuesFile, _ := command.Flags().GetString("values-file")
+		if len(valuesFile) == 0 {
+			valuesFile = "values.yaml"
+		}
 
-			if index := strings.Index(setV, "="); index > -1 {
-				k = setV[:index]
-				v = setV[index+1:]
-				setMap[k] = v
+		if helm3 {
+			err = helm3Install(chartPath, chartName, namespace, valuesFile, setMap, userValues)
+			if err != nil {
+				return err
 			}
-		}
 
-		err = templateChart(chartPath, chartName, namespace, outputPath, "values.yaml", setMap)
-		if err != nil {
-			return err
+			if err := recordRelease(chartName, chartName, namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, chartName, namespace, outputPath, valuesFile, setMap, userValues)
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, outputPath)
+			}
+
+			err = kubectl("apply", "--namespace", namespace, "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest(chartName, outputPath); err != nil {
+				return err
+			}
 		}
 
-		err = kubectl("apply", "--namespace", namespace, "-R", "-f", outputPath)
-		if err != nil {
+		if err := recordInstalled(chartName, chartVersion(chartPath, chartName)); err != nil {
 			return err
 		}
 
-		fmt.Println(
+		logInfo(
 			`=======================================================================
 chart ` + chartRepoName + ` installed.
 =======================================================================
-		
-Thank you for using k3sup!`)
+
+` + thanksForUsing)
 
 		return nil
 	}
 
 	return chartCmd
 }
+
+// chartNameFromRepo returns the name of a chart given as repo/chart
+func chartNameFromRepo(chartRepoName string) string {
+	if index := strings.Index(chartRepoName, "/"); index > -1 {
+		return chartRepoName[index+1:]
+	}
+	return chartRepoName
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var skipVerify = false
+
+// AddVerifyFlags adds --skip-verify to root and all of its sub-commands
+func AddVerifyFlags(root *cobra.Command) {
+	root.PersistentFlags().BoolVar(&skipVerify, "skip-verify", skipVerify, "Use downloads of k3s, helm, app CLIs and k3sup itself without checking them against their published SHA256 checksums")
+}
+
+// downloadFile saves url to dest. It is written to a temporary file first,
+// which is only renamed to dest once it matches the SHA256 checksum
+// published at checksumURL. An empty checksumURL is for a download which
+// has no checksum published.
+func downloadFile(url, checksumURL, dest string, mode os.FileMode) error {
+	want := ""
+	if skipVerify {
+		logWarnf("Not verifying the checksum of %s, as --skip-verify was given\n", url)
+	} else if len(checksumURL) == 0 {
+		logDebugf("No checksum is published for %s\n", url)
+	} else {
+		var err error
+		if want, err = fetchChecksum(checksumURL, path.Base(url)); err != nil {
+			return err
+		}
+	}
+
+	res, err := http.DefaultClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("unable to download %s: %s", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to download %s: %s", url, res.Status)
+	}
+
+	tmp := dest + ".download"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+
+	body := io.Reader(res.Body)
+	bar := startDownloadProgress(url)
+	if bar.drawing() {
+		body = &downloadProgress{reader: res.Body, progress: bar, total: res.ContentLength}
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), body)
+	bar.Stop(err)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("unable to download %s: %s", url, err)
+	}
+
+	if got := hex.EncodeToString(hash.Sum(nil)); len(want) > 0 && got != want {
+		os.Remove(tmp)
+		return fmt.Errorf("the SHA256 checksum of %s is %s, but %s was published, the download may be corrupt or tampered with. Give --skip-verify to use it anyway", url, got, want)
+	}
+
+	if len(want) > 0 {
+		logDebugf("Verified the SHA256 checksum of %s\n", url)
+	}
+
+	return os.Rename(tmp, dest)
+}
+
+// fetchChecksum downloads a checksum file and returns the checksum of name
+func fetchChecksum(checksumURL, name string) (string, error) {
+	res, err := http.DefaultClient.Get(checksumURL)
+	if err != nil {
+		return "", fmt.Errorf("unable to download the checksum %s: %s, give --skip-verify to download without it", checksumURL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to download the checksum %s: %s, give --skip-verify to download without it", checksumURL, res.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, 1024*1024))
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := parseChecksum(string(data), name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", checksumURL, err)
+	}
+	return checksum, nil
+}
+
+// parseChecksum reads the output of sha256sum, with a line of "CHECKSUM
+// NAME" for each file, and returns the checksum of name. A file with a
+// single checksum and no name is also accepted.
+func parseChecksum(data, name string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 1 && len(lines) == 1 {
+			return validChecksum(fields[0])
+		}
+
+		// sha256sum marks a file read in binary mode with *
+		if len(fields) == 2 && path.Base(strings.TrimPrefix(fields[1], "*")) == name {
+			return validChecksum(fields[0])
+		}
+	}
+
+	return "", fmt.Errorf("no checksum found for %s", name)
+}
+
+func validChecksum(checksum string) (string, error) {
+	checksum = strings.ToLower(checksum)
+	if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid SHA256 checksum: %q", checksum)
+	}
+	return checksum, nil
+}
+
+// downloadTarball downloads and verifies the tarball at url as for
+// downloadFile, then extracts it to dir
+func downloadTarball(url, checksumURL, dir string) error {
+	tmp, err := ioutil.TempFile("", "k3sup-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := downloadFile(url, checksumURL, tmp.Name(), 0600); err != nil {
+		return err
+	}
+
+	file, err := os.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return Untar(file, dir)
+}
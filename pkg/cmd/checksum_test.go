@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// binarySHA256 is the SHA256 checksum of "binary"
+const binarySHA256 = "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd"
+
+func Test_parseChecksum(t *testing.T) {
+	tests := []struct {
+		title string
+		data  string
+		name  string
+		want  string
+	}{
+		{"only a checksum", binarySHA256 + "\n", "helm.tar.gz", binarySHA256},
+		{"sha256sum", "0000000000000000000000000000000000000000000000000000000000000000  k3s-arm64\n" + binarySHA256 + "  k3s\n", "k3s", binarySHA256},
+		{"binary mode and a folder", strings.ToUpper(binarySHA256) + " *bin/k3sup", "k3sup", binarySHA256},
+	}
+
+	for _, test := range tests {
+		got, err := parseChecksum(test.data, test.name)
+		if err != nil {
+			t.Errorf("%s: %s", test.title, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: want %s, got %s", test.title, test.want, got)
+		}
+	}
+}
+
+func Test_parseChecksum_Invalid(t *testing.T) {
+	if _, err := parseChecksum(binarySHA256+"  k3s\n", "k3s-arm64"); err == nil {
+		t.Errorf("want an error for a file which is not listed")
+	}
+	if _, err := parseChecksum("abc  k3s\n", "k3s"); err == nil {
+		t.Errorf("want an error for an invalid checksum")
+	}
+}
+
+func checksumServer(checksum string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/k3s":
+			fmt.Fprint(w, "binary")
+		case "/sha256sum.txt":
+			fmt.Fprintf(w, "%s  k3s\n", checksum)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func Test_downloadFile_Verified(t *testing.T) {
+	server := checksumServer(binarySHA256)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "k3sup-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, "k3s")
+	if err := downloadFile(server.URL+"/k3s", server.URL+"/sha256sum.txt", dest, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := ioutil.ReadFile(dest); string(data) != "binary" {
+		t.Errorf("want the binary, got %q", data)
+	}
+}
+
+func Test_downloadFile_Mismatch(t *testing.T) {
+	server := checksumServer(strings.Repeat("0", 64))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "k3sup-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, "k3s")
+	err = downloadFile(server.URL+"/k3s", server.URL+"/sha256sum.txt", dest, 0700)
+	if err == nil || !strings.Contains(err.Error(), "--skip-verify") {
+		t.Errorf("want an error for the checksum, got %v", err)
+	}
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("want nothing written for a mismatch")
+	}
+	if _, err := os.Stat(dest + ".download"); !os.IsNotExist(err) {
+		t.Errorf("want the temporary file removed for a mismatch")
+	}
+}
+
+func Test_downloadFile_SkipVerify(t *testing.T) {
+	server := checksumServer(strings.Repeat("0", 64))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "k3sup-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	skipVerify = true
+	defer func() { skipVerify = false }()
+
+	if err := downloadFile(server.URL+"/k3s", server.URL+"/missing.txt", filepath.Join(dir, "k3s"), 0700); err != nil {
+		t.Errorf("want no error with --skip-verify, got %s", err)
+	}
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// bashCompletionFunction completes the apps recorded as installed for
+// "k3sup app uninstall" and "k3sup app upgrade"
+const bashCompletionFunction = `__k3sup_custom_func() {
+    case ${last_command} in
+        k3sup_app_uninstall | k3sup_app_upgrade)
+            COMPREPLY=( $(compgen -W "$(k3sup completion __apps --installed 2>/dev/null)" -- "$cur") )
+            return
+            ;;
+    esac
+}
+`
+
+func MakeCompletion() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Print a shell completion script",
+		Long: `Print a script which completes the commands, flags and app names of k3sup
+for bash, zsh, fish or powershell.`,
+		Example: `  source <(k3sup completion bash)
+  k3sup completion zsh > "${fpath[1]}/_k3sup"
+  k3sup completion fish > ~/.config/fish/completions/k3sup.fish
+  k3sup completion powershell | Out-String | Invoke-Expression`,
+		ValidArgs:    []string{"bash", "zsh", "fish", "powershell"},
+		SilenceUsage: true,
+	}
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("give the name of a shell, i.e. k3sup completion bash")
+		}
+
+		root := command.Root()
+
+		switch args[0] {
+		case "bash":
+			root.BashCompletionFunction = bashCompletionFunction
+			return root.GenBashCompletion(textOutput)
+		case "zsh":
+			return root.GenZshCompletion(textOutput)
+		case "fish":
+			return genFishCompletion(root, os.Stdout)
+		case "powershell":
+			return root.GenPowerShellCompletion(textOutput)
+		}
+
+		return fmt.Errorf("unsupported shell %q, use bash, zsh, fish or powershell", args[0])
+	}
+
+	var apps = &cobra.Command{
+		Use:    "__apps",
+		Short:  "Print the names of apps for completion scripts",
+		Hidden: true,
+	}
+
+	apps.Flags().Bool("installed", false, "Only print the apps which have been installed with k3sup")
+
+	apps.RunE = func(command *cobra.Command, args []string) error {
+		names := getApps()
+
+		if installed, _ := command.Flags().GetBool("installed"); installed {
+			var err error
+			if names, err = listAppStates(); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range names {
+			fmt.Fprintln(textOutput, name)
+		}
+		return nil
+	}
+
+	command.AddCommand(apps)
+
+	return command
+}
+
+// fishCompletionHelpers are used by genFishCompletion to find the command
+// being completed, skipping over flags and their values
+const fishCompletionHelpers = `function __k3sup_words
+    set -l words
+    for w in (commandline -opc)[2..-1]
+        if not string match -q -- '-*' $w
+            set words $words $w
+        end
+    end
+    echo $words
+end
+
+function __k3sup_using
+    set -l words (__k3sup_words)
+    test "$words" = "$argv"
+end
+
+function __k3sup_seen
+    set -l words (__k3sup_words)
+    string match -q -- "$argv*" "$words"
+end
+
+complete -c k3sup -n '__k3sup_using app uninstall; or __k3sup_using app upgrade' -f -a '(k3sup completion __apps --installed 2>/dev/null)'
+`
+
+// genFishCompletion writes a fish completion script for root, cobra does
+// not generate one for fish
+func genFishCompletion(root *cobra.Command, w io.Writer) error {
+	if _, err := io.WriteString(w, fishCompletionHelpers); err != nil {
+		return err
+	}
+
+	return writeFishCompletions(w, root, []string{})
+}
+
+func writeFishCompletions(w io.Writer, command *cobra.Command, path []string) error {
+	condition := strings.TrimSpace("__k3sup_using " + strings.Join(path, " "))
+
+	for _, arg := range command.ValidArgs {
+		if _, err := fmt.Fprintf(w, "complete -c k3sup -n '%s' -f -a %s\n", condition, arg); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range command.Commands() {
+		if c.Hidden || !c.IsAvailableCommand() {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "complete -c k3sup -n '%s' -f -a %s -d %s\n", condition, c.Name(), fishQuote(c.Short)); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	seen := strings.TrimSpace("__k3sup_seen " + strings.Join(path, " "))
+	command.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Hidden {
+			return
+		}
+
+		line := fmt.Sprintf("complete -c k3sup -n '%s' -l %s", seen, flag.Name)
+		if len(flag.Shorthand) > 0 {
+			line += " -s " + flag.Shorthand
+		}
+		if flag.Value.Type() != "bool" {
+			line += " -r"
+		}
+
+		_, err = fmt.Fprintf(w, "%s -d %s\n", line, fishQuote(flag.Usage))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range command.Commands() {
+		if c.Hidden || !c.IsAvailableCommand() {
+			continue
+		}
+
+		if err := writeFishCompletions(w, c, append(append([]string{}, path...), c.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fishQuote quotes s for a fish script, only the first line is kept
+func fishQuote(s string) string {
+	s = strings.SplitN(s, "\n", 2)[0]
+	return "'" + strings.Replace(strings.Replace(s, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_genFishCompletion(t *testing.T) {
+	root := &cobra.Command{Use: "k3sup"}
+	root.PersistentFlags().Bool("verbose", false, "Print debug output")
+
+	app := &cobra.Command{Use: "app", Short: "Manage Kubernetes apps", Run: func(*cobra.Command, []string) {}}
+	install := &cobra.Command{Use: "install", Short: "Install a Kubernetes app", Run: func(*cobra.Command, []string) {}}
+	install.Flags().String("kubeconfig", "kubeconfig", "Local path for your kubeconfig file")
+	info := &cobra.Command{Use: "info", Short: "Show the app's instructions", ValidArgs: []string{"openfaas"}, Run: func(*cobra.Command, []string) {}}
+
+	app.AddCommand(install, info)
+	root.AddCommand(app)
+
+	buf := bytes.Buffer{}
+	if err := genFishCompletion(root, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"complete -c k3sup -n '__k3sup_using' -f -a app -d 'Manage Kubernetes apps'",
+		"complete -c k3sup -n '__k3sup_seen' -l verbose -d 'Print debug output'",
+		"complete -c k3sup -n '__k3sup_using app' -f -a install -d 'Install a Kubernetes app'",
+		"complete -c k3sup -n '__k3sup_seen app install' -l kubeconfig -r -d 'Local path for your kubeconfig file'",
+		"complete -c k3sup -n '__k3sup_using app info' -f -a openfaas",
+		`-d 'Show the app\'s instructions'`,
+	}
+
+	for _, line := range want {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("want: %q in:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var assumeYes = false
+
+var (
+	// confirmLock asks one question at a time, as agents are joined in
+	// parallel by plan and join --hosts
+	confirmLock sync.Mutex
+
+	confirmInput  io.Reader = os.Stdin
+	confirmOutput io.Writer = os.Stderr
+
+	stdinTerminal = func() bool {
+		return terminal.IsTerminal(int(os.Stdin.Fd()))
+	}
+)
+
+// AddConfirmFlags adds --yes to root and all of its sub-commands
+func AddConfirmFlags(root *cobra.Command) {
+	root.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", assumeYes, "Do not ask before uninstalling k3s or an app, overwriting a kubeconfig or installing over an existing k3s node")
+}
+
+// confirm asks whether to go ahead with something which can not be undone,
+// it returns an error unless the answer is yes. Without a terminal to ask
+// on, as in a script, it goes ahead as k3sup did before it asked.
+func confirm(format string, a ...interface{}) error {
+	question := fmt.Sprintf(format, a...)
+	if assumeYes {
+		logDebugf("%s yes, as --yes was given\n", question)
+		return nil
+	}
+
+	if !stdinTerminal() {
+		logDebugf("%s yes, as stdin is not a terminal\n", question)
+		return nil
+	}
+
+	confirmLock.Lock()
+	defer confirmLock.Unlock()
+
+	clearProgress()
+	fmt.Fprintf(confirmOutput, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(confirmInput).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("cancelled, as the answer was not yes")
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// answer runs confirm with answer typed in a terminal
+func answer(answer string, fn func() error) error {
+	input, output, terminal := confirmInput, confirmOutput, stdinTerminal
+	confirmInput, confirmOutput = strings.NewReader(answer), ioutil.Discard
+	stdinTerminal = func() bool { return true }
+	defer func() {
+		confirmInput, confirmOutput, stdinTerminal = input, output, terminal
+	}()
+
+	return fn()
+}
+
+func Test_confirm_Answers(t *testing.T) {
+	tests := map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+	}
+
+	for input, want := range tests {
+		err := answer(input, func() error {
+			return confirm("Uninstall k3s from %s?", "192.168.0.100")
+		})
+		if got := err == nil; got != want {
+			t.Errorf("%q: want confirmed %t, got %t (%v)", input, want, got, err)
+		}
+	}
+}
+
+func Test_confirm_NoTerminal(t *testing.T) {
+	terminal := stdinTerminal
+	stdinTerminal = func() bool { return false }
+	defer func() { stdinTerminal = terminal }()
+
+	if err := confirm("Uninstall k3s from %s?", "192.168.0.100"); err != nil {
+		t.Errorf("want no question without a terminal, got %s", err)
+	}
+
+	assumeYes = true
+	defer func() { assumeYes = false }()
+
+	if err := confirm("Uninstall k3s from %s?", "192.168.0.100"); err != nil {
+		t.Errorf("want no question with --yes, got %s", err)
+	}
+}
+
+func Test_confirmReinstall(t *testing.T) {
+	installed := &nodeOperator{replies: map[string]string{"ls ": agentUninstallScript + "\n"}}
+	if err := answer("n\n", func() error { return confirmReinstall(installed, "192.168.0.101") }); err == nil {
+		t.Errorf("want an error when the install over k3s is not confirmed")
+	}
+
+	fresh := &nodeOperator{replies: map[string]string{"ls ": ""}}
+	if err := answer("", func() error { return confirmReinstall(fresh, "192.168.0.101") }); err != nil {
+		t.Errorf("want no question for a node without k3s, got %s", err)
+	}
+}
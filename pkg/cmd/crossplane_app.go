@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// crossplaneProviders is the package installed for each --provider
+var crossplaneProviders = map[string]string{
+	"aws":   "crossplane/provider-aws:v0.18.1",
+	"gcp":   "crossplane/provider-gcp:v0.17.0",
+	"azure": "crossplane/provider-azure:v0.16.0",
+}
+
+// crossplaneProvider holds the values for templates/crossplane/providers.yaml
+type crossplaneProvider struct {
+	Name    string
+	Package string
+}
+
+func makeInstallCrossplane() *cobra.Command {
+	var crossplane = &cobra.Command{
+		Use:   "crossplane",
+		Short: "Install crossplane",
+		Long: `Install crossplane to manage cloud resources from the cluster, along with
+the package for each --provider. Configure a provider's credentials with a
+ProviderConfig once it is installed.`,
+		Example: `  k3sup app install crossplane --provider aws
+  k3sup app install crossplane --provider gcp --provider azure`,
+		SilenceUsage: true,
+	}
+
+	crossplane.Flags().StringP("namespace", "n", "crossplane-system", "The namespace used for installation")
+	crossplane.Flags().String("version", "1.2.1", "The version of the crossplane chart to install")
+	crossplane.Flags().StringArray("provider", []string{}, "Install the provider package for: aws, gcp or azure, can be repeated")
+	crossplane.Flags().Bool("update-repo", true, "Update the helm repo")
+
+	crossplane.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		version, _ := command.Flags().GetString("version")
+		providerNames, _ := command.Flags().GetStringArray("provider")
+
+		providers, err := getCrossplaneProviders(providerNames)
+		if err != nil {
+			return err
+		}
+
+		providersFile := ""
+		if len(providers) > 0 {
+			providersYaml, err := buildCrossplaneProviders(providers)
+			if err != nil {
+				return err
+			}
+
+			providersFile, err = writeTempFile("crossplane_providers", providersYaml)
+			if err != nil {
+				return err
+			}
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("crossplane-stable", "https://charts.crossplane.io/stable", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("crossplane", namespace); err != nil {
+				return err
+			}
+		}
+
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "crossplane-stable/crossplane", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		outputPath := path.Join(chartPath, "crossplane/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, nil)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "crossplane",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("crossplane", "crossplane", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "crossplane",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				sources := []string{outputPath}
+				if len(providersFile) > 0 {
+					sources = append(sources, providersFile)
+				}
+				return printManifests(command, sources...)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("crossplane", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if len(providersFile) > 0 {
+			if err := waitForCRDs("providers.pkg.crossplane.io"); err != nil {
+				return err
+			}
+
+			if err := applyManifests("crossplane", providersFile); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("crossplane", chartVersion(chartPath, "crossplane")); err != nil {
+			return err
+		}
+
+		logInfo(crossplaneInstallMsg)
+
+		return nil
+	}
+
+	return crossplane
+}
+
+// getCrossplaneProviders returns the packages for names, sorted by name
+func getCrossplaneProviders(names []string) ([]crossplaneProvider, error) {
+	providers := []crossplaneProvider{}
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		pkg, ok := crossplaneProviders[name]
+		if !ok {
+			return nil, fmt.Errorf("give the --provider as aws, gcp or azure, not: %q", name)
+		}
+
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		providers = append(providers, crossplaneProvider{Name: "provider-" + name, Package: pkg})
+	}
+
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+
+	return providers, nil
+}
+
+func buildCrossplaneProviders(providers []crossplaneProvider) ([]byte, error) {
+	tmpl, err := appTemplate("crossplane/providers.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, providers); err != nil {
+		return nil, err
+	}
+
+	return tpl.Bytes(), nil
+}
+
+const crossplaneInfoMsg = `# Check that the providers are installed and healthy
+kubectl get providers
+
+# Give a provider its credentials with a secret and a ProviderConfig, see:
+# https://crossplane.io/docs/v1.2/getting-started/install-configure.html
+
+# Find out more at:
+# https://crossplane.io/docs/`
+
+const crossplaneInstallMsg = `=======================================================================
+= crossplane has been installed.                                      =
+=======================================================================
+
+` + crossplaneInfoMsg + `
+
+` + thanksForUsing
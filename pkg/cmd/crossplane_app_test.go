@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_getCrossplaneProviders(t *testing.T) {
+	providers, err := getCrossplaneProviders([]string{"gcp", "AWS", "gcp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(providers) != 2 {
+		t.Fatalf("want 2 providers, got: %v", providers)
+	}
+
+	if providers[0].Name != "provider-aws" || providers[1].Name != "provider-gcp" {
+		t.Errorf("want aws then gcp, got: %v", providers)
+	}
+
+	if _, err := getCrossplaneProviders([]string{"alibaba"}); err == nil {
+		t.Errorf("want error for an unsupported provider")
+	}
+}
+
+func Test_buildCrossplaneProviders(t *testing.T) {
+	templBytes, err := buildCrossplaneProviders([]crossplaneProvider{
+		{Name: "provider-aws", Package: "crossplane/provider-aws:v0.18.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "kind: Provider\nmetadata:\n  name: provider-aws\nspec:\n  package: crossplane/provider-aws:v0.18.1\n"
+	if !strings.Contains(string(templBytes), want) {
+		t.Errorf("want %q in: %q", want, string(templBytes))
+	}
+}
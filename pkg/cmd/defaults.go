@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const defaultConfigPath = "~/.k3sup/config.yaml"
+
+var configPath = defaultConfigPath
+
+// flagDefaults are the values read from ~/.k3sup/config.yaml or --config
+// for flags which are not given on the command-line. Global values are used
+// by every command which has the flag, such as:
+//
+//	ssh-key: ~/.ssh/id_ed25519
+//	user: pi
+//
+// A section named after a command, such as "install" or "app install
+// openfaas", applies to that command and its sub-commands. Its flags must
+// exist, and a more specific section wins:
+//
+//	install:
+//	  context: rpi
+//	app install chart:
+//	  set:
+//	    - replicas=2
+type flagDefaults struct {
+	Global   map[string][]string
+	Sections map[string]map[string][]string
+}
+
+// envPrefix is prepended to the name of a flag to find its environment
+// variable, i.e. K3SUP_SSH_KEY for --ssh-key
+const envPrefix = "K3SUP_"
+
+// flagHooks check and apply the global flags, such as --verbose, once they
+// have been read from the command-line, environment and config file
+var flagHooks []func() error
+
+// AddConfigFlag adds the --config flag to root. Before a command is run, its
+// flags which were not given are read from K3SUP_* environment variables,
+// and then from the config file.
+func AddConfigFlag(root *cobra.Command) {
+	root.PersistentFlags().StringVar(&configPath, "config", defaultConfigPath, "Config file with default values for flags")
+
+	root.PersistentPreRunE = applyFlags
+}
+
+// applyFlags sets the flags of command which were not given on the
+// command-line, then runs the flagHooks. It is the PersistentPreRunE of the
+// root command, one set on a sub-command must call it first, as cobra only
+// runs the nearest.
+func applyFlags(command *cobra.Command, args []string) error {
+	if err := applyEnvFlags(command, os.LookupEnv); err != nil {
+		return err
+	}
+
+	defaults, err := loadFlagDefaults(configPath, command.Flags().Changed("config"))
+	if err != nil {
+		return err
+	}
+
+	if err := applyFlagDefaults(command, defaults); err != nil {
+		return err
+	}
+
+	for _, hook := range flagHooks {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyEnvFlags sets each flag of command which was not given on the
+// command-line from its environment variable, so that secrets such as
+// --datastore do not appear in the process list
+func applyEnvFlags(command *cobra.Command, lookupEnv func(string) (string, bool)) error {
+	var err error
+
+	command.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed {
+			return
+		}
+
+		name := envName(flag.Name)
+		value, ok := lookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if setErr := command.Flags().Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for --%s from %s: %s", value, flag.Name, name, setErr)
+		}
+	})
+
+	return err
+}
+
+// envName returns the environment variable for a flag
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+// loadFlagDefaults reads the config file at path, it is only an error for
+// the file not to exist when it was given with --config
+func loadFlagDefaults(path string, required bool) (*flagDefaults, error) {
+	data, err := ioutil.ReadFile(expandPath(path))
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return &flagDefaults{}, nil
+		}
+		return nil, fmt.Errorf("unable to read config file: %s", err)
+	}
+
+	defaults, err := parseFlagDefaults(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config file %s: %s", path, err)
+	}
+
+	return defaults, nil
+}
+
+// applyFlagDefaults sets each flag of command which has a default and was
+// not given on the command-line
+func applyFlagDefaults(command *cobra.Command, defaults *flagDefaults) error {
+	given := map[string]bool{}
+	command.Flags().Visit(func(flag *pflag.Flag) {
+		given[flag.Name] = true
+	})
+
+	set := func(name string, values []string) error {
+		if given[name] {
+			return nil
+		}
+		given[name] = true
+
+		flagType := command.Flags().Lookup(name).Value.Type()
+		list := flagType == "stringArray" || strings.HasSuffix(flagType, "Slice")
+		if !list && len(values) != 1 {
+			return fmt.Errorf("--%s takes one value in the config file, not %d", name, len(values))
+		}
+
+		for _, value := range values {
+			if err := command.Flags().Set(name, value); err != nil {
+				return fmt.Errorf("invalid value %q for --%s in the config file: %s", value, name, err)
+			}
+		}
+		return nil
+	}
+
+	path := strings.Fields(strings.TrimPrefix(command.CommandPath(), command.Root().Name()))
+	for i := len(path); i > 0; i-- {
+		section := strings.Join(path[:i], " ")
+
+		for name, values := range defaults.Sections[section] {
+			if command.Flags().Lookup(name) == nil {
+				return fmt.Errorf("unknown flag --%s in the %q section of the config file", name, section)
+			}
+
+			if err := set(name, values); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, values := range defaults.Global {
+		if command.Flags().Lookup(name) == nil {
+			continue
+		}
+
+		if err := set(name, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseFlagDefaults reads the config file, whose top-level keys are
+// global flags or sections of flags for a command
+func parseFlagDefaults(data []byte) (*flagDefaults, error) {
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	defaults := &flagDefaults{
+		Global:   map[string][]string{},
+		Sections: map[string]map[string][]string{},
+	}
+
+	for key, value := range config {
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			values, err := flagValues(key, value)
+			if err != nil {
+				return nil, err
+			}
+			defaults.Global[key] = values
+			continue
+		}
+
+		defaults.Sections[key] = map[string][]string{}
+		for name, value := range section {
+			values, err := flagValues(name, value)
+			if err != nil {
+				return nil, fmt.Errorf("%s in the %q section", err, key)
+			}
+			defaults.Sections[key][name] = values
+		}
+	}
+
+	return defaults, nil
+}
+
+// flagValues returns the value of a flag in the config file as it would be
+// given on the command-line, a list gives the flag once for each item
+func flagValues(name string, value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+
+	values := []string{}
+	for _, item := range list {
+		switch item.(type) {
+		case string, bool, float64:
+			values = append(values, fmt.Sprint(item))
+		default:
+			return nil, fmt.Errorf("--%s needs a value or a list of values", name)
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/apps"
+
+	"github.com/spf13/cobra"
+)
+
+// dryRunFromFlags returns true when --dry-run was given, in which case an app
+// prints the manifests it would apply instead of changing the cluster
+func dryRunFromFlags(command *cobra.Command) (bool, error) {
+	dryRun, _ := command.Flags().GetBool("dry-run")
+	helm3, _ := command.Flags().GetBool("helm3")
+
+	if dryRun && helm3 {
+		return false, fmt.Errorf("--dry-run renders charts with helm 2, so it cannot be used with --helm3")
+	}
+
+	return dryRun, nil
+}
+
+// printManifests writes the manifests from sources to --output-file, or to
+// stdout when it is not given. Sources are URLs, files or folders.
+func printManifests(command *cobra.Command, sources ...string) error {
+	outputFile, _ := command.Flags().GetString("output-file")
+
+	if len(outputFile) == 0 {
+		return writeManifests(os.Stdout, sources)
+	}
+
+	file, err := os.OpenFile(expandPath(outputFile), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := writeManifests(file, sources); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(textOutput, "Manifests written to: %s\n", outputFile)
+	return nil
+}
+
+// writeManifests concatenates each YAML or JSON document found in sources
+// into a single multi-document stream
+func writeManifests(w io.Writer, sources []string) error {
+	for _, source := range sources {
+		if apps.IsURL(source) {
+			data, err := fetchManifest(source)
+			if err != nil {
+				return err
+			}
+
+			if err := writeManifest(w, source, data); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := filepath.Walk(source, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			ext := filepath.Ext(p)
+			if info.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+				return nil
+			}
+
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+
+			name, err := filepath.Rel(filepath.Dir(source), p)
+			if err != nil {
+				return err
+			}
+
+			return writeManifest(w, name, data)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeManifest(w io.Writer, name string, data []byte) error {
+	content := strings.TrimPrefix(strings.TrimSpace(string(data)), "---")
+	if len(strings.TrimSpace(content)) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "---\n# Source: %s\n%s\n", name, strings.TrimSpace(content))
+	return err
+}
+
+func fetchManifest(url string) ([]byte, error) {
+	res, err := http.DefaultClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download %s, status: %d", url, res.StatusCode)
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_writeManifests_concatenates_rendered_chart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k3sup-rendered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	templates := path.Join(dir, "rendered", "openfaas", "templates")
+	if err := os.MkdirAll(templates, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"a-deployment.yaml": "---\nkind: Deployment\n",
+		"b-service.yaml":    "kind: Service",
+		"empty.yaml":        "---\n",
+		"NOTES.txt":         "not a manifest",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(templates, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := bytes.Buffer{}
+	if err := writeManifests(&buf, []string{path.Join(dir, "rendered")}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `---
+# Source: rendered/openfaas/templates/a-deployment.yaml
+kind: Deployment
+---
+# Source: rendered/openfaas/templates/b-service.yaml
+kind: Service
+`
+	if buf.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// externalDNSEnv is read for each provider's credentials when
+// --secret-file is not given
+var externalDNSEnv = map[string]string{
+	"cloudflare":   "CF_API_TOKEN",
+	"route53":      "AWS_SECRET_ACCESS_KEY",
+	"digitalocean": "DO_TOKEN",
+}
+
+func makeInstallExternalDNS() *cobra.Command {
+	var externalDNS = &cobra.Command{
+		Use:   "external-dns",
+		Short: "Install external-dns",
+		Long: `Install external-dns to publish the hostnames of Ingresses and LoadBalancer
+services, such as those from k3sup's ingress apps, to a DNS provider.
+
+The credentials are read from --secret-file, or from CF_API_TOKEN,
+AWS_SECRET_ACCESS_KEY or DO_TOKEN for each provider. For route53 the access
+key ID is read from --aws-access-key-id or AWS_ACCESS_KEY_ID.`,
+		Example: `  k3sup app install external-dns --provider cloudflare --secret-file ~/cloudflare-token.txt
+  AWS_ACCESS_KEY_ID=... AWS_SECRET_ACCESS_KEY=... \
+    k3sup app install external-dns --provider route53 --domain-filter example.com`,
+		SilenceUsage: true,
+	}
+
+	externalDNS.Flags().StringP("namespace", "n", "external-dns", "The namespace used for installation")
+	externalDNS.Flags().String("version", "", "The version of the external-dns chart (Default to the latest)")
+	externalDNS.Flags().String("provider", "", "The DNS provider: cloudflare, route53 or digitalocean")
+	externalDNS.Flags().String("secret-file", "", "File with the API token, or the AWS secret access key")
+	externalDNS.Flags().String("aws-access-key-id", "", "AWS access key ID for route53 (Default to $AWS_ACCESS_KEY_ID)")
+	externalDNS.Flags().String("aws-region", "us-east-1", "AWS region for route53")
+	externalDNS.Flags().StringArray("domain-filter", []string{}, "Only publish hostnames within this domain, can be repeated")
+	externalDNS.Flags().String("txt-owner-id", "k3sup", "Marks the records owned by this cluster, so that other records are left alone")
+	externalDNS.Flags().Bool("sync", false, "Also delete records when their Ingress or Service is removed, instead of only adding and updating them")
+	externalDNS.Flags().Bool("update-repo", true, "Update the helm repo")
+
+	externalDNS.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		provider, _ := command.Flags().GetString("provider")
+		secretFile, _ := command.Flags().GetString("secret-file")
+		domainFilters, _ := command.Flags().GetStringArray("domain-filter")
+		txtOwnerID, _ := command.Flags().GetString("txt-owner-id")
+		sync, _ := command.Flags().GetBool("sync")
+
+		overrides, err := externalDNSCredentials(command, provider, secretFile, os.Getenv)
+		if err != nil {
+			return err
+		}
+
+		overrides["provider"] = provider
+		overrides["sources"] = "{ingress,service}"
+		overrides["txtOwnerId"] = txtOwnerID
+		overrides["policy"] = "upsert-only"
+		if sync {
+			overrides["policy"] = "sync"
+		}
+		if len(domainFilters) > 0 {
+			overrides["domainFilters"] = "{" + strings.Join(domainFilters, ",") + "}"
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("bitnami", "https://charts.bitnami.com/bitnami", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("external-dns", namespace); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "bitnami/external-dns", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		outputPath := path.Join(chartPath, "external-dns/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "external-dns",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("external-dns", "external-dns", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "external-dns",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, outputPath)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("external-dns", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("external-dns", chartVersion(chartPath, "external-dns")); err != nil {
+			return err
+		}
+
+		logInfo(externalDNSInstallMsg)
+
+		return nil
+	}
+
+	return externalDNS
+}
+
+// externalDNSCredentials returns the chart values for the provider's
+// credentials, read from secretFile or the environment with getenv
+func externalDNSCredentials(command *cobra.Command, provider, secretFile string, getenv func(string) string) (map[string]string, error) {
+	envName, ok := externalDNSEnv[provider]
+	if !ok {
+		return nil, fmt.Errorf("give the --provider as cloudflare, route53 or digitalocean, not: %q", provider)
+	}
+
+	secret := getenv(envName)
+	if len(secretFile) > 0 {
+		data, err := ioutil.ReadFile(expandPath(secretFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read --secret-file: %s", err)
+		}
+		secret = string(data)
+	}
+
+	secret = strings.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("give the credentials for %s with --secret-file or $%s", provider, envName)
+	}
+
+	overrides := map[string]string{}
+
+	switch provider {
+	case "cloudflare":
+		overrides["cloudflare.apiToken"] = secret
+		overrides["cloudflare.proxied"] = "false"
+	case "digitalocean":
+		overrides["digitalocean.apiToken"] = secret
+	case "route53":
+		accessKeyID, _ := command.Flags().GetString("aws-access-key-id")
+		if len(accessKeyID) == 0 {
+			accessKeyID = getenv("AWS_ACCESS_KEY_ID")
+		}
+		if len(accessKeyID) == 0 {
+			return nil, fmt.Errorf("give the AWS access key ID for route53 with --aws-access-key-id or $AWS_ACCESS_KEY_ID")
+		}
+
+		region, _ := command.Flags().GetString("aws-region")
+		overrides["aws.credentials.accessKey"] = accessKeyID
+		overrides["aws.credentials.secretKey"] = secret
+		overrides["aws.region"] = region
+	}
+
+	return overrides, nil
+}
+
+const externalDNSInfoMsg = `# Hostnames from Ingresses, and from LoadBalancer services annotated
+# with this, will now be published:
+# external-dns.alpha.kubernetes.io/hostname: app.example.com
+
+# Check the logs for the records which were created
+kubectl logs -n external-dns deploy/external-dns
+
+# Find out more at:
+# https://github.com/kubernetes-sigs/external-dns`
+
+const externalDNSInstallMsg = `=======================================================================
+= external-dns has been installed.                                    =
+=======================================================================
+
+` + externalDNSInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newExternalDNSCommand() *cobra.Command {
+	command := &cobra.Command{}
+	command.Flags().String("aws-access-key-id", "", "")
+	command.Flags().String("aws-region", "us-east-1", "")
+	return command
+}
+
+func Test_externalDNSCredentials_FromEnv(t *testing.T) {
+	env := map[string]string{"CF_API_TOKEN": "token\n"}
+
+	overrides, err := externalDNSCredentials(newExternalDNSCommand(), "cloudflare", "", func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := overrides["cloudflare.apiToken"]; got != "token" {
+		t.Errorf("want token from the environment, got: %q", got)
+	}
+}
+
+func Test_externalDNSCredentials_Route53NeedsAccessKeyID(t *testing.T) {
+	env := map[string]string{"AWS_SECRET_ACCESS_KEY": "secret"}
+	getenv := func(k string) string { return env[k] }
+
+	if _, err := externalDNSCredentials(newExternalDNSCommand(), "route53", "", getenv); err == nil {
+		t.Errorf("want error without an access key ID")
+	}
+
+	env["AWS_ACCESS_KEY_ID"] = "AKIA"
+	overrides, err := externalDNSCredentials(newExternalDNSCommand(), "route53", "", getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if overrides["aws.credentials.accessKey"] != "AKIA" || overrides["aws.credentials.secretKey"] != "secret" {
+		t.Errorf("want AWS credentials from the environment, got: %v", overrides)
+	}
+}
+
+func Test_externalDNSCredentials_UnknownProvider(t *testing.T) {
+	_, err := externalDNSCredentials(newExternalDNSCommand(), "bind", "", func(string) string { return "x" })
+	if err == nil {
+		t.Errorf("want error for an unsupported provider")
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// fluxSource holds the values for templates/flux/sync.yaml, the git
+// repository which flux syncs the cluster from
+type fluxSource struct {
+	URL    string
+	Branch string
+	Path   string
+}
+
+func makeInstallFlux() *cobra.Command {
+	var flux = &cobra.Command{
+		Use:   "flux",
+		Short: "Install flux and sync from a git repository",
+		Long: `Install flux for GitOps, then sync the cluster from the manifests or
+kustomizations at --git-path in --git-url. The repository must be readable
+by the cluster, i.e. a public https URL.`,
+		Example: `  k3sup app install flux --git-url https://github.com/example/fleet \
+    --git-branch main --git-path clusters/prod`,
+		SilenceUsage: true,
+	}
+
+	flux.Flags().String("version", "v0.16.0", "The version of flux to install")
+	flux.Flags().String("git-url", "", "The git repository to sync the cluster from")
+	flux.Flags().String("git-branch", "main", "The branch to sync")
+	flux.Flags().String("git-path", "", "The path within the repository to sync (Default to the root)")
+
+	flux.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		source := fluxSource{}
+		source.URL, _ = command.Flags().GetString("git-url")
+		source.Branch, _ = command.Flags().GetString("git-branch")
+		gitPath, _ := command.Flags().GetString("git-path")
+
+		if len(source.URL) == 0 {
+			return fmt.Errorf("--git-url is required, give the git repository to sync the cluster from")
+		}
+
+		if len(source.Branch) == 0 {
+			return fmt.Errorf("--git-branch should not be empty")
+		}
+
+		source.Path = fluxPath(gitPath)
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		version, _ := command.Flags().GetString("version")
+		manifest := fmt.Sprintf("https://github.com/fluxcd/flux2/releases/download/%s/install.yaml", version)
+
+		sourceYaml, err := buildFluxSource(source)
+		if err != nil {
+			return err
+		}
+
+		sourceFile, err := writeTempFile("flux_source", sourceYaml)
+		if err != nil {
+			return err
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return printManifests(command, manifest, sourceFile)
+		}
+
+		if err := applyManifests("flux", manifest); err != nil {
+			return err
+		}
+
+		err = waitForCRDs("gitrepositories.source.toolkit.fluxcd.io", "kustomizations.kustomize.toolkit.fluxcd.io")
+		if err != nil {
+			return err
+		}
+
+		if err := applyManifests("flux", sourceFile); err != nil {
+			return err
+		}
+
+		if err := recordInstalled("flux", version); err != nil {
+			return err
+		}
+
+		logInfo(fluxInstallMsg)
+
+		return nil
+	}
+
+	return flux
+}
+
+// fluxPath returns gitPath relative to the root of the repository, as flux
+// expects it i.e. ./clusters/prod
+func fluxPath(gitPath string) string {
+	cleaned := path.Clean("/" + strings.TrimSpace(gitPath))
+	if cleaned == "/" {
+		return "./"
+	}
+	return "." + cleaned
+}
+
+func buildFluxSource(source fluxSource) ([]byte, error) {
+	tmpl, err := appTemplate("flux/sync.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, source); err != nil {
+		return nil, err
+	}
+
+	return tpl.Bytes(), nil
+}
+
+const fluxInfoMsg = `# Check that the git repository has been fetched and applied
+kubectl get gitrepository,kustomization -n flux-system
+
+# Commit and push to the repository to change the cluster, flux checks
+# for changes every minute. Install again to sync a different repository.
+
+# Get the flux CLI to follow a sync, or to force one
+flux get kustomizations --watch
+flux reconcile kustomization flux-system --with-source
+
+# Find out more at:
+# https://fluxcd.io/docs/`
+
+const fluxInstallMsg = `=======================================================================
+= flux has been installed.                                            =
+=======================================================================
+
+` + fluxInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_fluxPath(t *testing.T) {
+	cases := map[string]string{
+		"":                "./",
+		"/":               "./",
+		"clusters/prod":   "./clusters/prod",
+		"./clusters/prod": "./clusters/prod",
+		"/clusters/prod/": "./clusters/prod",
+	}
+
+	for gitPath, want := range cases {
+		if got := fluxPath(gitPath); got != want {
+			t.Errorf("%q, want: %q, got: %q", gitPath, want, got)
+		}
+	}
+}
+
+func Test_buildFluxSource(t *testing.T) {
+	templBytes, err := buildFluxSource(fluxSource{
+		URL:    "https://github.com/example/fleet",
+		Branch: "main",
+		Path:   "./clusters/prod",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	for _, want := range []string{
+		"  url: https://github.com/example/fleet\n  ref:\n    branch: main\n",
+		"  path: ./clusters/prod\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %q", want, got)
+		}
+	}
+}
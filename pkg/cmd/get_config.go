@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func MakeGetConfig() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "get-config",
+		Short: "Fetch the kubeconfig from a server via SSH",
+		Long: `Fetch the kubeconfig from an existing k3s server via SSH, without running
+the installer, i.e. on a new machine or after the file was lost. The names and
+server address are rewritten as for k3sup install.`,
+		Example: `  k3sup get-config --ip 192.168.0.100 --user root
+  k3sup get-config --ip 192.168.0.100 --merge --context k3s-prod
+  k3sup get-config --ip 192.168.0.100 --print-kubeconfig > kubeconfig`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+	command.Flags().Bool("local", false, "Fetch the kubeconfig of k3s on this machine instead of over SSH")
+	command.Flags().String("local-path", "kubeconfig", "Local path to save the kubeconfig file")
+	command.Flags().String("context", "default", "Set the name of the kubeconfig context.")
+	command.Flags().String("cluster-name", "", "Set the name of the cluster in the kubeconfig (Default to --context)")
+	command.Flags().String("user-name", "", "Set the name of the user in the kubeconfig (Default to --context)")
+	command.Flags().Bool("merge", false, "Merge the config into an existing kubeconfig under the name given by --context.\nDefaults to $KUBECONFIG or ~/.kube/config, unless --local-path is given")
+	command.Flags().Bool("no-backup", false, "Do not copy an existing kubeconfig to a backup with a timestamp before it is overwritten or merged")
+	command.Flags().Bool("print-kubeconfig", false, "Write the kubeconfig to stdout instead of saving it, i.e. to pipe it into a secret store, the progress is written to stderr")
+
+	command.Flags().SetNormalizeFunc(contextNameAlias)
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		printKubeconfig, _ := command.Flags().GetBool("print-kubeconfig")
+		if printKubeconfig {
+			progressToStderr()
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		local, _ := command.Flags().GetBool("local")
+		if local {
+			if os.Geteuid() == 0 {
+				escalation = escalateNone
+			}
+			// --ip is only needed for the address in the kubeconfig
+			if ip, _ := command.Flags().GetString("ip"); len(ip) == 0 {
+				command.Flags().Set("ip", "127.0.0.1")
+			}
+		}
+
+		opts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		merge, _ := command.Flags().GetBool("merge")
+		noBackup, _ := command.Flags().GetBool("no-backup")
+		context, _ := command.Flags().GetString("context")
+		clusterName, _ := command.Flags().GetString("cluster-name")
+		userName, _ := command.Flags().GetString("user-name")
+
+		return installK3s(installOptions{
+			SSH:                 opts,
+			PrivilegeEscalation: escalation,
+			SkipInstall:         true,
+			Local:               local,
+			LocalPath:           localKubeconfigFromFlags(command),
+			Names: kubeconfigNames{
+				Context: context,
+				Cluster: clusterName,
+				User:    userName,
+			},
+			Merge:           merge,
+			NoBackup:        noBackup,
+			PrintKubeconfig: printKubeconfig,
+		})
+	}
+
+	command.PreRunE = func(command *cobra.Command, args []string) error {
+		ip, _ := command.Flags().GetString("ip")
+		local, _ := command.Flags().GetBool("local")
+		if len(ip) == 0 && !local {
+			return fmt.Errorf("--ip is required, or give --local to fetch the kubeconfig from this machine")
+		}
+
+		if len(ip) > 0 {
+			if _, _, _, err := parseSSHAddress(ip, "", 0); err != nil {
+				return err
+			}
+		}
+
+		return validPrintKubeconfigFlags(command)
+	}
+
+	return command
+}
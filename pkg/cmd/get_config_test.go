@@ -0,0 +1,26 @@
+package cmd
+
+import "testing"
+
+func Test_MakeGetConfig_NeedsIPOrLocal(t *testing.T) {
+	command := MakeGetConfig()
+	if err := command.PreRunE(command, nil); err == nil {
+		t.Errorf("want an error without --ip or --local")
+	}
+
+	command.Flags().Set("local", "true")
+	if err := command.PreRunE(command, nil); err != nil {
+		t.Errorf("want no error with --local, got: %s", err)
+	}
+}
+
+func Test_MakeGetConfig_PrintKubeconfigWithMerge(t *testing.T) {
+	command := MakeGetConfig()
+	if err := command.ParseFlags([]string{"--ip", "192.168.0.100", "--print-kubeconfig", "--merge"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := command.PreRunE(command, nil); err == nil {
+		t.Errorf("want an error for --print-kubeconfig with --merge")
+	}
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/alexellis/k3sup/pkg/config"
+	"github.com/sethvargo/go-password/password"
+
+	"github.com/spf13/cobra"
+)
+
+// giteaOptions configures the gitea chart
+type giteaOptions struct {
+	Domain       string
+	TLS          bool
+	Persistence  bool
+	Size         string
+	StorageClass string
+	SQLite       bool
+}
+
+func makeInstallGitea() *cobra.Command {
+	var gitea = &cobra.Command{
+		Use:   "gitea",
+		Short: "Install gitea",
+		Long: `Install gitea for self-hosted git from the gitea chart, which needs helm 3.
+The admin user's password is generated and saved in the gitea-admin secret,
+it is kept when installing again.
+
+Give --email to expose gitea on --domain with an Ingress and a TLS
+certificate from Let's Encrypt, which requires cert-manager.
+
+Repositories are stored in a PersistentVolumeClaim unless --persistence=false
+is given. The bundled postgresql is only published for amd64, so sqlite is
+used instead on arm and arm64 nodes, or with --sqlite.`,
+		Example: `  k3sup app install gitea --helm3 --domain git.example.com
+  k3sup app install gitea --helm3 --domain git.example.com --email admin@example.com`,
+		SilenceUsage: true,
+	}
+
+	gitea.Flags().StringP("namespace", "n", "gitea", "The namespace used for installation")
+	gitea.Flags().String("version", "4.0.1", "The version of the gitea chart to install")
+	gitea.Flags().Bool("persistence", true, "Store repositories in a PersistentVolumeClaim, so that they are kept when the Pod restarts")
+	gitea.Flags().String("size", "10Gi", "The size of the PersistentVolumeClaim")
+	gitea.Flags().String("storage-class", "", "The StorageClass for the PersistentVolumeClaim (Default to the cluster's default)")
+	gitea.Flags().Bool("sqlite", false, "Use sqlite instead of the bundled postgresql, set by default for arm and arm64 nodes")
+	gitea.Flags().String("admin-user", "gitea_admin", "The name of the admin user")
+	gitea.Flags().Bool("update-repo", true, "Update the helm repo")
+	addIngressFlags(gitea)
+
+	gitea.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		if _, err := dryRunFromFlags(command); err != nil {
+			return err
+		}
+
+		helm3, _ := command.Flags().GetBool("helm3")
+		if !helm3 {
+			return fmt.Errorf("the gitea chart needs helm 3, install it with --helm3")
+		}
+
+		namespace, _ := command.Flags().GetString("namespace")
+		adminUser, _ := command.Flags().GetString("admin-user")
+		email, _ := command.Flags().GetString("email")
+
+		opts := giteaOptions{}
+		opts.Domain, _ = command.Flags().GetString("domain")
+		opts.Persistence, _ = command.Flags().GetBool("persistence")
+		opts.Size, _ = command.Flags().GetString("size")
+		opts.StorageClass, _ = command.Flags().GetString("storage-class")
+		opts.TLS = len(email) > 0
+
+		if len(opts.Domain) == 0 {
+			return fmt.Errorf("--domain is required, give the domain gitea will be served on")
+		}
+
+		if !opts.Persistence && (command.Flags().Changed("size") || command.Flags().Changed("storage-class")) {
+			return fmt.Errorf("--size and --storage-class can only be used with --persistence")
+		}
+
+		var inputData InputData
+		var err error
+		if opts.TLS {
+			inputData, err = ingressFromFlags(command)
+			if err != nil {
+				return err
+			}
+			inputData.IngressName = "gitea"
+			inputData.Namespace = namespace
+			inputData.ServiceName = "gitea-http"
+			inputData.ServicePort = 3000
+			inputData.TLSSecret = "gitea-tls"
+		}
+
+		arch := getArchitecture()
+		logDebugf("Node architecture: %q\n", arch)
+
+		opts.SQLite = arch == "arm" || arch == "arm64" || arch == "aarch64"
+		if command.Flags().Changed("sqlite") {
+			opts.SQLite, _ = command.Flags().GetBool("sqlite")
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("gitea-charts", "https://dl.gitea.io/charts/", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := createNamespace("gitea", namespace); err != nil {
+			return err
+		}
+
+		pass, err := createGiteaAdminSecret(namespace, adminUser)
+		if err != nil {
+			return err
+		}
+
+		chartPath := path.Join(os.TempDir(), "charts")
+		version, _ := command.Flags().GetString("version")
+		err = fetchChart(chartPath, "gitea-charts/gitea", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		overrides, userValues, err := chartValuesFromFlags(command, giteaOverrides(opts))
+		if err != nil {
+			return err
+		}
+
+		err = helm3Install(chartPath, "gitea",
+			namespace,
+			"values.yaml",
+			overrides, userValues)
+
+		if err != nil {
+			return err
+		}
+
+		if err := recordRelease("gitea", "gitea", namespace); err != nil {
+			return err
+		}
+
+		if opts.TLS {
+			if err := applyIngress(command, "gitea", inputData); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("gitea", chartVersion(chartPath, "gitea")); err != nil {
+			return err
+		}
+
+		logInfo(giteaInstallMsg)
+		logInfof(`# Log in as the admin user:
+#   username: %s
+#   password: %s
+`, adminUser, pass)
+
+		return nil
+	}
+
+	return gitea
+}
+
+// giteaOverrides sets the URLs gitea generates for clones and links, and
+// the storage for repositories and the database
+func giteaOverrides(opts giteaOptions) map[string]string {
+	scheme := "http"
+	if opts.TLS {
+		scheme = "https"
+	}
+
+	overrides := map[string]string{
+		"gitea.admin.existingSecret":     "gitea-admin",
+		"gitea.config.server.DOMAIN":     opts.Domain,
+		"gitea.config.server.ROOT_URL":   scheme + "://" + opts.Domain + "/",
+		"gitea.config.server.SSH_DOMAIN": opts.Domain,
+		"persistence.enabled":            strconv.FormatBool(opts.Persistence),
+		"memcached.enabled":              "false",
+	}
+
+	if opts.Persistence {
+		overrides["persistence.size"] = opts.Size
+		if len(opts.StorageClass) > 0 {
+			overrides["persistence.storageClass"] = opts.StorageClass
+		}
+	}
+
+	if opts.SQLite {
+		overrides["postgresql.enabled"] = "false"
+		overrides["gitea.config.database.DB_TYPE"] = "sqlite3"
+	}
+
+	return overrides
+}
+
+// createGiteaAdminSecret returns the admin user's password, which is
+// generated on the first install
+func createGiteaAdminSecret(namespace, username string) (string, error) {
+	pass, err := secretValue(namespace, "gitea-admin", "password")
+	if err != nil {
+		return "", err
+	}
+
+	if len(pass) > 0 {
+		logInfo("Using the existing gitea-admin secret")
+		return pass, recordSecret("gitea", "gitea-admin", namespace)
+	}
+
+	pass, err = password.Generate(25, 10, 0, false, true)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := kubectlTask("-n", namespace, "create", "secret", "generic",
+		"gitea-admin",
+		"--from-literal=username="+username,
+		"--from-literal=password="+pass)
+	if err != nil {
+		return "", err
+	}
+
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("unable to create the gitea-admin secret: %s", res.Stderr)
+	}
+
+	return pass, recordSecret("gitea", "gitea-admin", namespace)
+}
+
+const giteaInfoMsg = `# Get the admin user's password
+kubectl get secret -n gitea gitea-admin \
+  -o jsonpath="{.data.password}" | base64 --decode; echo
+
+# Forward gitea to your machine, then open http://127.0.0.1:3000
+# or your domain when installed with --email
+kubectl port-forward -n gitea svc/gitea-http 3000:3000 &
+
+# Find out more at:
+# https://docs.gitea.io`
+
+const giteaInstallMsg = `=======================================================================
+= gitea has been installed.                                           =
+=======================================================================
+
+` + giteaInfoMsg + `
+
+` + thanksForUsing
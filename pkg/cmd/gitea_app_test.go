@@ -0,0 +1,40 @@
+package cmd
+
+import "testing"
+
+func Test_giteaOverrides_TLSAndSQLite(t *testing.T) {
+	overrides := giteaOverrides(giteaOptions{Domain: "git.example.com", TLS: true, Persistence: true, Size: "20Gi", SQLite: true})
+
+	want := map[string]string{
+		"gitea.config.server.ROOT_URL":  "https://git.example.com/",
+		"persistence.size":              "20Gi",
+		"postgresql.enabled":            "false",
+		"gitea.config.database.DB_TYPE": "sqlite3",
+	}
+
+	for k, v := range want {
+		if overrides[k] != v {
+			t.Errorf("%s want: %q, got: %q", k, v, overrides[k])
+		}
+	}
+
+	if _, ok := overrides["persistence.storageClass"]; ok {
+		t.Errorf("want no storageClass when --storage-class is not given")
+	}
+}
+
+func Test_giteaOverrides_WithoutPersistence(t *testing.T) {
+	overrides := giteaOverrides(giteaOptions{Domain: "git.example.com", Size: "10Gi"})
+
+	if overrides["gitea.config.server.ROOT_URL"] != "http://git.example.com/" {
+		t.Errorf("want http ROOT_URL without TLS, got: %q", overrides["gitea.config.server.ROOT_URL"])
+	}
+
+	if overrides["persistence.enabled"] != "false" {
+		t.Errorf("want persistence disabled, got: %q", overrides["persistence.enabled"])
+	}
+
+	if _, ok := overrides["persistence.size"]; ok {
+		t.Errorf("want no size without persistence")
+	}
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/alexellis/k3sup/pkg/config"
+	"github.com/sethvargo/go-password/password"
+
+	"github.com/spf13/cobra"
+)
+
+// harborOptions configures the harbor chart
+type harborOptions struct {
+	Domain        string
+	AdminPassword string
+	Persistence   bool
+	RegistrySize  string
+	StorageClass  string
+}
+
+func makeInstallHarbor() *cobra.Command {
+	var harbor = &cobra.Command{
+		Use:   "harbor",
+		Short: "Install harbor",
+		Long: `Install the harbor registry on --domain with an Ingress and a TLS certificate
+from Let's Encrypt, which requires cert-manager. Harbor adds a web UI,
+projects, users and image scanning, use the registry app for a single
+user registry.
+
+A password is generated for the admin user and kept when installing again.
+The images for harbor are only published for amd64.`,
+		Example: `  k3sup app install harbor --domain harbor.example.com --email admin@example.com
+  k3sup app install harbor --domain harbor.example.com --email admin@example.com \
+    --registry-size 50Gi --storage-class local-path`,
+		SilenceUsage: true,
+	}
+
+	harbor.Flags().StringP("namespace", "n", "harbor", "The namespace used for installation")
+	harbor.Flags().String("version", "1.6.0", "The version of the harbor chart to install")
+	harbor.Flags().Bool("persistence", true, "Store images and the database in PersistentVolumeClaims, so that they are kept when Pods restart")
+	harbor.Flags().String("registry-size", "5Gi", "The size of the PersistentVolumeClaim for images")
+	harbor.Flags().String("storage-class", "", "The StorageClass for the PersistentVolumeClaims (Default to the cluster's default)")
+	harbor.Flags().Bool("update-repo", true, "Update the helm repo")
+	addIngressFlags(harbor)
+
+	harbor.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+
+		opts := harborOptions{}
+		opts.Domain, _ = command.Flags().GetString("domain")
+		opts.Persistence, _ = command.Flags().GetBool("persistence")
+		opts.RegistrySize, _ = command.Flags().GetString("registry-size")
+		opts.StorageClass, _ = command.Flags().GetString("storage-class")
+
+		if !opts.Persistence && (command.Flags().Changed("registry-size") || command.Flags().Changed("storage-class")) {
+			return fmt.Errorf("--registry-size and --storage-class can only be used with --persistence")
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		inputData, err := ingressFromFlags(command)
+		if err != nil {
+			return err
+		}
+		inputData.IngressName = "harbor"
+		inputData.Namespace = namespace
+		inputData.ServiceName = "harbor"
+		inputData.ServicePort = 80
+		inputData.TLSSecret = "harbor-tls"
+		// Image layers are larger than the default limit of nginx
+		inputData.Annotations = map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "0"}
+
+		arch := getArchitecture()
+		logDebugf("Node architecture: %q\n", arch)
+
+		if !dryRun && arch != "x86_64" && arch != "amd64" {
+			return fmt.Errorf("The images for harbor are only published for amd64, not %s", arch)
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("harbor", "https://helm.goharbor.io", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("harbor", namespace); err != nil {
+				return err
+			}
+
+			opts.AdminPassword, err = secretValue(namespace, "harbor-core", "HARBOR_ADMIN_PASSWORD")
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(opts.AdminPassword) == 0 {
+			opts.AdminPassword, err = password.Generate(25, 10, 0, false, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		chartPath := path.Join(os.TempDir(), "charts")
+		version, _ := command.Flags().GetString("version")
+		err = fetchChart(chartPath, "harbor/harbor", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		outputPath := path.Join(chartPath, "harbor/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, harborOverrides(opts))
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "harbor",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("harbor", "harbor", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "harbor",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				ingressYaml, err := buildYaml(inputData)
+				if err != nil {
+					return err
+				}
+
+				ingressFile, err := writeTempFile("harbor", ingressYaml)
+				if err != nil {
+					return err
+				}
+				return printManifests(command, outputPath, ingressFile)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("harbor", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if err := applyIngress(command, "harbor", inputData); err != nil {
+			return err
+		}
+
+		if err := recordInstalled("harbor", chartVersion(chartPath, "harbor")); err != nil {
+			return err
+		}
+
+		logInfo(harborInstallMsg)
+		logInfof(`# Log in to https://%s as:
+#   username: admin
+#   password: %s
+`, opts.Domain, opts.AdminPassword)
+
+		return nil
+	}
+
+	return harbor
+}
+
+// harborOverrides exposes harbor with a ClusterIP Service for the Ingress,
+// which terminates TLS. Notary is turned off as it needs a second domain.
+func harborOverrides(opts harborOptions) map[string]string {
+	overrides := map[string]string{
+		"expose.type":         "clusterIP",
+		"expose.tls.enabled":  "false",
+		"externalURL":         "https://" + opts.Domain,
+		"harborAdminPassword": opts.AdminPassword,
+		"notary.enabled":      "false",
+		"persistence.enabled": strconv.FormatBool(opts.Persistence),
+	}
+
+	if opts.Persistence {
+		overrides["persistence.persistentVolumeClaim.registry.size"] = opts.RegistrySize
+
+		if len(opts.StorageClass) > 0 {
+			for _, component := range []string{"registry", "chartmuseum", "jobservice", "database", "redis", "trivy"} {
+				overrides["persistence.persistentVolumeClaim."+component+".storageClass"] = opts.StorageClass
+			}
+		}
+	}
+
+	return overrides
+}
+
+const harborInfoMsg = `# Get the password for the admin user
+kubectl get secret -n harbor harbor-core \
+  -o jsonpath="{.data.HARBOR_ADMIN_PASSWORD}" | base64 --decode; echo
+
+# Open your domain to create a project, then log in and push an image
+docker login harbor.example.com
+docker tag alpine:3.12 harbor.example.com/library/alpine:3.12
+docker push harbor.example.com/library/alpine:3.12
+
+# Find out more at:
+# https://goharbor.io/docs/`
+
+const harborInstallMsg = `=======================================================================
+= harbor has been installed.                                          =
+=======================================================================
+
+` + harborInfoMsg + `
+
+` + thanksForUsing
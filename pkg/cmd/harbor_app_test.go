@@ -0,0 +1,38 @@
+package cmd
+
+import "testing"
+
+func Test_harborOverrides_StorageClass(t *testing.T) {
+	overrides := harborOverrides(harborOptions{
+		Domain:        "harbor.example.com",
+		AdminPassword: "secret",
+		Persistence:   true,
+		RegistrySize:  "50Gi",
+		StorageClass:  "local-path",
+	})
+
+	want := map[string]string{
+		"externalURL":         "https://harbor.example.com",
+		"harborAdminPassword": "secret",
+		"persistence.persistentVolumeClaim.registry.size":         "50Gi",
+		"persistence.persistentVolumeClaim.database.storageClass": "local-path",
+	}
+
+	for k, v := range want {
+		if overrides[k] != v {
+			t.Errorf("%s want: %q, got: %q", k, v, overrides[k])
+		}
+	}
+}
+
+func Test_harborOverrides_WithoutPersistence(t *testing.T) {
+	overrides := harborOverrides(harborOptions{Domain: "harbor.example.com", RegistrySize: "5Gi"})
+
+	if overrides["persistence.enabled"] != "false" {
+		t.Errorf("want persistence disabled, got: %q", overrides["persistence.enabled"])
+	}
+
+	if _, ok := overrides["persistence.persistentVolumeClaim.registry.size"]; ok {
+		t.Errorf("want no size without persistence")
+	}
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// InputData holds the values for templates/ingress/ingress.yaml, an Ingress
+// with a TLS certificate from a cert-manager ClusterIssuer
+type InputData struct {
+	IngressName      string
+	Namespace        string
+	ServiceName      string
+	ServicePort      int
+	TLSSecret        string
+	IngressDomain    string
+	CertmanagerEmail string
+	IngressClass     string
+	IssuerName       string
+	ACMEServer       string
+	AccountKeySecret string
+	DNS01            dns01Options
+	DNS01Secret      string
+	DNS01SecretKey   string
+	Annotations      map[string]string
+}
+
+// dns01Options configures a DNS-01 solver, for clusters which Let's Encrypt
+// cannot reach on port 80
+type dns01Options struct {
+	Provider       string
+	SecretFile     string
+	AWSRegion      string
+	AWSAccessKeyID string
+	GCPProject     string
+}
+
+// dns01SecretKeys is the key within the credentials secret for each
+// supported DNS-01 provider
+var dns01SecretKeys = map[string]string{
+	"cloudflare":   "api-token",
+	"route53":      "secret-access-key",
+	"digitalocean": "access-token",
+	"google":       "key.json",
+}
+
+const letsencryptProdServer = "https://acme-v02.api.letsencrypt.org/directory"
+
+// letsencryptStagingServer has much higher rate limits, but issues
+// certificates which are not trusted by browsers
+const letsencryptStagingServer = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+func makeInstallIngress() *cobra.Command {
+	var ingress = &cobra.Command{
+		Use:   "ingress",
+		Short: "Install an ingress with TLS for any service",
+		Long: `Install an ingress with a TLS certificate from Let's Encrypt for any service in the cluster. Requires cert-manager 0.11.0 or higher installation in the cluster, and an IngressController such as nginx-ingress or traefik.
+
+The app is named ingress-NAME, so that it can be removed with "k3sup app uninstall ingress-NAME".`,
+		Example: `  k3sup app install ingress --namespace default --service grafana --port 3000 \
+    --domain grafana.example.com --email admin@example.com`,
+		SilenceUsage: true,
+	}
+
+	ingress.Flags().StringP("namespace", "n", "default", "The namespace of the service")
+	ingress.Flags().String("service", "", "The name of the service to expose")
+	ingress.Flags().Int("port", 80, "The port of the service to expose")
+	ingress.Flags().String("name", "", "The name of the Ingress (Default to --service)")
+	addIngressFlags(ingress)
+
+	ingress.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		inputData, err := ingressFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		inputData.Namespace, _ = command.Flags().GetString("namespace")
+		inputData.ServiceName, _ = command.Flags().GetString("service")
+		inputData.ServicePort, _ = command.Flags().GetInt("port")
+		inputData.IngressName, _ = command.Flags().GetString("name")
+
+		if len(inputData.ServiceName) == 0 {
+			return errors.New("--service is required, give the name of the service to expose")
+		}
+
+		if len(inputData.IngressName) == 0 {
+			inputData.IngressName = inputData.ServiceName
+		}
+		inputData.TLSSecret = inputData.IngressName + "-tls"
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		app := "ingress-" + inputData.IngressName
+		if err := applyIngress(command, app, inputData); err != nil {
+			return err
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return nil
+		}
+
+		logInfo(`=======================================================================
+= Ingress and cert-manager ClusterIssuer have been installed          =
+=======================================================================
+
+# You will need to ensure that your domain points to your cluster and is
+# accessible through ports 80 and 443, unless you used --dns01-provider.
+
+# To see the ingress record run
+kubectl get -n ` + inputData.Namespace + ` ingress ` + inputData.IngressName + `
+
+# To check the status of your certificate you can run
+kubectl describe -n ` + inputData.Namespace + ` Certificate ` + inputData.TLSSecret + `
+
+# Remove the ingress with
+k3sup app uninstall ` + app + `
+
+` + thanksForUsing)
+
+		return nil
+	}
+
+	return ingress
+}
+
+// addIngressFlags adds the flags shared by the apps which install an
+// Ingress with a certificate from Let's Encrypt
+func addIngressFlags(command *cobra.Command) {
+	command.Flags().StringP("domain", "d", "", "Custom Ingress Domain")
+	command.Flags().StringP("email", "e", "", "Letsencrypt Email")
+	command.Flags().Bool("staging", false, "Use the Let's Encrypt staging server to test without hitting the production rate limits")
+	command.Flags().String("ingress-class", "nginx", "The IngressController to use, i.e. nginx, or traefik which k3s installs by default")
+	command.Flags().String("dns01-provider", "", "Use a DNS-01 challenge instead of HTTP-01 with: cloudflare, route53, digitalocean or google")
+	command.Flags().String("dns01-secret-file", "", "File with the credentials for the DNS-01 provider: an API token, AWS secret access key or GCP service account JSON")
+	command.Flags().String("aws-region", "us-east-1", "AWS region for the route53 DNS-01 provider")
+	command.Flags().String("aws-access-key-id", "", "AWS access key ID for the route53 DNS-01 provider")
+	command.Flags().String("gcp-project", "", "GCP project for the google DNS-01 provider")
+}
+
+// ingressFromFlags validates the flags added by addIngressFlags, the caller
+// sets the Ingress name and the Service to route to
+func ingressFromFlags(command *cobra.Command) (InputData, error) {
+	email, _ := command.Flags().GetString("email")
+	domain, _ := command.Flags().GetString("domain")
+	ingressClass, _ := command.Flags().GetString("ingress-class")
+	staging, _ := command.Flags().GetBool("staging")
+
+	if email == "" || domain == "" {
+		return InputData{}, errors.New("both --email and --domain flags should be set and not empty, please set these values")
+	}
+
+	if ingressClass == "" {
+		return InputData{}, errors.New("--ingress-class should not be empty, use nginx or traefik")
+	}
+
+	dns01, err := dns01FromFlags(command)
+	if err != nil {
+		return InputData{}, err
+	}
+
+	return newInputData(domain, email, ingressClass, staging, dns01), nil
+}
+
+// applyIngress renders and applies the Ingress and ClusterIssuer for app,
+// along with the secret for a DNS-01 provider
+func applyIngress(command *cobra.Command, app string, inputData InputData) error {
+	yamlBytes, templateErr := buildYaml(inputData)
+	if templateErr != nil {
+		logWarn("Unable to install the application. Could not build the templated yaml file for the resources")
+		return templateErr
+	}
+
+	tempFile, tempFileErr := writeTempFile(app, yamlBytes)
+	if tempFileErr != nil {
+		logWarn("Unable to save generated yaml file into the temporary directory")
+		return tempFileErr
+	}
+
+	if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+		return printManifests(command, tempFile)
+	}
+
+	if len(inputData.DNS01.Provider) > 0 {
+		res, err := kubectlTask("create", "secret", "generic", inputData.DNS01Secret,
+			"--namespace", "cert-manager",
+			"--from-file", inputData.DNS01SecretKey+"="+expandPath(inputData.DNS01.SecretFile))
+		if err != nil {
+			return err
+		}
+
+		if res.ExitCode != 0 && !strings.Contains(res.Stderr, "AlreadyExists") {
+			return fmt.Errorf("unable to create the DNS-01 credentials secret: %s", res.Stderr)
+		}
+
+		if err := recordSecret(app, inputData.DNS01Secret, "cert-manager"); err != nil {
+			return err
+		}
+	}
+
+	res, err := kubectlTask("apply", "-f", tempFile)
+
+	if err != nil {
+		logWarn(err)
+		return err
+	}
+
+	if res.Stderr != "" {
+		return fmt.Errorf("unable to install %s. Have you got the %s service running in the %s namespace and cert-manager 0.11.0 or higher installed in cert-manager namespace? %s",
+			app, inputData.ServiceName, inputData.Namespace, res.Stderr)
+	}
+
+	if err := recordManifest(app, tempFile); err != nil {
+		return err
+	}
+
+	return recordInstalled(app, "")
+}
+
+func createTempDirectory(directory string) (string, error) {
+	tempDirectory := filepath.Join(os.TempDir(), directory)
+	if _, err := os.Stat(tempDirectory); os.IsNotExist(err) {
+		logDebugf("%s\n", tempDirectory)
+		errr := os.Mkdir(tempDirectory, 0744)
+		if errr != nil {
+			logWarnf("couldnt make dir %s\n", err)
+			return "", err
+		}
+	}
+
+	return tempDirectory, nil
+}
+
+func writeTempFile(name string, input []byte) (string, error) {
+	var tempDirectory, dirErr = createTempDirectory(".k3sup/")
+	if dirErr != nil {
+		return "", dirErr
+	}
+
+	filename := filepath.Join(tempDirectory, "temp_"+name+".yaml")
+
+	err := ioutil.WriteFile(filename, input, 0744)
+	if err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+func dns01FromFlags(command *cobra.Command) (dns01Options, error) {
+	dns01 := dns01Options{}
+	dns01.Provider, _ = command.Flags().GetString("dns01-provider")
+	dns01.SecretFile, _ = command.Flags().GetString("dns01-secret-file")
+	dns01.AWSRegion, _ = command.Flags().GetString("aws-region")
+	dns01.AWSAccessKeyID, _ = command.Flags().GetString("aws-access-key-id")
+	dns01.GCPProject, _ = command.Flags().GetString("gcp-project")
+
+	if len(dns01.Provider) == 0 {
+		return dns01, nil
+	}
+
+	if _, ok := dns01SecretKeys[dns01.Provider]; !ok {
+		return dns01, fmt.Errorf("unsupported --dns01-provider %q, use cloudflare, route53, digitalocean or google", dns01.Provider)
+	}
+
+	if len(dns01.SecretFile) == 0 {
+		return dns01, fmt.Errorf("--dns01-secret-file is required with --dns01-provider")
+	}
+
+	if dns01.Provider == "route53" && len(dns01.AWSAccessKeyID) == 0 {
+		return dns01, fmt.Errorf("--aws-access-key-id is required for the route53 provider")
+	}
+
+	if dns01.Provider == "google" && len(dns01.GCPProject) == 0 {
+		return dns01, fmt.Errorf("--gcp-project is required for the google provider")
+	}
+
+	return dns01, nil
+}
+
+// newInputData returns the values for templates/ingress/ingress.yaml, with a
+// ClusterIssuer for either the production or staging Let's Encrypt server
+func newInputData(domain, email, ingressClass string, staging bool, dns01 dns01Options) InputData {
+	inputData := InputData{
+		IngressDomain:    domain,
+		CertmanagerEmail: email,
+		IngressClass:     ingressClass,
+		IssuerName:       "letsencrypt-prod",
+		ACMEServer:       letsencryptProdServer,
+		AccountKeySecret: "example-issuer-account-key",
+	}
+
+	if staging {
+		inputData.IssuerName = "letsencrypt-staging"
+		inputData.ACMEServer = letsencryptStagingServer
+		inputData.AccountKeySecret = "letsencrypt-staging-account-key"
+	}
+
+	if len(dns01.Provider) > 0 {
+		inputData.DNS01 = dns01
+		inputData.DNS01Secret = "k3sup-dns01-" + dns01.Provider
+		inputData.DNS01SecretKey = dns01SecretKeys[dns01.Provider]
+	}
+
+	return inputData
+}
+
+func buildYaml(inputData InputData) ([]byte, error) {
+	tmpl, err := appTemplate("ingress/ingress.yaml")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl bytes.Buffer
+
+	err = tmpl.Execute(&tpl, inputData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tpl.Bytes(), nil
+}
+
+const ingressInfoMsg = `# You will need to ensure that your domain points to your cluster and is
+# accessible through ports 80 and 443, unless you used --dns01-provider.
+
+# To see the ingress records and certificates run
+kubectl get ingress --all-namespaces
+kubectl get certificate --all-namespaces
+
+# Remove an ingress with
+k3sup app uninstall ingress-NAME`
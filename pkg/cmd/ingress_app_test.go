@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_build_yaml_routes_to_any_service(t *testing.T) {
+	inputData := newInputData("grafana.example.com", "admin@example.com", "traefik", false, dns01Options{})
+	inputData.IngressName = "grafana"
+	inputData.Namespace = "monitoring"
+	inputData.ServiceName = "grafana"
+	inputData.ServicePort = 3000
+	inputData.TLSSecret = "grafana-tls"
+
+	templBytes, err := buildYaml(inputData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	for _, want := range []string{
+		"  name: grafana\n  namespace: monitoring\n",
+		"          serviceName: grafana\n          servicePort: 3000\n",
+		"    secretName: grafana-tls\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %q", want, got)
+		}
+	}
+}
+
+func Test_build_yaml_adds_annotations(t *testing.T) {
+	inputData := newInputData("registry.example.com", "admin@example.com", "nginx", false, dns01Options{})
+	inputData.Annotations = map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "0"}
+
+	templBytes, err := buildYaml(inputData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "    kubernetes.io/ingress.class: nginx\n    nginx.ingress.kubernetes.io/proxy-body-size: \"0\"\nspec:"
+	if !strings.Contains(string(templBytes), want) {
+		t.Errorf("want %q in: %q", want, string(templBytes))
+	}
+}
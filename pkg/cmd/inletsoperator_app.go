@@ -17,29 +17,51 @@ func makeInstallInletsOperator() *cobra.Command {
 
 	inletsOperator.Flags().StringP("namespace", "n", "default", "The namespace used for installation")
 	inletsOperator.Flags().StringP("token-file", "t", "", "Text file for your DigitalOcean token")
+	inletsOperator.Flags().String("version", "master", "The tag or branch of inlets-operator to install manifests from")
 
 	inletsOperator.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
 		kubeConfigPath := getDefaultKubeconfig()
 
 		if command.Flags().Changed("kubeconfig") {
 			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
 		}
 
-		fmt.Printf("Using kubeconfig: %s\n", kubeConfigPath)
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
 
 		namespace, _ := command.Flags().GetString("namespace")
 
-		if namespace != "default" {
-			return fmt.Errorf(`to override the namespace, edit the YAML files on GitHub`)
+		version, _ := command.Flags().GetString("version")
+		yamls := []string{}
+		for _, name := range []string{"crd.yaml", "operator-rbac.yaml", "operator.yaml"} {
+			yamls = append(yamls, fmt.Sprintf("https://raw.githubusercontent.com/inlets/inlets-operator/%s/artifacts/%s", version, name))
+		}
+
+		yamls, err := setManifestNamespace("default", namespace, yamls...)
+		if err != nil {
+			return err
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return printManifests(command, yamls...)
 		}
+
 		secretFileName, _ := command.Flags().GetString("token-file")
 
 		if len(secretFileName) == 0 {
 			return fmt.Errorf(`--token-file is a required field for your cloud API token`)
 		}
 
+		if err := createNamespace("inlets-operator", namespace); err != nil {
+			return err
+		}
+
 		res, err := kubectlTask("create", "secret", "generic",
 			"inlets-access-key",
+			"--namespace", namespace,
 			"--from-file", "inlets-access-key="+secretFileName)
 
 		if len(res.Stderr) > 0 {
@@ -50,32 +72,34 @@ func makeInstallInletsOperator() *cobra.Command {
 			return err
 		}
 
-		yamls := []string{
-			"https://raw.githubusercontent.com/inlets/inlets-operator/master/artifacts/crd.yaml",
-			"https://raw.githubusercontent.com/inlets/inlets-operator/master/artifacts/operator-rbac.yaml",
-			"https://raw.githubusercontent.com/inlets/inlets-operator/master/artifacts/operator.yaml",
+		if err := recordSecret("inlets-operator", "inlets-access-key", namespace); err != nil {
+			return err
 		}
 
-		for _, yaml := range yamls {
-			err = kubectl("apply", "-f", yaml)
+		if err := applyNamespacedManifests("inlets-operator", namespace, yamls...); err != nil {
+			return err
+		}
 
-			if err != nil {
-				return err
-			}
+		if err := recordInstalled("inlets-operator", version); err != nil {
+			return err
 		}
 
-		fmt.Println(`=======================================================================
-= inlets-operator has been installed.                                  =
-=======================================================================
+		logInfo(inletsOperatorInstallMsg)
+
+		return nil
+	}
 
-# The default configuration is for DigitalOcean and your secret is
+	return inletsOperator
+}
+
+const inletsOperatorInfoMsg = `# The default configuration is for DigitalOcean and your secret is
 # stored as "inlets-access-key" in the "default" namespace.
 
 # To get your first Public IP run the following:
 kubectl run nginx-1 --image=nginx --port=80 --restart=Always
 kubectl expose deployment nginx-1 --port=80 --type=LoadBalancer
 
-# Find your IP in the "EXTERNAL-IP" field, watch for "<pending>" to 
+# Find your IP in the "EXTERNAL-IP" field, watch for "<pending>" to
 # change to an IP
 
 kubectl get svc -w
@@ -84,12 +108,12 @@ kubectl get svc -w
 kubectl delete svc/nginx-1
 
 # Find out more at:
-# https://github.com/inlets/inlets-operator
+# https://github.com/inlets/inlets-operator`
 
-Thank you for using k3sup!`)
+const inletsOperatorInstallMsg = `=======================================================================
+= inlets-operator has been installed.                                  =
+=======================================================================
 
-		return nil
-	}
+` + inletsOperatorInfoMsg + `
 
-	return inletsOperator
-}
+` + thanksForUsing
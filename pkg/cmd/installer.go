@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/provision"
+	"github.com/spf13/cobra"
+)
+
+// k3sVersionFromFlags returns the --k3s-version and --k3s-channel flags,
+// the pinned default version is dropped when only a channel is given
+func k3sVersionFromFlags(command *cobra.Command) (string, string, error) {
+	version, _ := command.Flags().GetString("k3s-version")
+	channel, _ := command.Flags().GetString("k3s-channel")
+
+	if len(channel) > 0 {
+		if command.Flags().Changed("k3s-version") {
+			return "", "", fmt.Errorf("give either --k3s-version or --k3s-channel, not both")
+		}
+		version = ""
+	}
+
+	return version, channel, nil
+}
+
+// validTLSSANs returns an error for an empty name or one with spaces
+func validTLSSANs(sans []string) error {
+	for _, san := range sans {
+		if len(san) == 0 || strings.ContainsAny(san, " \t'\"") {
+			return fmt.Errorf("invalid --tls-san %q, give an IP address or DNS name", san)
+		}
+	}
+	return nil
+}
+
+// disableComponents are the packaged components which a k3s server deploys
+// unless they are disabled, each has a --no-<component> flag
+var disableComponents = []string{"traefik", "servicelb", "metrics-server", "local-storage"}
+
+// addDisableFlags adds --no-traefik and the other flags for
+// disableComponents
+func addDisableFlags(command *cobra.Command) {
+	for _, component := range disableComponents {
+		command.Flags().Bool("no-"+component, false, fmt.Sprintf("Do not deploy %s on the server, the same as --k3s-extra-args '--disable %s'", component, component))
+	}
+}
+
+// disableArgsFromFlags returns the --disable flags for k3s from the flags
+// added by addDisableFlags
+func disableArgsFromFlags(command *cobra.Command) string {
+	disabled := []string{}
+	for _, component := range disableComponents {
+		if ok, _ := command.Flags().GetBool("no-" + component); ok {
+			disabled = append(disabled, component)
+		}
+	}
+	return disableArgs(disabled)
+}
+
+func disableArgs(components []string) string {
+	args := []string{}
+	for _, component := range components {
+		args = append(args, "--disable "+component)
+	}
+	return strings.Join(args, " ")
+}
+
+// validDisableComponent returns an error unless component is one of
+// disableComponents
+func validDisableComponent(component string) error {
+	for _, c := range disableComponents {
+		if c == component {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown component %q to disable, use one of: %s", component, strings.Join(disableComponents, ", "))
+}
+
+// addNodeAddressFlags adds the flags for the addresses a node registers
+// with, for nodes with both private and public interfaces or a VPN such as
+// WireGuard
+func addNodeAddressFlags(command *cobra.Command) {
+	command.Flags().String("node-ip", "", "The IP address the node registers with and uses for cluster traffic, such as its LAN or VPN address, a comma-separated pair for dual-stack")
+	command.Flags().String("node-external-ip", "", "The public IP address of the node, when it is not that of the default interface")
+	command.Flags().String("advertise-address", "", "The IP address which the server advertises to other nodes for the Kubernetes API, only for servers")
+}
+
+// nodeAddressArgsFromFlags returns the k3s flags for the flags added by
+// addNodeAddressFlags
+func nodeAddressArgsFromFlags(command *cobra.Command) (string, error) {
+	nodeIP, _ := command.Flags().GetString("node-ip")
+	externalIP, _ := command.Flags().GetString("node-external-ip")
+	advertise, _ := command.Flags().GetString("advertise-address")
+
+	return nodeAddressArgs(nodeIP, externalIP, advertise)
+}
+
+// nodeAddressArgs returns --node-ip, --node-external-ip and
+// --advertise-address for k3s, each of which is optional
+func nodeAddressArgs(nodeIP, externalIP, advertise string) (string, error) {
+	args := []string{}
+
+	for _, address := range []struct {
+		flag  string
+		value string
+		list  bool
+	}{
+		{"node-ip", nodeIP, true},
+		{"node-external-ip", externalIP, true},
+		{"advertise-address", advertise, false},
+	} {
+		if len(address.value) == 0 {
+			continue
+		}
+
+		ips := []string{address.value}
+		if address.list {
+			ips = strings.Split(address.value, ",")
+		}
+		for _, ip := range ips {
+			if net.ParseIP(ip) == nil {
+				return "", fmt.Errorf("invalid --%s %q, give an IP address", address.flag, address.value)
+			}
+		}
+
+		args = append(args, "--"+address.flag+" "+address.value)
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// addDatastoreFlags adds the flags for an external datastore, usage
+// describes --datastore for the command
+func addDatastoreFlags(command *cobra.Command, usage string) {
+	command.Flags().String("datastore", "", usage)
+	command.Flags().String("datastore-cafile", "", "Local path to the CA certificate of the --datastore, uploaded to the server")
+	command.Flags().String("datastore-certfile", "", "Local path to the client certificate for the --datastore, uploaded to the server")
+	command.Flags().String("datastore-keyfile", "", "Local path to the client key for the --datastore, uploaded to the server")
+}
+
+func datastoreFromFlags(command *cobra.Command) provision.Datastore {
+	endpoint, _ := command.Flags().GetString("datastore")
+	caFile, _ := command.Flags().GetString("datastore-cafile")
+	certFile, _ := command.Flags().GetString("datastore-certfile")
+	keyFile, _ := command.Flags().GetString("datastore-keyfile")
+
+	return provision.Datastore{
+		Endpoint: endpoint,
+		CAFile:   caFile,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+}
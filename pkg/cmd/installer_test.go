@@ -0,0 +1,36 @@
+package cmd
+
+import "testing"
+
+func Test_validTLSSANs(t *testing.T) {
+	if err := validTLSSANs([]string{"k3s.example.com", "a b"}); err == nil {
+		t.Errorf("want an error for a name with a space")
+	}
+}
+
+func Test_disableArgsFromFlags(t *testing.T) {
+	command := MakeInstall()
+	command.Flags().Set("no-traefik", "true")
+	command.Flags().Set("no-local-storage", "true")
+
+	want := "--disable traefik --disable local-storage"
+	if got := disableArgsFromFlags(command); got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+}
+
+func Test_nodeAddressArgs(t *testing.T) {
+	got, err := nodeAddressArgs("10.0.0.2,fd00::2", "203.0.113.10", "100.64.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "--node-ip 10.0.0.2,fd00::2 --node-external-ip 203.0.113.10 --advertise-address 100.64.0.2"
+	if got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+
+	if _, err := nodeAddressArgs("", "", "k3s.example.com"); err == nil {
+		t.Errorf("want an error for an advertise-address which is not an IP")
+	}
+}
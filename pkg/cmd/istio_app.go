@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	execute "github.com/alexellis/go-execute/pkg/v1"
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallIstio() *cobra.Command {
+	var istio = &cobra.Command{
+		Use:   "istio",
+		Short: "Install istio",
+		Long: `Install the istio service mesh with istioctl, which is downloaded to
+~/.k3sup/.bin/. The minimal profile installs only istiod, use
+--low-resources to also turn off autoscaling and telemetry and to lower the
+resource requests, so that istio fits on Raspberry Pi class nodes.`,
+		Example: `  k3sup app install istio --profile minimal
+  k3sup app install istio --low-resources`,
+		SilenceUsage: true,
+	}
+
+	istio.Flags().String("version", "1.6.0", "The version of istio to install")
+	istio.Flags().String("profile", "minimal", "The istio profile to install, i.e. minimal, default or demo")
+	istio.Flags().Bool("low-resources", false, "Turn off autoscaling, telemetry and the ingress gateway, and lower the resource requests")
+
+	istio.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		version, _ := command.Flags().GetString("version")
+		profile, _ := command.Flags().GetString("profile")
+		lowResources, _ := command.Flags().GetBool("low-resources")
+		dryRun, _ := command.Flags().GetBool("dry-run")
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		if err := tryDownloadIstioctl(clientArch, clientOS, version); err != nil {
+			return err
+		}
+
+		overrides := istioOverrides(profile, lowResources)
+
+		res, err := istioctlTask(append([]string{"manifest", "generate"}, overrides...)...)
+		if err != nil {
+			return err
+		}
+
+		if res.ExitCode != 0 {
+			return fmt.Errorf("unable to render the istio manifests: %s", res.Stderr)
+		}
+
+		manifest, err := writeTempFile("istio", []byte(res.Stdout))
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			return printManifests(command, manifest)
+		}
+
+		// istioctl waits for the CRDs before applying the resources which
+		// use them, which kubectl apply would not
+		res, err = istioctlTask(append([]string{"install"}, overrides...)...)
+		if err != nil {
+			return err
+		}
+
+		logDebugf("%s\n", res.Stdout)
+
+		if res.ExitCode != 0 {
+			return fmt.Errorf("unable to install istio: %s", res.Stderr)
+		}
+
+		if err := recordManifest("istio", manifest); err != nil {
+			return err
+		}
+
+		if err := recordInstalled("istio", version); err != nil {
+			return err
+		}
+
+		logInfo(istioInstallMsg)
+
+		return nil
+	}
+
+	return istio
+}
+
+// istioOverrides returns the --set flags for istioctl
+func istioOverrides(profile string, lowResources bool) []string {
+	overrides := []string{"--set", "profile=" + profile}
+
+	if lowResources {
+		for _, o := range []string{
+			"values.pilot.autoscaleEnabled=false",
+			"values.pilot.resources.requests.cpu=100m",
+			"values.pilot.resources.requests.memory=128Mi",
+			"values.global.proxy.resources.requests.cpu=10m",
+			"values.global.proxy.resources.requests.memory=40Mi",
+			"values.gateways.istio-ingressgateway.autoscaleEnabled=false",
+			"values.telemetry.enabled=false",
+			"components.ingressGateways[0].enabled=false",
+		} {
+			overrides = append(overrides, "--set", o)
+		}
+	}
+
+	return overrides
+}
+
+// tryDownloadIstioctl downloads istioctl, unless the version requested is
+// already present
+func tryDownloadIstioctl(clientArch, clientOS, version string) error {
+	if res, err := istioctlTask("version", "--remote=false", "--short"); err == nil && res.ExitCode == 0 {
+		if strings.TrimSpace(res.Stdout) == version {
+			return nil
+		}
+	}
+
+	istioURL := getIstioctlURL(clientArch, clientOS, version)
+	logDebugf("%s\n", istioURL)
+
+	return downloadTarball(istioURL, istioURL+".sha256", localBinary(""))
+}
+
+func getIstioctlURL(arch, os, version string) string {
+	suffix := "linux-amd64"
+
+	if strings.ToLower(os) == "darwin" {
+		suffix = "osx"
+	} else if strings.HasPrefix(arch, "armv7") {
+		suffix = "linux-armv7"
+	} else if strings.HasPrefix(arch, "aarch64") || arch == "arm64" {
+		suffix = "linux-arm64"
+	}
+
+	return fmt.Sprintf("https://github.com/istio/istio/releases/download/%s/istioctl-%s-%s.tar.gz", version, version, suffix)
+}
+
+func istioctlTask(args ...string) (execute.ExecResult, error) {
+	task := execute.ExecTask{
+		Command: localBinary("istioctl"),
+		Args:    args,
+		Env:     os.Environ(),
+	}
+
+	return runTask(task)
+}
+
+const istioInfoMsg = `# Add istioctl to your PATH
+export PATH=$PATH:$HOME/.k3sup/.bin/
+
+# Check the control plane
+istioctl verify-install
+
+# Add the sidecar to new Pods in a namespace
+kubectl label namespace default istio-injection=enabled
+
+# Find out more at:
+# https://istio.io/docs/setup/getting-started/`
+
+const istioInstallMsg = `=======================================================================
+= istio has been installed.                                           =
+=======================================================================
+
+` + istioInfoMsg + `
+
+` + thanksForUsing
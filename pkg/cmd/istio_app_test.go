@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_getIstioctlURL(t *testing.T) {
+	cases := []struct {
+		arch string
+		os   string
+		want string
+	}{
+		{"x86_64", "Linux", "https://github.com/istio/istio/releases/download/1.6.0/istioctl-1.6.0-linux-amd64.tar.gz"},
+		{"armv7l", "Linux", "https://github.com/istio/istio/releases/download/1.6.0/istioctl-1.6.0-linux-armv7.tar.gz"},
+		{"aarch64", "Linux", "https://github.com/istio/istio/releases/download/1.6.0/istioctl-1.6.0-linux-arm64.tar.gz"},
+		{"x86_64", "Darwin", "https://github.com/istio/istio/releases/download/1.6.0/istioctl-1.6.0-osx.tar.gz"},
+	}
+
+	for _, c := range cases {
+		got := getIstioctlURL(c.arch, c.os, "1.6.0")
+		if got != c.want {
+			t.Errorf("%s %s, want: %s, got: %s", c.arch, c.os, c.want, got)
+		}
+	}
+}
+
+func Test_istioOverrides_LowResources(t *testing.T) {
+	got := strings.Join(istioOverrides("minimal", false), " ")
+	if got != "--set profile=minimal" {
+		t.Errorf("want only the profile, got: %s", got)
+	}
+
+	got = strings.Join(istioOverrides("minimal", true), " ")
+	if !strings.Contains(got, "--set values.telemetry.enabled=false") {
+		t.Errorf("want telemetry turned off, got: %s", got)
+	}
+}
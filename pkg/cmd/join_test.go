@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_summariseJoins_all_joined(t *testing.T) {
+	err := summariseJoins([]joinResult{{Host: "192.168.0.101"}, {Host: "192.168.0.102"}})
+	if err != nil {
+		t.Errorf("want no error, got: %s", err)
+	}
+}
+
+func Test_summariseJoins_lists_failed_hosts(t *testing.T) {
+	err := summariseJoins([]joinResult{
+		{Host: "192.168.0.101"},
+		{Host: "192.168.0.102", Err: fmt.Errorf("connection refused")},
+		{Host: "192.168.0.103", Err: fmt.Errorf("timeout")},
+	})
+	if err == nil {
+		t.Fatal("want an error")
+	}
+
+	want := "2 agent(s) failed to join:\n  192.168.0.102: connection refused\n  192.168.0.103: timeout"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("want: %q, got: %q", want, err.Error())
+	}
+}
+
+func Test_hostWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := newHostWriter("192.168.0.101", out)
+
+	fmt.Fprint(w, "[INFO]  Using v1.19.5+k3s1\n[INFO]  Down")
+	fmt.Fprint(w, "loading hash\n[INFO]  Skip")
+	if want := "[192.168.0.101] [INFO]  Using v1.19.5+k3s1\n[192.168.0.101] [INFO]  Downloading hash\n"; out.String() != want {
+		t.Errorf("want each whole line prefixed:\n%q\ngot:\n%q", want, out.String())
+	}
+
+	w.Flush()
+	if !strings.HasSuffix(out.String(), "[192.168.0.101] [INFO]  Skip\n") {
+		t.Errorf("want the last line on Flush, got:\n%q", out.String())
+	}
+}
+
+func Test_joinOptions_serverURL(t *testing.T) {
+	opts := joinOptions{Server: sshOptions{Host: "192.168.0.100"}}
+	if got := opts.serverURL(); got != "https://192.168.0.100:6443" {
+		t.Errorf("want the URL from the server's host, got: %q", got)
+	}
+
+	opts.ServerURL = "https://k3s.example.com:6443/"
+	if got := opts.serverURL(); got != "https://k3s.example.com:6443" {
+		t.Errorf("want --server-url, got: %q", got)
+	}
+}
+
+func Test_validServerFlags(t *testing.T) {
+	cases := []struct {
+		hasServerIP bool
+		serverURL   string
+		hasToken    bool
+		wantErr     bool
+	}{
+		{true, "", false, false},
+		{true, "", true, false},
+		{false, "https://192.168.0.100:6443", true, false},
+		{true, "https://k3s.example.com:6443", false, false},
+		{false, "", false, true},
+		{false, "", true, true},
+		{false, "https://192.168.0.100:6443", false, true},
+		{false, "http://192.168.0.100:6443", true, true},
+		{false, "192.168.0.100:6443", true, true},
+	}
+
+	for _, c := range cases {
+		err := validServerFlags(c.hasServerIP, c.serverURL, c.hasToken)
+		if (err != nil) != c.wantErr {
+			t.Errorf("server-ip: %t, server-url: %q, token: %t, want error: %t, got: %v", c.hasServerIP, c.serverURL, c.hasToken, c.wantErr, err)
+		}
+	}
+}
+
+func tokenCommand(args ...string) *cobra.Command {
+	command := &cobra.Command{}
+	command.Flags().String("token", "", "")
+	command.Flags().String("token-file", "", "")
+	command.Flags().Parse(args)
+	return command
+}
+
+func Test_tokenFromFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k3sup-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := path.Join(dir, "node-token")
+	if err := ioutil.WriteFile(tokenFile, []byte("K10file::server:abc\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args    []string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{[]string{}, "", "", false},
+		{[]string{"--token", "K10flag"}, "", "K10flag", false},
+		{[]string{"--token-file", tokenFile}, "", "K10file::server:abc", false},
+		{[]string{"--token-file", "-"}, "K10stdin\n", "K10stdin", false},
+		{[]string{"--token-file", "-"}, "\n", "", true},
+		{[]string{"--token-file", path.Join(dir, "missing")}, "", "", true},
+		{[]string{"--token", "K10'flag"}, "", "K10'flag", false},
+	}
+
+	for _, c := range cases {
+		got, err := tokenFromFlags(tokenCommand(c.args...), strings.NewReader(c.stdin))
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("args: %v, want: %q (error: %t), got: %q, %v", c.args, c.want, c.wantErr, got, err)
+		}
+	}
+}
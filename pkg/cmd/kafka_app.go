@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// kafkaCluster holds the values for templates/kafka/kafka.yaml, a Kafka
+// resource for the Strimzi operator
+type kafkaCluster struct {
+	Name              string
+	Namespace         string
+	Replicas          int
+	ZookeeperReplicas int
+	ReplicationFactor int
+	MinISR            int
+	Persistence       bool
+	Size              string
+}
+
+func makeInstallKafka() *cobra.Command {
+	var kafka = &cobra.Command{
+		Use:   "kafka",
+		Short: "Install kafka with the Strimzi operator",
+		Long: `Install the Strimzi operator, then a kafka cluster with a single broker and
+ephemeral storage. Use --replicas and --persistence for a cluster which
+keeps its data when Pods restart.`,
+		Example: `  k3sup app install kafka
+  k3sup app install kafka --replicas 3 --persistence --size 20Gi`,
+		SilenceUsage: true,
+	}
+
+	kafka.Flags().StringP("namespace", "n", "kafka", "The namespace used for installation")
+	kafka.Flags().String("version", "0.18.0", "The version of the Strimzi operator to install")
+	kafka.Flags().String("cluster-name", "kafka", "The name of the kafka cluster")
+	kafka.Flags().Int("replicas", 1, "The number of kafka brokers")
+	kafka.Flags().Bool("persistence", false, "Store data in PersistentVolumeClaims, instead of ephemeral storage")
+	kafka.Flags().String("size", "10Gi", "The size of each PersistentVolumeClaim")
+	kafka.Flags().Bool("update-repo", true, "Update the helm repo")
+
+	kafka.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		version, _ := command.Flags().GetString("version")
+		clusterName, _ := command.Flags().GetString("cluster-name")
+		replicas, _ := command.Flags().GetInt("replicas")
+		persistence, _ := command.Flags().GetBool("persistence")
+		size, _ := command.Flags().GetString("size")
+
+		if replicas < 1 {
+			return fmt.Errorf("--replicas must be at least 1")
+		}
+
+		if command.Flags().Changed("size") && !persistence {
+			return fmt.Errorf("--size can only be used with --persistence")
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		arch := getArchitecture()
+		logDebugf("Node architecture: %q\n", arch)
+
+		if !dryRun && arch != "x86_64" && arch != "amd64" {
+			return fmt.Errorf("The Strimzi images are only published for amd64, not %s", arch)
+		}
+
+		clusterYaml, err := buildKafkaCluster(newKafkaCluster(clusterName, namespace, replicas, persistence, size))
+		if err != nil {
+			return err
+		}
+
+		clusterFile, err := writeTempFile("kafka_cluster", clusterYaml)
+		if err != nil {
+			return err
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("strimzi", "https://strimzi.io/charts/", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("kafka", namespace); err != nil {
+				return err
+			}
+		}
+
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "strimzi/strimzi-kafka-operator", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		outputPath := path.Join(chartPath, "strimzi-kafka-operator/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, nil)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "strimzi-kafka-operator",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("kafka", "strimzi-kafka-operator", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "strimzi-kafka-operator",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, outputPath, clusterFile)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("kafka", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if err := waitForCRDs("kafkas.kafka.strimzi.io"); err != nil {
+			return err
+		}
+
+		if err := applyManifests("kafka", clusterFile); err != nil {
+			return err
+		}
+
+		if err := recordInstalled("kafka", version); err != nil {
+			return err
+		}
+
+		logInfo(kafkaInstallMsg)
+		logInfof("# Your bootstrap server is: %s-kafka-bootstrap.%s:9092\n", clusterName, namespace)
+
+		return nil
+	}
+
+	return kafka
+}
+
+// newKafkaCluster returns the values for a cluster with replicas brokers, the
+// internal topics are replicated to at most 3 brokers, and zookeeper runs
+// 1 or 3 replicas to keep a quorum
+func newKafkaCluster(name, namespace string, replicas int, persistence bool, size string) kafkaCluster {
+	cluster := kafkaCluster{
+		Name:              name,
+		Namespace:         namespace,
+		Replicas:          replicas,
+		ZookeeperReplicas: 1,
+		ReplicationFactor: replicas,
+		MinISR:            1,
+		Persistence:       persistence,
+		Size:              size,
+	}
+
+	if replicas >= 3 {
+		cluster.ZookeeperReplicas = 3
+		cluster.ReplicationFactor = 3
+		cluster.MinISR = 2
+	}
+
+	return cluster
+}
+
+func buildKafkaCluster(cluster kafkaCluster) ([]byte, error) {
+	tmpl, err := appTemplate("kafka/kafka.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, cluster); err != nil {
+		return nil, err
+	}
+
+	return tpl.Bytes(), nil
+}
+
+const kafkaInfoMsg = `# Wait for the brokers to start, it can take a few minutes:
+kubectl get kafka -n kafka
+kubectl get pods -n kafka -w
+
+# Send messages to a topic
+kubectl -n kafka run kafka-producer -ti --rm=true --restart=Never \
+  --image=strimzi/kafka:0.18.0-kafka-2.5.0 -- bin/kafka-console-producer.sh \
+  --broker-list kafka-kafka-bootstrap:9092 --topic my-topic
+
+# Then read them back
+kubectl -n kafka run kafka-consumer -ti --rm=true --restart=Never \
+  --image=strimzi/kafka:0.18.0-kafka-2.5.0 -- bin/kafka-console-consumer.sh \
+  --bootstrap-server kafka-kafka-bootstrap:9092 --topic my-topic --from-beginning
+
+# Find out more at:
+# https://strimzi.io/docs/`
+
+const kafkaInstallMsg = `=======================================================================
+= kafka has been installed.                                           =
+=======================================================================
+
+` + kafkaInfoMsg + `
+
+` + thanksForUsing
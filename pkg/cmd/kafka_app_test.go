@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_buildKafkaCluster_SingleEphemeralBroker(t *testing.T) {
+	templBytes, err := buildKafkaCluster(newKafkaCluster("kafka", "kafka", 1, false, "10Gi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	for _, want := range []string{
+		"  kafka:\n    replicas: 1\n",
+		"      offsets.topic.replication.factor: 1\n",
+		"  zookeeper:\n    replicas: 1\n    storage:\n      type: ephemeral\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %q", want, got)
+		}
+	}
+}
+
+func Test_buildKafkaCluster_PersistentReplicas(t *testing.T) {
+	templBytes, err := buildKafkaCluster(newKafkaCluster("kafka", "kafka", 5, true, "20Gi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	for _, want := range []string{
+		"  kafka:\n    replicas: 5\n",
+		"      offsets.topic.replication.factor: 3\n",
+		"      transaction.state.log.min.isr: 2\n",
+		"  zookeeper:\n    replicas: 3\n",
+		"      type: persistent-claim\n      size: 20Gi\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// knativeConfig holds the values for templates/knative/config.yaml
+type knativeConfig struct {
+	Domain string
+}
+
+func makeInstallKnativeServing() *cobra.Command {
+	var knative = &cobra.Command{
+		Use:   "knative-serving",
+		Short: "Install knative serving with kourier",
+		Long: `Install knative serving to run serverless workloads, with kourier for
+networking. The resource requests of the knative controllers are lowered to
+fit on small k3s nodes.
+
+Give --domain for the URLs of your services, with a wildcard DNS record
+pointing at kourier, otherwise sslip.io is used with kourier's IP.
+
+k3s runs traefik on ports 80 and 443 by default, use --service-type NodePort
+or install the server with --no-deploy traefik for kourier to get a
+LoadBalancer.`,
+		Example: `  k3sup app install knative-serving
+  k3sup app install knative-serving --domain knative.example.com`,
+		SilenceUsage: true,
+	}
+
+	knative.Flags().String("version", "v0.22.0", "The version of knative serving and kourier to install")
+	knative.Flags().String("domain", "", "The domain for the URLs of services, i.e. knative.example.com")
+	knative.Flags().String("service-type", "LoadBalancer", "The type of kourier's Service, LoadBalancer or NodePort")
+
+	knative.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		version, _ := command.Flags().GetString("version")
+		domain, _ := command.Flags().GetString("domain")
+		serviceType, _ := command.Flags().GetString("service-type")
+
+		if serviceType != "LoadBalancer" && serviceType != "NodePort" {
+			return fmt.Errorf("give the --service-type as LoadBalancer or NodePort, not: %q", serviceType)
+		}
+
+		crds := fmt.Sprintf("https://github.com/knative/serving/releases/download/%s/serving-crds.yaml", version)
+		manifests := []string{
+			fmt.Sprintf("https://github.com/knative/serving/releases/download/%s/serving-core.yaml", version),
+			fmt.Sprintf("https://github.com/knative/net-kourier/releases/download/%s/kourier.yaml", version),
+		}
+
+		config, err := buildKnativeConfig(knativeConfig{Domain: domain})
+		if err != nil {
+			return err
+		}
+
+		configFile, err := writeTempFile("knative_config", config)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, configFile)
+
+		if len(domain) == 0 {
+			manifests = append(manifests, fmt.Sprintf("https://github.com/knative/serving/releases/download/%s/serving-default-domain.yaml", version))
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return printManifests(command, append([]string{crds}, manifests...)...)
+		}
+
+		if err := applyManifests("knative-serving", crds); err != nil {
+			return err
+		}
+
+		if err := waitForCRDs("services.serving.knative.dev"); err != nil {
+			return err
+		}
+
+		if err := applyManifests("knative-serving", manifests...); err != nil {
+			return err
+		}
+
+		err = kubectl("set", "resources", "deployment", "activator", "autoscaler", "controller", "webhook",
+			"--namespace", "knative-serving", "--requests", "cpu=30m,memory=40Mi")
+		if err != nil {
+			return fmt.Errorf("unable to lower the resource requests: %s", err)
+		}
+
+		if serviceType != "LoadBalancer" {
+			err = kubectl("patch", "service", "kourier", "--namespace", "kourier-system",
+				"--patch", fmt.Sprintf(`{"spec":{"type":%q}}`, serviceType))
+			if err != nil {
+				return fmt.Errorf("unable to set the type of the kourier Service: %s", err)
+			}
+		}
+
+		if err := recordInstalled("knative-serving", version); err != nil {
+			return err
+		}
+
+		logInfo(knativeInstallMsg)
+
+		return nil
+	}
+
+	return knative
+}
+
+func buildKnativeConfig(config knativeConfig) ([]byte, error) {
+	tmpl, err := appTemplate("knative/config.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, config); err != nil {
+		return nil, err
+	}
+
+	return tpl.Bytes(), nil
+}
+
+const knativeInfoMsg = `# Find the IP of kourier, point a wildcard DNS record for your domain
+# at it if you installed with --domain
+kubectl get svc kourier -n kourier-system
+
+# Deploy a service with the kn CLI, then find its URL
+kn service create hello --image gcr.io/knative-samples/helloworld-go
+kubectl get ksvc hello
+
+# Find out more at:
+# https://knative.dev/docs/serving/`
+
+const knativeInstallMsg = `=======================================================================
+= knative serving has been installed.                                 =
+=======================================================================
+
+` + knativeInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_buildKnativeConfig_WithDomain(t *testing.T) {
+	templBytes, err := buildKnativeConfig(knativeConfig{Domain: "knative.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	for _, want := range []string{
+		"  ingress.class: kourier.ingress.networking.knative.dev\n",
+		"  name: config-domain\n",
+		"  knative.example.com: \"\"\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %q", want, got)
+		}
+	}
+}
+
+func Test_buildKnativeConfig_WithoutDomain(t *testing.T) {
+	templBytes, err := buildKnativeConfig(knativeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(templBytes), "config-domain") {
+		t.Errorf("want no config-domain without a domain, got: %q", string(templBytes))
+	}
+}
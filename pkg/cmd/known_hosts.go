@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const defaultKnownHostsFile = "~/.ssh/known_hosts"
+
+// Values for --ssh-strict-host-key-checking, as for StrictHostKeyChecking in
+// ssh_config. With "no" the host key is not checked, which suits machines
+// which have just been imaged and whose keys are not known yet.
+const (
+	hostKeyCheckingNo        = "no"
+	hostKeyCheckingYes       = "yes"
+	hostKeyCheckingAcceptNew = "accept-new"
+)
+
+// knownHostsLock serialises reading and appending to known_hosts, as the
+// agents in a plan are joined at the same time
+var knownHostsLock sync.Mutex
+
+// addHostKeyFlags adds the flags to verify the host keys of nodes
+func addHostKeyFlags(command *cobra.Command) {
+	command.Flags().String("ssh-strict-host-key-checking", hostKeyCheckingNo, "Verify host keys against --known-hosts-file: yes, no, or accept-new to add the keys of new hosts and reject changed keys")
+	command.Flags().String("known-hosts-file", defaultKnownHostsFile, "The known_hosts file used by --ssh-strict-host-key-checking")
+}
+
+// hostKeyOptionsFromFlags reads the flags added by addHostKeyFlags
+func hostKeyOptionsFromFlags(command *cobra.Command) (string, string, error) {
+	checking, _ := command.Flags().GetString("ssh-strict-host-key-checking")
+	knownHosts, _ := command.Flags().GetString("known-hosts-file")
+
+	switch checking {
+	case hostKeyCheckingNo, hostKeyCheckingYes, hostKeyCheckingAcceptNew:
+	default:
+		return "", "", fmt.Errorf("--ssh-strict-host-key-checking must be yes, no or accept-new, not %q", checking)
+	}
+
+	return checking, expandPath(knownHosts), nil
+}
+
+// hostKeyCallback returns the callback which checks the key of each host,
+// including any jump host, against knownHosts
+func hostKeyCallback(checking, knownHosts string) ssh.HostKeyCallback {
+	if len(checking) == 0 || checking == hostKeyCheckingNo {
+		return ssh.InsecureIgnoreHostKey()
+	}
+
+	if len(knownHosts) == 0 {
+		knownHosts = expandPath(defaultKnownHostsFile)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsLock.Lock()
+		defer knownHostsLock.Unlock()
+
+		host := knownhosts.Normalize(hostname)
+
+		err := checkHostKey(knownHosts, hostname, remote, key)
+
+		var keyErr *knownhosts.KeyError
+		switch e := err.(type) {
+		case nil:
+			return nil
+		case *knownhosts.RevokedError:
+			return fmt.Errorf("the host key of %s is revoked on line %d of %s", host, e.Revoked.Line, e.Revoked.Filename)
+		case *knownhosts.KeyError:
+			keyErr = e
+		default:
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			want := keyErr.Want[0]
+			for _, k := range keyErr.Want {
+				if k.Key.Type() == key.Type() {
+					want = k
+				}
+			}
+			return fmt.Errorf("the %s host key of %s does not match line %d of %s, the host may have been reinstalled or the connection intercepted. Check the key, then remove the old one with: ssh-keygen -R %q -f %s",
+				key.Type(), host, want.Line, want.Filename, host, knownHosts)
+		}
+
+		if checking == hostKeyCheckingAcceptNew {
+			logInfof("Adding the %s host key of %s to %s\n", key.Type(), host, knownHosts)
+			return appendHostKey(knownHosts, hostname, key)
+		}
+
+		return fmt.Errorf("%s is not in %s, add its key with: ssh-keyscan %s >> %s, or use --ssh-strict-host-key-checking accept-new",
+			host, knownHosts, keyscanArgs(hostname), knownHosts)
+	}
+}
+
+// checkHostKey checks key against knownHosts, which is read again for each
+// host so that keys added by accept-new are seen. A file which does not
+// exist yet holds no hosts.
+func checkHostKey(knownHosts, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if _, err := os.Stat(knownHosts); os.IsNotExist(err) {
+		return &knownhosts.KeyError{}
+	}
+
+	callback, err := knownhosts.New(knownHosts)
+	if err != nil {
+		return errors.Wrap(err, "unable to read known hosts")
+	}
+
+	return callback(hostname, remote, key)
+}
+
+// appendHostKey adds key for hostname to knownHosts, creating it if needed
+func appendHostKey(knownHosts, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(knownHosts), 0700); err != nil {
+		return errors.Wrap(err, "unable to create the known hosts directory")
+	}
+
+	file, err := os.OpenFile(knownHosts, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "unable to add to known hosts")
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintln(file, knownhosts.Line([]string{hostname}, key))
+	return err
+}
+
+// keyscanArgs returns the arguments for ssh-keyscan to fetch the key of a
+// host:port address
+func keyscanArgs(hostname string) string {
+	host, port, err := net.SplitHostPort(hostname)
+	if err != nil {
+		return hostname
+	}
+	if port == "22" {
+		return host
+	}
+	return "-p " + port + " " + host
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public
+}
+
+// nodeAddr is the remote address which the ssh client passes to the callback
+var nodeAddr = &net.TCPAddr{IP: net.ParseIP("192.168.0.100"), Port: 22}
+
+func Test_hostKeyCallback_AcceptNewThenYes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "known-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	knownHosts := filepath.Join(dir, ".ssh", "known_hosts")
+	key := newHostKey(t)
+
+	err = hostKeyCallback(hostKeyCheckingYes, knownHosts)("192.168.0.100:22", nodeAddr, key)
+	if err == nil || !strings.Contains(err.Error(), "ssh-keyscan 192.168.0.100") {
+		t.Errorf("want an error for an unknown host, got: %v", err)
+	}
+
+	if err := hostKeyCallback(hostKeyCheckingAcceptNew, knownHosts)("192.168.0.100:2222", nodeAddr, key); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "[192.168.0.100]:2222 ssh-rsa ") {
+		t.Errorf("want the key added with the port, got: %q", data)
+	}
+
+	if err := hostKeyCallback(hostKeyCheckingYes, knownHosts)("192.168.0.100:2222", nodeAddr, key); err != nil {
+		t.Errorf("want the added key to be accepted, got: %s", err)
+	}
+
+	err = hostKeyCallback(hostKeyCheckingAcceptNew, knownHosts)("192.168.0.100:2222", nodeAddr, newHostKey(t))
+	if err == nil || !strings.Contains(err.Error(), "does not match line 1") {
+		t.Errorf("want an error for a changed key, got: %v", err)
+	}
+}
+
+func Test_hostKeyCallback_No(t *testing.T) {
+	if err := hostKeyCallback(hostKeyCheckingNo, "/does/not/exist")("192.168.0.100:22", nodeAddr, newHostKey(t)); err != nil {
+		t.Errorf("want no check, got: %s", err)
+	}
+}
+
+func Test_hostKeyCallback_HashedAndRevoked(t *testing.T) {
+	key := newHostKey(t)
+	revoked := newHostKey(t)
+
+	tmpfile, err := ioutil.TempFile("", "known_hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpfile.WriteString("# comment\n" +
+		knownhosts.HashHostname("node-1") + " " + string(ssh.MarshalAuthorizedKey(key)) +
+		"node-*,!node-9 " + string(ssh.MarshalAuthorizedKey(revoked)) +
+		"@revoked * " + string(ssh.MarshalAuthorizedKey(revoked)))
+	tmpfile.Close()
+
+	callback := hostKeyCallback(hostKeyCheckingYes, tmpfile.Name())
+
+	if err := callback("node-1:22", nodeAddr, key); err != nil {
+		t.Errorf("want the hashed entry to match, got: %s", err)
+	}
+
+	if err := callback("node-2:22", nodeAddr, revoked); err == nil || !strings.Contains(err.Error(), "revoked on line 4") {
+		t.Errorf("want the key to be revoked, got: %v", err)
+	}
+
+	if err := callback("node-9:22", nodeAddr, key); err == nil || !strings.Contains(err.Error(), "node-9 is not in") {
+		t.Errorf("want node-9 to be unknown, got: %v", err)
+	}
+}
+
+func Test_hostKeyOptionsFromFlags_Invalid(t *testing.T) {
+	command := MakeInstall()
+	command.Flags().Set("ssh-strict-host-key-checking", "ask")
+
+	if _, _, err := hostKeyOptionsFromFlags(command); err == nil {
+		t.Errorf("want an error for an unknown policy")
+	}
+}
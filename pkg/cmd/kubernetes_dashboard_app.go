@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallDashboard() *cobra.Command {
+	var dashboard = &cobra.Command{
+		Use:          "kubernetes-dashboard",
+		Short:        "Install kubernetes-dashboard",
+		Long:         `Install kubernetes-dashboard, a web UI for the cluster`,
+		Example:      `  k3sup app install kubernetes-dashboard --admin-user`,
+		SilenceUsage: true,
+	}
+
+	dashboard.Flags().String("version", "v2.0.4", "The version of kubernetes-dashboard to install")
+	dashboard.Flags().Bool("admin-user", false, "Create an admin-user ServiceAccount bound to cluster-admin, to log into the dashboard")
+
+	dashboard.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		version, _ := command.Flags().GetString("version")
+		manifest := fmt.Sprintf("https://raw.githubusercontent.com/kubernetes/dashboard/%s/aio/deploy/recommended.yaml", version)
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return printManifests(command, manifest)
+		}
+
+		if err := applyManifests("kubernetes-dashboard", manifest); err != nil {
+			return err
+		}
+
+		adminUser, _ := command.Flags().GetBool("admin-user")
+		if adminUser {
+			if err := createDashboardAdmin(); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("kubernetes-dashboard", version); err != nil {
+			return err
+		}
+
+		logInfo(dashboardInstallMsg)
+
+		return nil
+	}
+
+	return dashboard
+}
+
+// createDashboardAdmin creates a ServiceAccount with cluster-admin, whose
+// token can be used to log into the dashboard
+func createDashboardAdmin() error {
+	res, err := kubectlTask("create", "serviceaccount", "admin-user", "--namespace", "kubernetes-dashboard")
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 && !strings.Contains(res.Stderr, "AlreadyExists") {
+		return fmt.Errorf("unable to create the admin-user ServiceAccount: %s", res.Stderr)
+	}
+
+	if err := recordResource("kubernetes-dashboard", "serviceaccount", "admin-user", "kubernetes-dashboard"); err != nil {
+		return err
+	}
+
+	res, err = kubectlTask("create", "clusterrolebinding", "kubernetes-dashboard-admin-user",
+		"--clusterrole", "cluster-admin", "--serviceaccount=kubernetes-dashboard:admin-user")
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 && !strings.Contains(res.Stderr, "AlreadyExists") {
+		return fmt.Errorf("unable to create the admin-user ClusterRoleBinding: %s", res.Stderr)
+	}
+
+	return recordResource("kubernetes-dashboard", "clusterrolebinding", "kubernetes-dashboard-admin-user", "")
+}
+
+const dashboardInfoMsg = `# Forward the dashboard to your machine
+kubectl proxy
+
+# Then open:
+# http://localhost:8001/api/v1/namespaces/kubernetes-dashboard/services/https:kubernetes-dashboard:/proxy/
+
+# If you installed with --admin-user, log in with its token:
+kubectl -n kubernetes-dashboard describe secret \
+  $(kubectl -n kubernetes-dashboard get secret | grep admin-user-token | awk '{print $1}')
+
+# Find out more at:
+# https://github.com/kubernetes/dashboard`
+
+const dashboardInstallMsg = `=======================================================================
+= kubernetes-dashboard has been installed.                            =
+=======================================================================
+
+` + dashboardInfoMsg + `
+
+` + thanksForUsing
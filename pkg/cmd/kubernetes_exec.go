@@ -1,32 +1,48 @@
 package cmd
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/http"
-	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 
+	"github.com/alexellis/k3sup/pkg/apps"
+
 	execute "github.com/alexellis/go-execute/pkg/v1"
+	"github.com/spf13/cobra"
 )
 
 const helmVersion = "v2.15.2"
 
-func fetchChart(path, chart string) error {
+const helm3Version = "v3.0.2"
+
+// stableRepoURL is added for helm 3, which unlike helm 2 has no stable repo
+// configured by default
+const stableRepoURL = "https://kubernetes-charts.storage.googleapis.com"
+
+// fetchChart downloads and extracts chart to path, the latest version is
+// fetched unless version is given
+func fetchChart(path, chart, version string, helm3 bool) error {
 	mkErr := os.MkdirAll(path, 0700)
 
 	if mkErr != nil {
 		return mkErr
 	}
 
+	versionStr := ""
+	if len(version) > 0 {
+		versionStr = " --version " + version
+	}
+
 	task := execute.ExecTask{
-		Command: fmt.Sprintf("%s fetch %s --untar --untardir %s", localBinary("helm"), chart, path),
+		Command: fmt.Sprintf("%s fetch %s --untar --untardir %s%s", helmBinary(helm3), chart, path, versionStr),
 		Env:     os.Environ(),
 	}
-	res, err := task.Execute()
+	res, err := runTask(task)
 
 	if err != nil {
 		return err
@@ -46,12 +62,14 @@ func getArchitecture() string {
 	return arch
 }
 
-func templateChart(basePath, chart, namespace, outputPath, values string, overrides map[string]string) error {
+// templateChart renders chart to outputPath using the chart's values file,
+// followed by any userValues files and then the overrides
+func templateChart(basePath, chart, namespace, outputPath, values string, overrides map[string]string, userValues []string) error {
 
 	rmErr := os.RemoveAll(outputPath)
 
 	if rmErr != nil {
-		log.Printf("Error cleaning up: %s, %s\n", outputPath, rmErr.Error())
+		logWarnf("Error cleaning up: %s, %s\n", outputPath, rmErr.Error())
 	}
 
 	mkErr := os.MkdirAll(outputPath, 0700)
@@ -71,6 +89,10 @@ func templateChart(basePath, chart, namespace, outputPath, values string, overri
 		valuesStr = "--values " + path.Join(chartRoot, values)
 	}
 
+	for _, userValuesFile := range userValues {
+		valuesStr += " --values " + userValuesFile
+	}
+
 	task := execute.ExecTask{
 		Command: fmt.Sprintf("%s template %s --name %s --namespace %s --output-dir %s %s %s",
 			localBinary("helm"), chart, chart, namespace, outputPath, valuesStr, overridesStr),
@@ -78,7 +100,7 @@ func templateChart(basePath, chart, namespace, outputPath, values string, overri
 		Cwd: basePath,
 	}
 
-	res, err := task.Execute()
+	res, err := runTask(task)
 
 	if err != nil {
 		return err
@@ -89,9 +111,196 @@ func templateChart(basePath, chart, namespace, outputPath, values string, overri
 	}
 
 	if len(res.Stderr) > 0 {
-		log.Printf("stderr: %s\n", res.Stderr)
+		logDebugf("stderr: %s\n", res.Stderr)
+	}
+
+	return nil
+}
+
+// helm3Install installs or upgrades the release named after chart from the
+// chart fetched to basePath, tiller is not used.
+func helm3Install(basePath, chart, namespace, values string, overrides map[string]string, userValues []string) error {
+	chartRoot := path.Join(basePath, chart)
+
+	args := []string{"upgrade", "--install", chart, chartRoot, "--namespace", namespace}
+
+	if len(values) > 0 {
+		args = append(args, "--values", path.Join(chartRoot, values))
+	}
+
+	for _, userValuesFile := range userValues {
+		args = append(args, "--values", userValuesFile)
+	}
+
+	for k, v := range overrides {
+		args = append(args, "--set", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	task := execute.ExecTask{
+		Command: helmBinary(true),
+		Args:    args,
+		Env:     os.Environ(),
+		Cwd:     basePath,
+	}
+
+	res, err := runTask(task)
+
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		return fmt.Errorf("exit code %d, stderr: %s", res.ExitCode, res.Stderr)
+	}
+
+	return nil
+}
+
+// helm3Uninstall removes a release which was installed with helm3Install
+func helm3Uninstall(release, namespace string) error {
+	task := execute.ExecTask{
+		Command: helmBinary(true),
+		Args:    []string{"uninstall", release, "--namespace", namespace},
+		Env:     os.Environ(),
+	}
+
+	res, err := runTask(task)
+
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 && !strings.Contains(res.Stderr, "not found") {
+		return fmt.Errorf("exit code %d, stderr: %s", res.ExitCode, res.Stderr)
+	}
+
+	return nil
+}
+
+// helm3Manifest returns the manifest which was applied for a release
+func helm3Manifest(release, namespace string) (string, error) {
+	task := execute.ExecTask{
+		Command: helmBinary(true),
+		Args:    []string{"get", "manifest", release, "--namespace", namespace},
+		Env:     os.Environ(),
+	}
+
+	res, err := runTask(task)
+
+	if err != nil {
+		return "", err
+	}
+
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("exit code %d, stderr: %s", res.ExitCode, res.Stderr)
+	}
+
+	return res.Stdout, nil
+}
+
+// chartValuesFromFlags applies the --set flags over the app's overrides and
+// returns the absolute paths of any --values files
+func chartValuesFromFlags(command *cobra.Command, overrides map[string]string) (map[string]string, []string, error) {
+	merged := map[string]string{}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+
+	setVals, _ := command.Flags().GetStringArray("set")
+	for _, setV := range setVals {
+		index := strings.Index(setV, "=")
+		if index < 1 {
+			return nil, nil, fmt.Errorf("--set should be given as key=value, got: %q", setV)
+		}
+		merged[setV[:index]] = setV[index+1:]
+	}
+
+	valuesFiles, _ := command.Flags().GetStringArray("values")
+	userValues := []string{}
+	for _, valuesFile := range valuesFiles {
+		absPath, err := filepath.Abs(expandPath(valuesFile))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := os.Stat(absPath); err != nil {
+			return nil, nil, fmt.Errorf("unable to read --values file: %s", err)
+		}
+		userValues = append(userValues, absPath)
 	}
 
+	return merged, userValues, nil
+}
+
+// rejectValuesFlags returns an error when --set or --values are given to an
+// app which is not installed from a helm chart
+func rejectValuesFlags(command *cobra.Command) error {
+	if command.Flags().Changed("set") || command.Flags().Changed("values") {
+		return fmt.Errorf("--set and --values are only supported for apps installed from a helm chart, %s is not", command.Name())
+	}
+	return nil
+}
+
+// setManifestNamespace returns manifests which are published for the from
+// namespace, rewritten for the to namespace. They are downloaded or read and
+// written to the temporary folder, or returned as they are when to is from.
+func setManifestNamespace(from, to string, manifests ...string) ([]string, error) {
+	if from == to {
+		return manifests, nil
+	}
+
+	rewritten := []string{}
+	for i, manifest := range manifests {
+		var data []byte
+		var err error
+		if apps.IsURL(manifest) {
+			data, err = fetchManifest(manifest)
+		} else {
+			data, err = ioutil.ReadFile(manifest)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := writeTempFile(fmt.Sprintf("%s_%02d", to, i), replaceNamespace(data, from, to))
+		if err != nil {
+			return nil, err
+		}
+		rewritten = append(rewritten, file)
+	}
+
+	return rewritten, nil
+}
+
+// replaceNamespace rewrites the namespace of objects, and of the subjects
+// of role bindings, from one namespace to another
+func replaceNamespace(data []byte, from, to string) []byte {
+	re := regexp.MustCompile(`(?m)^([ \t]*(?:- )?namespace:[ \t]*)["']?` + regexp.QuoteMeta(from) + `["']?[ \t]*$`)
+	return re.ReplaceAll(data, []byte("${1}"+to))
+}
+
+// applyManifests applies each file, folder or URL with kubectl and records
+// it for app, so that it can be removed with "k3sup app uninstall"
+func applyManifests(app string, manifests ...string) error {
+	return applyNamespacedManifests(app, "", manifests...)
+}
+
+// applyNamespacedManifests applies manifests to namespace, for objects which
+// do not set their own
+func applyNamespacedManifests(app, namespace string, manifests ...string) error {
+	for _, manifest := range manifests {
+		parts := []string{"apply", "-R", "-f", manifest}
+		if len(namespace) > 0 {
+			parts = append(parts, "--namespace", namespace)
+		}
+
+		if err := kubectl(parts...); err != nil {
+			return fmt.Errorf("unable to apply %s: %s", manifest, err)
+		}
+
+		if err := recordNamespacedManifest(app, namespace, manifest); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -100,12 +309,21 @@ func localBinary(name string) string {
 	return path.Join(path.Join(home, ".k3sup/.bin/"), name)
 }
 
-func addHelmRepo(name, url string) error {
+// helmBinary returns the path of the helm binary downloaded by k3sup, helm 3
+// is kept in its own folder so that both versions can be used side by side
+func helmBinary(helm3 bool) string {
+	if helm3 {
+		return localBinary("helm3/helm")
+	}
+	return localBinary("helm")
+}
+
+func addHelmRepo(name, url string, helm3 bool) error {
 	task := execute.ExecTask{
-		Command: fmt.Sprintf("%s repo add %s %s", localBinary("helm"), name, url),
+		Command: fmt.Sprintf("%s repo add %s %s", helmBinary(helm3), name, url),
 		Env:     os.Environ(),
 	}
-	res, err := task.Execute()
+	res, err := runTask(task)
 
 	if err != nil {
 		return err
@@ -117,12 +335,12 @@ func addHelmRepo(name, url string) error {
 	return nil
 }
 
-func updateHelmRepos() error {
+func updateHelmRepos(helm3 bool) error {
 	task := execute.ExecTask{
-		Command: fmt.Sprintf("%s repo update", localBinary("helm")),
+		Command: fmt.Sprintf("%s repo update", helmBinary(helm3)),
 		Env:     os.Environ(),
 	}
-	res, err := task.Execute()
+	res, err := runTask(task)
 
 	if err != nil {
 		return err
@@ -140,7 +358,7 @@ func helmInit() error {
 		Env:     os.Environ(),
 		Args:    []string{"init", "--client-only"},
 	}
-	res, err := task.Execute()
+	res, err := runTask(task)
 
 	if err != nil {
 		return err
@@ -158,7 +376,7 @@ func kubectlTask(parts ...string) (execute.ExecResult, error) {
 		Args:    parts,
 	}
 
-	res, err := task.Execute()
+	res, err := runTask(task)
 
 	return res, err
 }
@@ -169,7 +387,7 @@ func kubectl(parts ...string) error {
 		Args:    parts,
 	}
 
-	res, err := task.Execute()
+	res, err := runTask(task)
 
 	if err != nil {
 		return err
@@ -181,6 +399,63 @@ func kubectl(parts ...string) error {
 	return nil
 }
 
+// secretValue returns the decoded value of key in a secret, it is empty
+// when the secret does not exist
+func secretValue(namespace, name, key string) (string, error) {
+	res, err := kubectlTask("get", "secret", name, "--namespace", namespace,
+		"--ignore-not-found", "--output", "jsonpath={.data."+key+"}")
+	if err != nil {
+		return "", err
+	}
+
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("unable to read the %s secret: %s", name, res.Stderr)
+	}
+
+	value := strings.TrimSpace(res.Stdout)
+	if len(value) == 0 {
+		return "", nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("unable to decode %s from the %s secret: %s", key, name, err)
+	}
+
+	return string(decoded), nil
+}
+
+// createNamespace creates namespace for app when it is absent. Only a
+// namespace which was created is recorded, so that "k3sup app uninstall
+// --purge" keeps namespaces such as default.
+func createNamespace(app, namespace string) error {
+	res, err := kubectlTask("create", "namespace", namespace)
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		if strings.Contains(res.Stderr, "AlreadyExists") {
+			return nil
+		}
+		return fmt.Errorf("unable to create the %s namespace: %s", namespace, res.Stderr)
+	}
+
+	return recordNamespace(app, namespace)
+}
+
+// waitForCRDs waits for CustomResourceDefinitions to be established, so
+// that resources which use them can be applied
+func waitForCRDs(crds ...string) error {
+	for _, crd := range crds {
+		err := kubectl("wait", "--for", "condition=established", "--timeout", "60s", "crd/"+crd)
+		if err != nil {
+			return fmt.Errorf("the %s CustomResourceDefinition was not established: %s", crd, err)
+		}
+	}
+	return nil
+}
+
 func getDefaultKubeconfig() string {
 	kubeConfigPath := path.Join(os.Getenv("HOME"), ".kube/config")
 
@@ -191,15 +466,23 @@ func getDefaultKubeconfig() string {
 	return kubeConfigPath
 }
 
-func tryDownloadHelm(userPath, clientArch, clientOS string) (string, error) {
-	helmBinaryPath := path.Join(path.Join(userPath, ".bin"), "helm")
+func tryDownloadHelm(userPath, clientArch, clientOS string, helm3 bool) (string, error) {
+	helmBinaryPath := helmBinary(helm3)
 	if _, statErr := os.Stat(helmBinaryPath); statErr != nil {
-		downloadHelm(userPath, clientArch, clientOS)
-
-		err := helmInit()
-		if err != nil {
+		if err := downloadHelm(userPath, clientArch, clientOS, helm3); err != nil {
 			return "", err
 		}
+
+		if helm3 {
+			if err := addHelmRepo("stable", stableRepoURL, true); err != nil {
+				return "", err
+			}
+		} else {
+			err := helmInit()
+			if err != nil {
+				return "", err
+			}
+		}
 	}
 	return helmBinaryPath, nil
 }
@@ -207,17 +490,17 @@ func tryDownloadHelm(userPath, clientArch, clientOS string) (string, error) {
 // getClientArch returns a pair of arch and os
 func getClientArch() (string, string) {
 	task := execute.ExecTask{Command: "uname", Args: []string{"-m"}}
-	res, err := task.Execute()
+	res, err := runTask(task)
 	if err != nil {
-		log.Println(err)
+		logWarn(err)
 	}
 
 	arch := strings.TrimSpace(res.Stdout)
 
 	taskOS := execute.ExecTask{Command: "uname", Args: []string{"-s"}}
-	resOS, errOS := taskOS.Execute()
+	resOS, errOS := runTask(taskOS)
 	if errOS != nil {
-		log.Println(errOS)
+		logWarn(errOS)
 	}
 
 	os := strings.TrimSpace(resOS.Stdout)
@@ -238,22 +521,20 @@ func getHelmURL(arch, os, version string) string {
 	return fmt.Sprintf("https://get.helm.sh/helm-%s-%s-%s.tar.gz", version, osSuffix, archSuffix)
 }
 
-func downloadHelm(userPath, clientArch, clientOS string) error {
-	helmURL := getHelmURL(clientArch, clientOS, helmVersion)
-	fmt.Println(helmURL)
-	parsedURL, _ := url.Parse(helmURL)
+func downloadHelm(userPath, clientArch, clientOS string, helm3 bool) error {
+	version := helmVersion
+	binDir := path.Join(userPath, ".bin")
+	if helm3 {
+		version = helm3Version
+		binDir = path.Join(binDir, "helm3")
+	}
 
-	res, err := http.DefaultClient.Get(parsedURL.String())
-	if err != nil {
+	if err := os.MkdirAll(binDir, 0700); err != nil {
 		return err
 	}
 
-	defer res.Body.Close()
-	r := ioutil.NopCloser(res.Body)
-	untarErr := Untar(r, path.Join(userPath, ".bin"))
-	if untarErr != nil {
-		return untarErr
-	}
+	helmURL := getHelmURL(clientArch, clientOS, version)
+	logDebugf("%s\n", helmURL)
 
-	return nil
+	return downloadTarball(helmURL, helmURL+".sha256", binDir)
 }
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_chartValuesFromFlags_SetOverridesDefaults(t *testing.T) {
+	command := &cobra.Command{}
+	command.Flags().StringArray("set", []string{}, "")
+	command.Flags().StringArray("values", []string{}, "")
+	command.Flags().Set("set", "gateway.replicas=2")
+	command.Flags().Set("set", "basic_auth=a=b")
+
+	overrides, userValues, err := chartValuesFromFlags(command, map[string]string{"gateway.replicas": "1", "rbac": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"gateway.replicas": "2", "basic_auth": "a=b", "rbac": "true"}
+	for k, v := range want {
+		if overrides[k] != v {
+			t.Errorf("%s want: %q, got: %q", k, v, overrides[k])
+		}
+	}
+
+	if len(userValues) != 0 {
+		t.Errorf("want no values files, got: %v", userValues)
+	}
+}
+
+func Test_chartValuesFromFlags_InvalidSet(t *testing.T) {
+	command := &cobra.Command{}
+	command.Flags().StringArray("set", []string{}, "")
+	command.Flags().StringArray("values", []string{}, "")
+	command.Flags().Set("set", "gateway.replicas")
+
+	if _, _, err := chartValuesFromFlags(command, nil); err == nil {
+		t.Errorf("want error for --set without a value")
+	}
+}
+
+func Test_replaceNamespace(t *testing.T) {
+	manifest := `kind: ClusterRoleBinding
+subjects:
+- kind: ServiceAccount
+  name: argocd-server
+  namespace: argocd
+---
+kind: Deployment
+metadata:
+  name: argocd-server
+  namespace: "argocd"
+  labels:
+    app.kubernetes.io/part-of: argocd
+`
+
+	got := string(replaceNamespace([]byte(manifest), "argocd", "gitops"))
+	want := `kind: ClusterRoleBinding
+subjects:
+- kind: ServiceAccount
+  name: argocd-server
+  namespace: gitops
+---
+kind: Deployment
+metadata:
+  name: argocd-server
+  namespace: gitops
+  labels:
+    app.kubernetes.io/part-of: argocd
+`
+
+	if got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	execute "github.com/alexellis/go-execute/pkg/v1"
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallLinkerd() *cobra.Command {
+	var linkerd = &cobra.Command{
+		Use:   "linkerd",
+		Short: "Install linkerd",
+		Long: `Install the linkerd service mesh. The linkerd CLI is downloaded to
+~/.k3sup/.bin/, the cluster is checked with "linkerd check --pre", then k3sup
+waits for "linkerd check" to pass after installing the control plane.`,
+		Example:      `  k3sup app install linkerd --version stable-2.7.0`,
+		SilenceUsage: true,
+	}
+
+	linkerd.Flags().String("version", "stable-2.7.0", "The version of linkerd to install")
+
+	linkerd.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		version, _ := command.Flags().GetString("version")
+		dryRun, _ := command.Flags().GetBool("dry-run")
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		if err := tryDownloadLinkerd(clientArch, clientOS, version); err != nil {
+			return err
+		}
+
+		if !dryRun {
+			if err := linkerdCheck("--pre"); err != nil {
+				return err
+			}
+		}
+
+		res, err := linkerdTask("install")
+		if err != nil {
+			return err
+		}
+
+		if res.ExitCode != 0 {
+			return fmt.Errorf("unable to render the linkerd control plane: %s", res.Stderr)
+		}
+
+		manifest, err := writeTempFile("linkerd", []byte(res.Stdout))
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			return printManifests(command, manifest)
+		}
+
+		if err := applyManifests("linkerd", manifest); err != nil {
+			return err
+		}
+
+		logInfo("Waiting for linkerd check to pass")
+		if err := linkerdCheck(); err != nil {
+			return err
+		}
+
+		if err := recordInstalled("linkerd", version); err != nil {
+			return err
+		}
+
+		logInfo(linkerdInstallMsg)
+
+		return nil
+	}
+
+	return linkerd
+}
+
+// tryDownloadLinkerd downloads the linkerd CLI, unless the version
+// requested is already present
+func tryDownloadLinkerd(clientArch, clientOS, version string) error {
+	if res, err := linkerdTask("version", "--client", "--short"); err == nil && res.ExitCode == 0 {
+		if strings.TrimSpace(res.Stdout) == version {
+			return nil
+		}
+	}
+
+	linkerdURL := getLinkerdURL(clientArch, clientOS, version)
+	logDebugf("%s\n", linkerdURL)
+
+	return downloadFile(linkerdURL, linkerdURL+".sha256", localBinary("linkerd"), 0700)
+}
+
+func getLinkerdURL(arch, os, version string) string {
+	suffix := strings.ToLower(os)
+
+	if strings.HasPrefix(arch, "armv7") {
+		suffix += "-arm"
+	} else if strings.HasPrefix(arch, "aarch64") || arch == "arm64" {
+		suffix += "-arm64"
+	}
+
+	return fmt.Sprintf("https://github.com/linkerd/linkerd2/releases/download/%s/linkerd2-cli-%s-%s", version, version, suffix)
+}
+
+// linkerdCheck runs "linkerd check", which waits for the control plane to
+// become healthy
+func linkerdCheck(args ...string) error {
+	res, err := linkerdTask(append([]string{"check"}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	logDebugf("%s\n", res.Stdout)
+
+	if res.ExitCode != 0 {
+		return fmt.Errorf("linkerd check %s failed: %s", strings.Join(args, " "), res.Stderr)
+	}
+
+	return nil
+}
+
+func linkerdTask(args ...string) (execute.ExecResult, error) {
+	task := execute.ExecTask{
+		Command: localBinary("linkerd"),
+		Args:    args,
+		Env:     os.Environ(),
+	}
+
+	return runTask(task)
+}
+
+const linkerdInfoMsg = `# Add the linkerd CLI to your PATH
+export PATH=$PATH:$HOME/.k3sup/.bin/
+
+# Open the linkerd dashboard
+linkerd dashboard &
+
+# Add the proxy to the Pods of a Deployment
+kubectl get deploy/NAME -o yaml | linkerd inject - | kubectl apply -f -
+
+# Find out more at:
+# https://linkerd.io/2/getting-started/`
+
+const linkerdInstallMsg = `=======================================================================
+= linkerd has been installed.                                         =
+=======================================================================
+
+` + linkerdInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,23 @@
+package cmd
+
+import "testing"
+
+func Test_getLinkerdURL(t *testing.T) {
+	cases := []struct {
+		arch string
+		os   string
+		want string
+	}{
+		{"x86_64", "Linux", "https://github.com/linkerd/linkerd2/releases/download/stable-2.7.0/linkerd2-cli-stable-2.7.0-linux"},
+		{"armv7l", "Linux", "https://github.com/linkerd/linkerd2/releases/download/stable-2.7.0/linkerd2-cli-stable-2.7.0-linux-arm"},
+		{"aarch64", "Linux", "https://github.com/linkerd/linkerd2/releases/download/stable-2.7.0/linkerd2-cli-stable-2.7.0-linux-arm64"},
+		{"x86_64", "Darwin", "https://github.com/linkerd/linkerd2/releases/download/stable-2.7.0/linkerd2-cli-stable-2.7.0-darwin"},
+	}
+
+	for _, c := range cases {
+		got := getLinkerdURL(c.arch, c.os, "stable-2.7.0")
+		if got != c.want {
+			t.Errorf("%s %s, want: %s, got: %s", c.arch, c.os, c.want, got)
+		}
+	}
+}
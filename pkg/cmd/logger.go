@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	execute "github.com/alexellis/go-execute/pkg/v1"
+	"github.com/alexellis/k3sup/pkg/provision"
+	"github.com/spf13/cobra"
+)
+
+// logLevel is set by --verbose and --quiet, progress is printed at
+// infoLevel unless either is given
+type logLevel int
+
+const (
+	quietLevel logLevel = iota
+	infoLevel
+	debugLevel
+)
+
+var (
+	verbose bool
+	quiet   bool
+	level   = infoLevel
+)
+
+// AddLogFlags adds the --verbose and --quiet flags to root and all of its
+// sub-commands
+func AddLogFlags(root *cobra.Command) {
+	root.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print debug output, including each SSH and kubectl command which is run")
+	root.PersistentFlags().BoolVar(&quiet, "quiet", false, "Only print warnings, errors and the result of the command")
+
+	flagHooks = append(flagHooks, func() error {
+		if verbose && quiet {
+			return fmt.Errorf("give --verbose or --quiet, not both")
+		}
+
+		if verbose {
+			level = debugLevel
+		} else if quiet {
+			level = quietLevel
+		}
+		return nil
+	})
+}
+
+func init() {
+	provision.SetLogger(provisionLogger{})
+}
+
+// provisionLogger prints the progress of pkg/provision at the level given
+// by --verbose and --quiet
+type provisionLogger struct{}
+
+func (provisionLogger) Infof(format string, a ...interface{}) {
+	logInfof(format, a...)
+}
+
+func (provisionLogger) Debugf(format string, a ...interface{}) {
+	logDebugf(format, a...)
+}
+
+// logDebugf prints detail such as the commands run over SSH and locally,
+// which is only shown with --verbose
+func logDebugf(format string, a ...interface{}) {
+	if level >= debugLevel {
+		clearProgress()
+		fmt.Fprintf(textOutput, format, a...)
+	}
+}
+
+// logInfof prints progress, which is hidden by --quiet
+func logInfof(format string, a ...interface{}) {
+	if level >= infoLevel {
+		clearProgress()
+		fmt.Fprintf(textOutput, format, a...)
+	}
+}
+
+// logInfo prints a message such as an app's install message, which is
+// hidden by --quiet
+func logInfo(a ...interface{}) {
+	if level >= infoLevel {
+		clearProgress()
+		fmt.Fprintln(textOutput, a...)
+	}
+}
+
+// logWarnf prints a warning to stderr at every level
+func logWarnf(format string, a ...interface{}) {
+	clearProgress()
+	fmt.Fprintf(os.Stderr, format, a...)
+}
+
+// logWarn prints a warning to stderr at every level
+func logWarn(a ...interface{}) {
+	clearProgress()
+	fmt.Fprintln(os.Stderr, a...)
+}
+
+// runTask runs a local command and captures its output, which is only
+// printed with --verbose. Each task has its own buffers, so tasks can run
+// from several goroutines at once.
+func runTask(task execute.ExecTask) (execute.ExecResult, error) {
+	logDebugf("exec: %s %s\n", task.Command, strings.Join(task.Args, " "))
+
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	cmd := taskCommand(task)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return execute.ExecResult{}, err
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	logDebugf("res: %s\n", stdout.String())
+
+	return execute.ExecResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: exitCode,
+	}, nil
+}
+
+// taskCommand builds the command for task as go-execute does, with Shell the
+// command and its arguments are run by bash
+func taskCommand(task execute.ExecTask) *exec.Cmd {
+	var cmd *exec.Cmd
+
+	switch {
+	case task.Shell && len(task.Args) == 0:
+		cmd = exec.Command("/bin/bash", "-c", task.Command)
+	case task.Shell:
+		cmd = exec.Command("/bin/bash", "-c", task.Command+" "+strings.Join(task.Args, " "))
+	case strings.Index(task.Command, " ") > 0:
+		parts := strings.Split(task.Command, " ")
+		cmd = exec.Command(parts[0], parts[1:]...)
+	default:
+		cmd = exec.Command(task.Command, task.Args...)
+	}
+
+	cmd.Dir = task.Cwd
+	if len(task.Env) > 0 {
+		cmd.Env = append(os.Environ(), task.Env...)
+	}
+
+	return cmd
+}
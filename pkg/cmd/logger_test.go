@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	execute "github.com/alexellis/go-execute/pkg/v1"
+)
+
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := textOutput
+	textOutput = w
+	f()
+	textOutput = stdout
+	w.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func Test_logLevels(t *testing.T) {
+	defer func() { level = infoLevel }()
+
+	cases := []struct {
+		level logLevel
+		want  string
+	}{
+		{quietLevel, ""},
+		{infoLevel, "info\n"},
+		{debugLevel, "debug\ninfo\n"},
+	}
+
+	for _, c := range cases {
+		level = c.level
+		got := captureStdout(t, func() {
+			logDebugf("debug\n")
+			logInfof("info\n")
+		})
+
+		if got != c.want {
+			t.Errorf("level %d want: %q, got: %q", c.level, c.want, got)
+		}
+	}
+}
+
+func Test_runTask_CapturesOutputOfEachTask(t *testing.T) {
+	stdout := captureStdout(t, func() {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				want := fmt.Sprintf("task %d", i)
+				res, err := runTask(execute.ExecTask{Command: "echo " + want + "; echo failed >&2; exit 3", Shell: true})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if res.Stdout != want+"\n" || res.Stderr != "failed\n" || res.ExitCode != 3 {
+					t.Errorf("want %q, failed and exit code 3, got: %v", want, res)
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+
+	if len(stdout) > 0 {
+		t.Errorf("want no output without --verbose, got: %q", stdout)
+	}
+}
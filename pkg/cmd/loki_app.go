@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/apps"
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// lokiDatasource holds the values for templates/loki/datasource.yaml
+type lokiDatasource struct {
+	GrafanaNamespace string
+	LokiURL          string
+}
+
+func makeInstallLoki() *cobra.Command {
+	var loki = &cobra.Command{
+		Use:   "loki",
+		Short: "Install loki and promtail",
+		Long: `Install loki for log aggregation, with promtail on each node to ship the
+logs of every Pod. Use --grafana to add loki as a datasource to the grafana
+installed by the monitoring app.`,
+		Example: `  k3sup app install loki
+  k3sup app install loki --grafana`,
+		SilenceUsage: true,
+	}
+
+	loki.Flags().StringP("namespace", "n", "loki", "The namespace used for installation")
+	loki.Flags().String("version", "", "The version of the loki-stack chart (Default to the latest)")
+	loki.Flags().Bool("grafana", false, "Add loki as a datasource to grafana from the monitoring app")
+	loki.Flags().Bool("update-repo", true, "Update the helm repo")
+
+	loki.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		wireGrafana, _ := command.Flags().GetBool("grafana")
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		sources := []string{}
+		var grafana apps.Resource
+		if wireGrafana {
+			inventory, err := loadInventory()
+			if err != nil {
+				return err
+			}
+
+			var found bool
+			grafana, found = findGrafana(inventory)
+			if !found {
+				return fmt.Errorf("--grafana needs the monitoring app, install it with: k3sup app install monitoring --helm3")
+			}
+
+			datasource, err := buildLokiDatasource(lokiDatasource{
+				GrafanaNamespace: grafana.Namespace,
+				LokiURL:          fmt.Sprintf("http://loki-stack.%s:3100", namespace),
+			})
+			if err != nil {
+				return err
+			}
+
+			datasourceFile, err := writeTempFile("loki_datasource", datasource)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, datasourceFile)
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("loki", "https://grafana.github.io/loki/charts", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("loki", namespace); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "loki/loki-stack", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		outputPath := path.Join(chartPath, "loki-stack/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, nil)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "loki-stack",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("loki", "loki-stack", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "loki-stack",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, append([]string{outputPath}, sources...)...)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("loki", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if wireGrafana {
+			if err := applyManifests("loki", sources...); err != nil {
+				return err
+			}
+
+			// grafana only reads its datasources when it starts
+			err = kubectl("rollout", "restart", "deploy/"+grafana.Name, "--namespace", grafana.Namespace)
+			if err != nil {
+				return fmt.Errorf("unable to restart grafana: %s", err)
+			}
+		}
+
+		if err := recordInstalled("loki", chartVersion(chartPath, "loki-stack")); err != nil {
+			return err
+		}
+
+		logInfo(lokiInstallMsg)
+
+		return nil
+	}
+
+	return loki
+}
+
+// findGrafana returns the grafana Deployment of the monitoring app from the
+// cluster's inventory
+func findGrafana(inventory map[string]apps.InventoryEntry) (apps.Resource, bool) {
+	entry, ok := inventory["monitoring"]
+	if !ok {
+		return apps.Resource{}, false
+	}
+
+	for _, workload := range entry.Workloads {
+		if workload.Kind == "deployment" && strings.HasSuffix(workload.Name, "-grafana") {
+			return workload, true
+		}
+	}
+
+	return apps.Resource{}, false
+}
+
+func buildLokiDatasource(datasource lokiDatasource) ([]byte, error) {
+	tmpl, err := appTemplate("loki/datasource.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, datasource); err != nil {
+		return nil, err
+	}
+
+	return tpl.Bytes(), nil
+}
+
+const lokiInfoMsg = `# Check that promtail is running on each node
+kubectl get pods -n loki
+
+# If you installed with --grafana, choose the Loki datasource in grafana's
+# Explore page and query the logs of a namespace with:
+# {namespace="kube-system"}
+
+# Find out more at:
+# https://grafana.com/docs/loki/latest/`
+
+const lokiInstallMsg = `=======================================================================
+= loki has been installed.                                            =
+=======================================================================
+
+` + lokiInfoMsg + `
+
+` + thanksForUsing
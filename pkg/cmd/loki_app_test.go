@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexellis/k3sup/pkg/apps"
+)
+
+func Test_findGrafana(t *testing.T) {
+	inventory := map[string]apps.InventoryEntry{
+		"monitoring": {
+			Workloads: []apps.Resource{
+				{Kind: "deployment", Name: "kube-prometheus-stack-operator", Namespace: "monitoring"},
+				{Kind: "deployment", Name: "kube-prometheus-stack-grafana", Namespace: "monitoring"},
+			},
+		},
+	}
+
+	grafana, found := findGrafana(inventory)
+	if !found {
+		t.Fatal("want grafana to be found")
+	}
+
+	if grafana.Name != "kube-prometheus-stack-grafana" || grafana.Namespace != "monitoring" {
+		t.Errorf("want the grafana Deployment, got: %v", grafana)
+	}
+
+	if _, found := findGrafana(map[string]apps.InventoryEntry{}); found {
+		t.Errorf("want grafana not found without the monitoring app")
+	}
+}
+
+func Test_buildLokiDatasource(t *testing.T) {
+	templBytes, err := buildLokiDatasource(lokiDatasource{
+		GrafanaNamespace: "monitoring",
+		LokiURL:          "http://loki-stack.loki:3100",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	for _, want := range []string{
+		"  namespace: monitoring\n",
+		"    grafana_datasource: \"1\"\n",
+		"      url: http://loki-stack.loki:3100\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %q", want, got)
+		}
+	}
+}
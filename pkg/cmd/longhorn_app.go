@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallLonghorn() *cobra.Command {
+	var longhorn = &cobra.Command{
+		Use:   "longhorn",
+		Short: "Install longhorn",
+		Long: `Install longhorn for distributed block storage on multi-node clusters.
+Each node needs open-iscsi installed, i.e. apt install -y open-iscsi`,
+		Example:      `  k3sup app install longhorn --default-storage-class`,
+		SilenceUsage: true,
+	}
+
+	longhorn.Flags().String("version", "v1.1.0", "The version of longhorn to install")
+	longhorn.Flags().Bool("default-storage-class", false, "Make longhorn the default StorageClass instead of local-path")
+
+	longhorn.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		arch := getArchitecture()
+		logDebugf("Node architecture: %q\n", arch)
+
+		if arch != "x86_64" && arch != "amd64" && arch != "arm64" && arch != "aarch64" {
+			return fmt.Errorf("This app is not known to work with the %s architecture", arch)
+		}
+
+		version, _ := command.Flags().GetString("version")
+		manifest := fmt.Sprintf("https://raw.githubusercontent.com/longhorn/longhorn/%s/deploy/longhorn.yaml", version)
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return printManifests(command, manifest)
+		}
+
+		if err := applyManifests("longhorn", manifest); err != nil {
+			return err
+		}
+
+		if defaultClass, _ := command.Flags().GetBool("default-storage-class"); defaultClass {
+			if err := setDefaultStorageClass("longhorn", "local-path"); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("longhorn", version); err != nil {
+			return err
+		}
+
+		logInfo(longhornInstallMsg)
+
+		return nil
+	}
+
+	return longhorn
+}
+
+// setDefaultStorageClass marks storageClass as the default, and unmarks
+// previous, such as the local-path class which k3s installs.
+func setDefaultStorageClass(storageClass, previous string) error {
+	res, err := kubectlTask("get", "storageclass", previous, "--ignore-not-found", "--output", "name")
+	if err != nil {
+		return err
+	}
+
+	if len(res.Stdout) > 0 {
+		err := kubectl("annotate", "--overwrite", "storageclass", previous,
+			"storageclass.kubernetes.io/is-default-class=false")
+		if err != nil {
+			return fmt.Errorf("unable to unset %s as the default StorageClass: %s", previous, err)
+		}
+	}
+
+	err = kubectl("annotate", "--overwrite", "storageclass", storageClass,
+		"storageclass.kubernetes.io/is-default-class=true")
+	if err != nil {
+		return fmt.Errorf("unable to set %s as the default StorageClass: %s", storageClass, err)
+	}
+
+	return nil
+}
+
+const longhornInfoMsg = `# Wait for longhorn to start, it can take a few minutes:
+kubectl get pods -n longhorn-system -w
+
+# Request a volume with the "longhorn" StorageClass, or leave it out
+# if you installed with --default-storage-class
+kubectl get storageclass
+
+# Open the longhorn UI
+kubectl port-forward -n longhorn-system svc/longhorn-frontend 8080:80
+
+# Volumes must be removed before longhorn is uninstalled, see:
+# https://longhorn.io/docs/latest/deploy/uninstall/
+
+# Find out more at:
+# https://github.com/longhorn/longhorn`
+
+const longhornInstallMsg = `=======================================================================
+= longhorn has been installed.                                        =
+=======================================================================
+
+` + longhornInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallMetalLB() *cobra.Command {
+	var metallb = &cobra.Command{
+		Use:   "metallb",
+		Short: "Install metallb",
+		Long: `Install metallb in L2 mode to give bare-metal clusters LoadBalancer
+services, with IPs from --address-range. On k3s install the server with
+--no-deploy servicelb first, so that the built-in service load balancer
+does not compete with metallb.`,
+		Example:      `  k3sup app install metallb --address-range 192.168.0.200-192.168.0.220`,
+		SilenceUsage: true,
+	}
+
+	metallb.Flags().String("version", "v0.9.5", "The version of metallb to install")
+	metallb.Flags().String("address-range", "", "The IPs to give to LoadBalancer services, as a range FROM-TO or a CIDR")
+
+	metallb.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		addressRange, _ := command.Flags().GetString("address-range")
+		if err := validateAddressRange(addressRange); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		version, _ := command.Flags().GetString("version")
+		manifests := []string{
+			fmt.Sprintf("https://raw.githubusercontent.com/metallb/metallb/%s/manifests/namespace.yaml", version),
+			fmt.Sprintf("https://raw.githubusercontent.com/metallb/metallb/%s/manifests/metallb.yaml", version),
+		}
+
+		config, err := buildMetalLBConfig(addressRange)
+		if err != nil {
+			return err
+		}
+
+		configFile, err := writeTempFile("metallb_config", config)
+		if err != nil {
+			return err
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return printManifests(command, append(manifests, configFile)...)
+		}
+
+		if err := applyManifests("metallb", manifests[0]); err != nil {
+			return err
+		}
+
+		if err := createMemberlistSecret(); err != nil {
+			return err
+		}
+
+		if err := applyManifests("metallb", manifests[1], configFile); err != nil {
+			return err
+		}
+
+		if err := recordInstalled("metallb", version); err != nil {
+			return err
+		}
+
+		logInfo(metallbInstallMsg)
+
+		return nil
+	}
+
+	return metallb
+}
+
+// validateAddressRange accepts a CIDR such as 192.168.0.200/29, or a range
+// of IPs such as 192.168.0.200-192.168.0.220
+func validateAddressRange(addressRange string) error {
+	if len(addressRange) == 0 {
+		return fmt.Errorf("--address-range is required, i.e. 192.168.0.200-192.168.0.220")
+	}
+
+	if strings.Contains(addressRange, "/") {
+		if _, _, err := net.ParseCIDR(addressRange); err != nil {
+			return fmt.Errorf("invalid --address-range: %s", err)
+		}
+		return nil
+	}
+
+	parts := strings.Split(addressRange, "-")
+	if len(parts) != 2 {
+		return fmt.Errorf("give the --address-range as FROM-TO or a CIDR, not: %q", addressRange)
+	}
+
+	for _, part := range parts {
+		if net.ParseIP(strings.TrimSpace(part)) == nil {
+			return fmt.Errorf("invalid IP in --address-range: %q", part)
+		}
+	}
+
+	return nil
+}
+
+// createMemberlistSecret generates the key metallb's speakers use to talk
+// to each other, an existing key is kept
+func createMemberlistSecret() error {
+	key := make([]byte, 128)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	res, err := kubectlTask("create", "secret", "generic", "memberlist",
+		"--namespace", "metallb-system",
+		"--from-literal=secretkey="+base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 && !strings.Contains(res.Stderr, "AlreadyExists") {
+		return fmt.Errorf("unable to create the memberlist secret: %s", res.Stderr)
+	}
+
+	return recordSecret("metallb", "memberlist", "metallb-system")
+}
+
+func buildMetalLBConfig(addressRange string) ([]byte, error) {
+	tmpl, err := appTemplate("metallb/config.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, addressRange); err != nil {
+		return nil, err
+	}
+
+	return tpl.Bytes(), nil
+}
+
+const metallbInfoMsg = `# LoadBalancer services will now get an IP from your address range:
+kubectl run nginx-1 --image=nginx --port=80 --restart=Always
+kubectl expose deployment nginx-1 --port=80 --type=LoadBalancer
+
+kubectl get svc nginx-1
+
+# Change the address range by installing again with --address-range
+
+# Find out more at:
+# https://metallb.universe.tf`
+
+const metallbInstallMsg = `=======================================================================
+= metallb has been installed.                                         =
+=======================================================================
+
+` + metallbInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,44 @@
+package cmd
+
+import "testing"
+
+func Test_validateAddressRange(t *testing.T) {
+	valid := []string{"192.168.0.200-192.168.0.220", "192.168.0.200/29"}
+	for _, addressRange := range valid {
+		if err := validateAddressRange(addressRange); err != nil {
+			t.Errorf("want %q to be valid, got: %s", addressRange, err)
+		}
+	}
+
+	invalid := []string{"", "192.168.0.200", "192.168.0.200-", "192.168.0.200/40"}
+	for _, addressRange := range invalid {
+		if err := validateAddressRange(addressRange); err == nil {
+			t.Errorf("want %q to be invalid", addressRange)
+		}
+	}
+}
+
+func Test_buildMetalLBConfig(t *testing.T) {
+	want := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: metallb-system
+  name: config
+data:
+  config: |
+    address-pools:
+    - name: default
+      protocol: layer2
+      addresses:
+      - 192.168.0.200-192.168.0.220
+`
+
+	got, err := buildMetalLBConfig("192.168.0.200-192.168.0.220")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != want {
+		t.Errorf("want: %q, got: %q", want, string(got))
+	}
+}
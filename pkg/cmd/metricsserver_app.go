@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path"
 
@@ -21,6 +20,7 @@ func makeInstallMetricsServer() *cobra.Command {
 	}
 
 	metricsServer.Flags().StringP("namespace", "n", "kube-system", "The namespace used for installation")
+	metricsServer.Flags().String("version", "", "The version of the metrics-server chart (Default to the latest)")
 
 	metricsServer.RunE = func(command *cobra.Command, args []string) error {
 		kubeConfigPath := getDefaultKubeconfig()
@@ -29,7 +29,7 @@ func makeInstallMetricsServer() *cobra.Command {
 			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
 		}
 
-		fmt.Printf("Using kubeconfig: %s\n", kubeConfigPath)
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
 
 		userPath, err := config.InitUserDir()
 		if err != nil {
@@ -37,61 +37,129 @@ func makeInstallMetricsServer() *cobra.Command {
 		}
 		namespace, _ := command.Flags().GetString("namespace")
 
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
 		if namespace != "kube-system" {
 			return fmt.Errorf(`to override the "kube-system", install via tiller`)
 		}
 
 		clientArch, clientOS := getClientArch()
 
-		fmt.Printf("Client: %s, %s\n", clientArch, clientOS)
-		log.Printf("User dir established as: %s\n", userPath)
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
 
 		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
 
-		_, err = tryDownloadHelm(userPath, clientArch, clientOS)
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
 		if err != nil {
 			return err
 		}
 
-		err = updateHelmRepos()
+		err = updateHelmRepos(helm3)
 		if err != nil {
 			return err
 		}
 
+		if !dryRun {
+			if err := createNamespace("metrics-server", namespace); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
 		chartPath := path.Join(os.TempDir(), "charts")
-		err = fetchChart(chartPath, "stable/metrics-server")
+		err = fetchChart(chartPath, "stable/metrics-server", version, helm3)
 
 		if err != nil {
 			return err
 		}
 
+		arch := getArchitecture()
+		logDebugf("Node architecture: %q\n", arch)
+
 		overrides := map[string]string{}
 		overrides["args"] = `{--kubelet-insecure-tls,--kubelet-preferred-address-types=InternalIP\,ExternalIP\,Hostname}`
-		fmt.Println("Chart path: ", chartPath)
+		overrides["image.repository"] = getMetricsServerImage(arch)
+		logDebugf("Chart path: %s\n", chartPath)
 		outputPath := path.Join(chartPath, "metrics-server/rendered")
 
-		err = templateChart(chartPath,
-			"metrics-server",
-			namespace,
-			outputPath,
-			"values.yaml",
-			overrides)
-
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
 		if err != nil {
 			return err
 		}
 
-		err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+		if helm3 {
+			err = helm3Install(chartPath,
+				"metrics-server",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("metrics-server", "metrics-server", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath,
+				"metrics-server",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, outputPath)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("metrics-server", outputPath); err != nil {
+				return err
+			}
+		}
 
-		if err != nil {
+		if err := recordInstalled("metrics-server", chartVersion(chartPath, "metrics-server")); err != nil {
 			return err
 		}
 
-		fmt.Println(`=======================================================================
-= metrics-server has been installed.                                  =
-=======================================================================
+		logInfo(metricsServerInstallMsg)
+
+		return nil
+	}
+
+	return metricsServer
+}
+
+// getMetricsServerImage returns the image for the node architecture, the
+// chart defaults to the amd64 image
+func getMetricsServerImage(arch string) string {
+	switch arch {
+	case "arm":
+		return "k8s.gcr.io/metrics-server-arm"
+	case "arm64", "aarch64":
+		return "k8s.gcr.io/metrics-server-arm64"
+	default:
+		return "k8s.gcr.io/metrics-server-amd64"
+	}
+}
 
-# It can take a few minutes for the metrics-server to collect data
+const metricsServerInfoMsg = `# It can take a few minutes for the metrics-server to collect data
 # from the cluster. Try these commands and wait a few moments if
 # no data is showing.
 
@@ -105,12 +173,12 @@ kubectl top node
 
 
 # Find out more at:
-# https://github.com/helm/charts/tree/master/stable/metrics-server
+# https://github.com/helm/charts/tree/master/stable/metrics-server`
 
-Thank you for using k3sup!`)
+const metricsServerInstallMsg = `=======================================================================
+= metrics-server has been installed.                                  =
+=======================================================================
 
-		return nil
-	}
+` + metricsServerInfoMsg + `
 
-	return metricsServer
-}
+` + thanksForUsing
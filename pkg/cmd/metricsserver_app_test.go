@@ -0,0 +1,27 @@
+package cmd
+
+import "testing"
+
+func Test_getMetricsServerImage_arm(t *testing.T) {
+	want := "k8s.gcr.io/metrics-server-arm"
+	got := getMetricsServerImage("arm")
+	if want != got {
+		t.Errorf("image, want: %s, got: %s", want, got)
+	}
+}
+
+func Test_getMetricsServerImage_aarch64(t *testing.T) {
+	want := "k8s.gcr.io/metrics-server-arm64"
+	got := getMetricsServerImage("aarch64")
+	if want != got {
+		t.Errorf("image, want: %s, got: %s", want, got)
+	}
+}
+
+func Test_getMetricsServerImage_amd64(t *testing.T) {
+	want := "k8s.gcr.io/metrics-server-amd64"
+	got := getMetricsServerImage("amd64")
+	if want != got {
+		t.Errorf("image, want: %s, got: %s", want, got)
+	}
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/alexellis/k3sup/pkg/config"
+	"github.com/sethvargo/go-password/password"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallMinio() *cobra.Command {
+	var minio = &cobra.Command{
+		Use:   "minio",
+		Short: "Install minio",
+		Long: `Install minio for S3-compatible object storage. The access and secret keys
+are generated unless given, and are kept when installing again.
+
+Give --domain and --email to expose minio with an Ingress and a TLS
+certificate from Let's Encrypt, which requires cert-manager.`,
+		Example: `  k3sup app install minio --persistence --size 20Gi
+  k3sup app install minio --domain minio.example.com --email admin@example.com`,
+		SilenceUsage: true,
+	}
+
+	minio.Flags().StringP("namespace", "n", "default", "The namespace used for installation")
+	minio.Flags().String("version", "", "The version of the minio chart (Default to the latest)")
+	minio.Flags().String("access-key", "", "The access key for minio, generated when not given")
+	minio.Flags().String("secret-key", "", "The secret key for minio, generated when not given")
+	minio.Flags().Bool("persistence", false, "Store data in a PersistentVolumeClaim, so that it is kept when the Pod restarts")
+	minio.Flags().String("size", "10Gi", "The size of the PersistentVolumeClaim")
+	minio.Flags().Bool("update-repo", true, "Update the helm repo")
+	addIngressFlags(minio)
+	markSensitive(minio, "access-key", "secret-key")
+
+	minio.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		accessKey, _ := command.Flags().GetString("access-key")
+		secretKey, _ := command.Flags().GetString("secret-key")
+		persistence, _ := command.Flags().GetBool("persistence")
+		size, _ := command.Flags().GetString("size")
+
+		if command.Flags().Changed("size") && !persistence {
+			return fmt.Errorf("--size can only be used with --persistence")
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		domain, _ := command.Flags().GetString("domain")
+		var inputData InputData
+		if len(domain) > 0 {
+			inputData, err = ingressFromFlags(command)
+			if err != nil {
+				return err
+			}
+			inputData.IngressName = "minio"
+			inputData.Namespace = namespace
+			inputData.ServiceName = "minio"
+			inputData.ServicePort = 9000
+			inputData.TLSSecret = "minio-tls"
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("minio", namespace); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "stable/minio", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		if !dryRun {
+			if len(accessKey) == 0 {
+				accessKey, err = secretValue(namespace, "minio", "accesskey")
+				if err != nil {
+					return err
+				}
+			}
+			if len(secretKey) == 0 {
+				secretKey, err = secretValue(namespace, "minio", "secretkey")
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(accessKey) == 0 {
+			accessKey, err = password.Generate(20, 5, 0, true, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(secretKey) == 0 {
+			secretKey, err = password.Generate(40, 10, 0, false, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		overrides := map[string]string{}
+		overrides["accessKey"] = accessKey
+		overrides["secretKey"] = secretKey
+		overrides["persistence.enabled"] = strconv.FormatBool(persistence)
+		if persistence {
+			overrides["persistence.size"] = size
+		}
+
+		outputPath := path.Join(chartPath, "minio/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "minio",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("minio", "minio", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "minio",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				sources := []string{outputPath}
+				if len(domain) > 0 {
+					ingressYaml, err := buildYaml(inputData)
+					if err != nil {
+						return err
+					}
+
+					ingressFile, err := writeTempFile("minio", ingressYaml)
+					if err != nil {
+						return err
+					}
+					sources = append(sources, ingressFile)
+				}
+				return printManifests(command, sources...)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("minio", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if len(domain) > 0 {
+			if err := applyIngress(command, "minio", inputData); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("minio", chartVersion(chartPath, "minio")); err != nil {
+			return err
+		}
+
+		logInfo(minioInstallMsg)
+		logInfof(`# Your keys are:
+#   access key: %s
+#   secret key: %s
+`, accessKey, secretKey)
+
+		return nil
+	}
+
+	return minio
+}
+
+const minioInfoMsg = `# Forward the minio port to your machine
+kubectl port-forward svc/minio 9000:9000 &
+
+# Get the keys
+export ACCESSKEY=$(kubectl get secret minio -o jsonpath="{.data.accesskey}" | base64 --decode)
+export SECRETKEY=$(kubectl get secret minio -o jsonpath="{.data.secretkey}" | base64 --decode)
+
+# Get the minio client, mc, then add the server and make a bucket
+mc config host add minio http://127.0.0.1:9000 $ACCESSKEY $SECRETKEY
+mc mb minio/k3sup
+
+# Give -n to kubectl when installed with --namespace, use your domain
+# instead of port-forward when installed with --domain
+
+# Find out more at:
+# https://docs.min.io`
+
+const minioInstallMsg = `=======================================================================
+= minio has been installed.                                           =
+=======================================================================
+
+` + minioInfoMsg + `
+
+` + thanksForUsing
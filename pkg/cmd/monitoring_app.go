@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/alexellis/k3sup/pkg/config"
+	"github.com/sethvargo/go-password/password"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallMonitoring() *cobra.Command {
+	var monitoring = &cobra.Command{
+		Use:   "monitoring",
+		Short: "Install prometheus and grafana",
+		Long: `Install prometheus, alertmanager and grafana from the kube-prometheus-stack
+chart, which needs helm 3. A password is generated for grafana's admin user,
+and kept when installing again.
+
+The admission webhooks are turned off with --arm-images, as their image is
+only published for amd64. It is set by default for arm and arm64 nodes.
+
+Give --domain and --email to expose grafana with an Ingress and a TLS
+certificate from Let's Encrypt, which requires cert-manager.`,
+		Example: `  k3sup app install monitoring --helm3
+  k3sup app install monitoring --helm3 --domain grafana.example.com --email admin@example.com`,
+		SilenceUsage: true,
+	}
+
+	monitoring.Flags().StringP("namespace", "n", "monitoring", "The namespace used for installation")
+	monitoring.Flags().String("version", "", "The version of the kube-prometheus-stack chart (Default to the latest)")
+	monitoring.Flags().Bool("arm-images", false, "Only use images which are published for arm and arm64 (Default to the node architecture)")
+	monitoring.Flags().Bool("update-repo", true, "Update the helm repo")
+	addIngressFlags(monitoring)
+
+	monitoring.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		if _, err := dryRunFromFlags(command); err != nil {
+			return err
+		}
+
+		helm3, _ := command.Flags().GetBool("helm3")
+		if !helm3 {
+			return fmt.Errorf("kube-prometheus-stack is a helm 3 chart with its CRDs in crds/, install it with --helm3")
+		}
+
+		namespace, _ := command.Flags().GetString("namespace")
+
+		domain, _ := command.Flags().GetString("domain")
+		var inputData InputData
+		var err error
+		if len(domain) > 0 {
+			inputData, err = ingressFromFlags(command)
+			if err != nil {
+				return err
+			}
+			inputData.IngressName = "grafana"
+			inputData.Namespace = namespace
+			inputData.ServiceName = "kube-prometheus-stack-grafana"
+			inputData.ServicePort = 80
+			inputData.TLSSecret = "grafana-tls"
+		}
+
+		arch := getArchitecture()
+		logDebugf("Node architecture: %q\n", arch)
+
+		armImages := arch == "arm" || arch == "arm64" || arch == "aarch64"
+		if command.Flags().Changed("arm-images") {
+			armImages, _ = command.Flags().GetBool("arm-images")
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("prometheus-community", "https://prometheus-community.github.io/helm-charts", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := createNamespace("monitoring", namespace); err != nil {
+			return err
+		}
+
+		version, _ := command.Flags().GetString("version")
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "prometheus-community/kube-prometheus-stack", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		pass, err := secretValue(namespace, "kube-prometheus-stack-grafana", "admin-password")
+		if err != nil {
+			return err
+		}
+
+		if len(pass) == 0 {
+			pass, err = password.Generate(25, 10, 0, false, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		overrides, userValues, err := chartValuesFromFlags(command, monitoringOverrides(pass, armImages))
+		if err != nil {
+			return err
+		}
+
+		err = helm3Install(chartPath, "kube-prometheus-stack",
+			namespace,
+			"values.yaml",
+			overrides, userValues)
+
+		if err != nil {
+			return err
+		}
+
+		if err := recordRelease("monitoring", "kube-prometheus-stack", namespace); err != nil {
+			return err
+		}
+
+		if len(domain) > 0 {
+			if err := applyIngress(command, "monitoring", inputData); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("monitoring", chartVersion(chartPath, "kube-prometheus-stack")); err != nil {
+			return err
+		}
+
+		logInfo(monitoringInstallMsg)
+
+		return nil
+	}
+
+	return monitoring
+}
+
+// monitoringOverrides turns off scraping of the control plane components
+// which k3s runs within its own process, so they have no endpoints
+func monitoringOverrides(grafanaPassword string, armImages bool) map[string]string {
+	overrides := map[string]string{
+		"grafana.adminPassword":         grafanaPassword,
+		"kubeEtcd.enabled":              "false",
+		"kubeControllerManager.enabled": "false",
+		"kubeScheduler.enabled":         "false",
+		"kubeProxy.enabled":             "false",
+	}
+
+	if armImages {
+		overrides["prometheusOperator.admissionWebhooks.enabled"] = "false"
+		overrides["prometheusOperator.tls.enabled"] = "false"
+	}
+
+	return overrides
+}
+
+const monitoringInfoMsg = `# Get the password for grafana's admin user
+kubectl get secret -n monitoring kube-prometheus-stack-grafana \
+  -o jsonpath="{.data.admin-password}" | base64 --decode; echo
+
+# Forward grafana to your machine, then open http://127.0.0.1:3000
+kubectl port-forward -n monitoring svc/kube-prometheus-stack-grafana 3000:80 &
+
+# Forward prometheus to your machine, then open http://127.0.0.1:9090
+kubectl port-forward -n monitoring svc/kube-prometheus-stack-prometheus 9090:9090 &
+
+# Find out more at:
+# https://github.com/prometheus-community/helm-charts/tree/main/charts/kube-prometheus-stack`
+
+const monitoringInstallMsg = `=======================================================================
+= monitoring has been installed.                                      =
+=======================================================================
+
+` + monitoringInfoMsg + `
+
+` + thanksForUsing
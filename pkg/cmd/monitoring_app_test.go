@@ -0,0 +1,19 @@
+package cmd
+
+import "testing"
+
+func Test_monitoringOverrides_ArmImagesTurnOffWebhooks(t *testing.T) {
+	overrides := monitoringOverrides("secret", false)
+	if _, ok := overrides["prometheusOperator.admissionWebhooks.enabled"]; ok {
+		t.Errorf("want admission webhooks left on for amd64")
+	}
+
+	overrides = monitoringOverrides("secret", true)
+	if got := overrides["prometheusOperator.admissionWebhooks.enabled"]; got != "false" {
+		t.Errorf("want admission webhooks turned off, got: %q", got)
+	}
+
+	if got := overrides["grafana.adminPassword"]; got != "secret" {
+		t.Errorf("want grafana password set, got: %q", got)
+	}
+}
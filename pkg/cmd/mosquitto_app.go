@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/sethvargo/go-password/password"
+	"github.com/spf13/cobra"
+)
+
+// mosquittoConfig holds the values for templates/mosquitto/mosquitto.yaml
+type mosquittoConfig struct {
+	Namespace   string
+	Version     string
+	ServiceType string
+	NodePort    int
+	Auth        bool
+	Username    string
+	Password    string
+	Passwd      string
+}
+
+func makeInstallMosquitto() *cobra.Command {
+	var mosquitto = &cobra.Command{
+		Use:   "mosquitto",
+		Short: "Install mosquitto",
+		Long: `Install the mosquitto MQTT broker. Clients connect without credentials
+unless --auth is given, then the password for --username is generated and
+kept in the mosquitto-auth secret when installing again.
+
+The broker is only reachable within the cluster by default, give
+--service-type NodePort or LoadBalancer for devices outside of it.`,
+		Example: `  k3sup app install mosquitto
+  k3sup app install mosquitto --auth --service-type NodePort --node-port 31883`,
+		SilenceUsage: true,
+	}
+
+	mosquitto.Flags().StringP("namespace", "n", "mosquitto", "The namespace used for installation")
+	mosquitto.Flags().String("version", "1.6.14", "The version of mosquitto to install")
+	mosquitto.Flags().Bool("auth", false, "Require clients to log in with a username and password")
+	mosquitto.Flags().String("username", "mqtt", "The username for clients when --auth is given")
+	mosquitto.Flags().String("password", "", "The password for clients when --auth is given, generated when not given")
+	mosquitto.Flags().String("service-type", "ClusterIP", "The type of the broker's Service, ClusterIP, NodePort or LoadBalancer")
+	mosquitto.Flags().Int("node-port", 0, "The port to expose on each node with --service-type NodePort, otherwise one is allocated")
+	markSensitive(mosquitto, "password")
+
+	mosquitto.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		config := mosquittoConfig{}
+		config.Namespace, _ = command.Flags().GetString("namespace")
+		config.Version, _ = command.Flags().GetString("version")
+		config.Auth, _ = command.Flags().GetBool("auth")
+		config.Username, _ = command.Flags().GetString("username")
+		config.Password, _ = command.Flags().GetString("password")
+		config.ServiceType, _ = command.Flags().GetString("service-type")
+		config.NodePort, _ = command.Flags().GetInt("node-port")
+
+		if config.ServiceType != "ClusterIP" && config.ServiceType != "NodePort" && config.ServiceType != "LoadBalancer" {
+			return fmt.Errorf("give the --service-type as ClusterIP, NodePort or LoadBalancer, not: %q", config.ServiceType)
+		}
+
+		if config.NodePort != 0 && config.ServiceType != "NodePort" {
+			return fmt.Errorf("--node-port can only be used with --service-type NodePort")
+		}
+
+		if !config.Auth && (command.Flags().Changed("username") || command.Flags().Changed("password")) {
+			return fmt.Errorf("--username and --password can only be used with --auth")
+		}
+
+		dryRun, _ := command.Flags().GetBool("dry-run")
+
+		if config.Auth {
+			var err error
+			if len(config.Password) == 0 && !dryRun {
+				config.Password, err = secretValue(config.Namespace, "mosquitto-auth", "password")
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(config.Password) == 0 {
+				config.Password, err = password.Generate(25, 10, 0, false, true)
+				if err != nil {
+					return err
+				}
+			}
+
+			config.Passwd, err = mosquittoPasswd(config.Username, config.Password)
+			if err != nil {
+				return err
+			}
+		}
+
+		manifest, err := buildMosquittoManifest(config)
+		if err != nil {
+			return err
+		}
+
+		manifestFile, err := writeTempFile("mosquitto", manifest)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			return printManifests(command, manifestFile)
+		}
+
+		if err := createNamespace("mosquitto", config.Namespace); err != nil {
+			return err
+		}
+
+		if err := applyManifests("mosquitto", manifestFile); err != nil {
+			return err
+		}
+
+		if err := recordInstalled("mosquitto", config.Version); err != nil {
+			return err
+		}
+
+		logInfo(mosquittoInstallMsg)
+		if config.Auth {
+			logInfof(`# Connect with:
+#   username: %s
+#   password: %s
+`, config.Username, config.Password)
+		}
+
+		return nil
+	}
+
+	return mosquitto
+}
+
+// mosquittoPasswd returns an entry for mosquitto's password_file, in the
+// format written by mosquitto_passwd: a SHA-512 digest of the password
+// followed by a random salt
+func mosquittoPasswd(username, pass string) (string, error) {
+	if len(username) == 0 || strings.Contains(username, ":") {
+		return "", fmt.Errorf("--username must be set and cannot contain a colon")
+	}
+
+	salt := make([]byte, 12)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	return mosquittoPasswdWithSalt(username, pass, salt), nil
+}
+
+func mosquittoPasswdWithSalt(username, pass string, salt []byte) string {
+	digest := sha512.Sum512(append([]byte(pass), salt...))
+
+	return fmt.Sprintf("%s:$6$%s$%s", username,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(digest[:]))
+}
+
+func buildMosquittoManifest(config mosquittoConfig) ([]byte, error) {
+	tmpl, err := appTemplate("mosquitto/mosquitto.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl bytes.Buffer
+	if err := tmpl.Execute(&tpl, config); err != nil {
+		return nil, err
+	}
+
+	return tpl.Bytes(), nil
+}
+
+const mosquittoInfoMsg = `# Subscribe and publish from within the cluster
+kubectl run -n mosquitto mqtt-sub --rm -it --restart=Never \
+  --image eclipse-mosquitto:1.6.14 -- mosquitto_sub -h mosquitto -t test
+
+kubectl run -n mosquitto mqtt-pub --rm -it --restart=Never \
+  --image eclipse-mosquitto:1.6.14 -- mosquitto_pub -h mosquitto -t test -m hello
+
+# Add -u and -P when installed with --auth, get the password with:
+kubectl get secret -n mosquitto mosquitto-auth \
+  -o jsonpath="{.data.password}" | base64 --decode; echo
+
+# Find the port for devices outside of the cluster with:
+kubectl get svc -n mosquitto mosquitto
+
+# Find out more at:
+# https://mosquitto.org/documentation/`
+
+const mosquittoInstallMsg = `=======================================================================
+= mosquitto has been installed.                                       =
+=======================================================================
+
+` + mosquittoInfoMsg + `
+
+` + thanksForUsing
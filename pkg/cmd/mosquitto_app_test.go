@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_mosquittoPasswdWithSalt(t *testing.T) {
+	got := mosquittoPasswdWithSalt("mqtt", "secret", []byte("0123456789ab"))
+
+	want := "mqtt:$6$MDEyMzQ1Njc4OWFi$"
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("want prefix %q, got: %q", want, got)
+	}
+
+	if got != mosquittoPasswdWithSalt("mqtt", "secret", []byte("0123456789ab")) {
+		t.Errorf("want the same entry for the same salt")
+	}
+}
+
+func Test_mosquittoPasswd_RejectsColon(t *testing.T) {
+	if _, err := mosquittoPasswd("a:b", "secret"); err == nil {
+		t.Errorf("want error for a username with a colon")
+	}
+}
+
+func Test_buildMosquittoManifest_WithAuth(t *testing.T) {
+	templBytes, err := buildMosquittoManifest(mosquittoConfig{
+		Namespace:   "mosquitto",
+		Version:     "1.6.14",
+		ServiceType: "NodePort",
+		NodePort:    31883,
+		Auth:        true,
+		Username:    "mqtt",
+		Password:    "secret",
+		Passwd:      "mqtt:$6$salt$hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	for _, want := range []string{
+		"        image: eclipse-mosquitto:1.6.14\n",
+		"    allow_anonymous false\n",
+		"  name: mosquitto-auth\n",
+		"  type: NodePort\n",
+		"    nodePort: 31883\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %q", want, got)
+		}
+	}
+}
+
+func Test_buildMosquittoManifest_Anonymous(t *testing.T) {
+	templBytes, err := buildMosquittoManifest(mosquittoConfig{Namespace: "mosquitto", ServiceType: "ClusterIP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	if !strings.Contains(got, "    allow_anonymous true\n") {
+		t.Errorf("want anonymous access, got: %q", got)
+	}
+
+	if strings.Contains(got, "mosquitto-auth") || strings.Contains(got, "nodePort") {
+		t.Errorf("want no secret or nodePort, got: %q", got)
+	}
+}
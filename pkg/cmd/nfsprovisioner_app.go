@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallNfsProvisioner() *cobra.Command {
+	var nfsProvisioner = &cobra.Command{
+		Use:   "nfs-provisioner",
+		Short: "Install nfs-client-provisioner",
+		Long: `Install nfs-client-provisioner to create PersistentVolumes on demand from an
+NFS export, i.e. on a NAS. Each node needs an NFS client installed, i.e.
+apt install -y nfs-common`,
+		Example:      `  k3sup app install nfs-provisioner --server 192.168.0.10 --path /export`,
+		SilenceUsage: true,
+	}
+
+	nfsProvisioner.Flags().StringP("namespace", "n", "default", "The namespace used for installation")
+	nfsProvisioner.Flags().String("version", "", "The version of the nfs-client-provisioner chart (Default to the latest)")
+	nfsProvisioner.Flags().String("server", "", "The IP or hostname of the NFS server")
+	nfsProvisioner.Flags().String("path", "", "The path of the export on the NFS server, i.e. /export")
+	nfsProvisioner.Flags().String("storage-class", "nfs-client", "The name of the StorageClass to create")
+	nfsProvisioner.Flags().Bool("default-storage-class", false, "Make the StorageClass the default instead of local-path")
+	nfsProvisioner.Flags().Bool("update-repo", true, "Update the helm repo")
+
+	nfsProvisioner.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		server, _ := command.Flags().GetString("server")
+		exportPath, _ := command.Flags().GetString("path")
+		storageClass, _ := command.Flags().GetString("storage-class")
+		defaultClass, _ := command.Flags().GetBool("default-storage-class")
+
+		if len(server) == 0 {
+			return fmt.Errorf("--server is required, give the IP or hostname of the NFS server")
+		}
+
+		if !strings.HasPrefix(exportPath, "/") {
+			return fmt.Errorf("--path is required, give the absolute path of the export i.e. /export")
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		arch := getArchitecture()
+		logDebugf("Node architecture: %q\n", arch)
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("nfs-provisioner", namespace); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "stable/nfs-client-provisioner", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		overrides := map[string]string{}
+		overrides["nfs.server"] = server
+		overrides["nfs.path"] = exportPath
+		overrides["storageClass.name"] = storageClass
+		overrides["storageClass.defaultClass"] = strconv.FormatBool(defaultClass)
+		overrides["image.repository"] = getNfsProvisionerImage(arch)
+
+		outputPath := path.Join(chartPath, "nfs-client-provisioner/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "nfs-client-provisioner",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("nfs-provisioner", "nfs-client-provisioner", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "nfs-client-provisioner",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, outputPath)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("nfs-provisioner", outputPath); err != nil {
+				return err
+			}
+		}
+
+		// The chart marks its class as the default, but does not unmark
+		// the local-path class which k3s installs
+		if defaultClass {
+			if err := setDefaultStorageClass(storageClass, "local-path"); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("nfs-provisioner", chartVersion(chartPath, "nfs-client-provisioner")); err != nil {
+			return err
+		}
+
+		logInfo(nfsProvisionerInstallMsg)
+
+		return nil
+	}
+
+	return nfsProvisioner
+}
+
+// getNfsProvisionerImage returns the image for the node architecture, the
+// chart defaults to the amd64 image
+func getNfsProvisionerImage(arch string) string {
+	switch arch {
+	case "arm", "arm64", "aarch64":
+		return "quay.io/external_storage/nfs-client-provisioner-arm"
+	default:
+		return "quay.io/external_storage/nfs-client-provisioner"
+	}
+}
+
+const nfsProvisionerInfoMsg = `# Request a volume with the "nfs-client" StorageClass, or leave it out
+# if you installed with --default-storage-class
+kubectl get storageclass
+
+# Each volume is a folder within the export, which is archived rather
+# than deleted when the PersistentVolumeClaim is removed
+
+# Find out more at:
+# https://github.com/helm/charts/tree/master/stable/nfs-client-provisioner`
+
+const nfsProvisionerInstallMsg = `=======================================================================
+= nfs-client-provisioner has been installed.                          =
+=======================================================================
+
+` + nfsProvisionerInfoMsg + `
+
+` + thanksForUsing
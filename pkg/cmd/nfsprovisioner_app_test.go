@@ -0,0 +1,17 @@
+package cmd
+
+import "testing"
+
+func Test_getNfsProvisionerImage(t *testing.T) {
+	cases := map[string]string{
+		"arm":     "quay.io/external_storage/nfs-client-provisioner-arm",
+		"aarch64": "quay.io/external_storage/nfs-client-provisioner-arm",
+		"amd64":   "quay.io/external_storage/nfs-client-provisioner",
+	}
+
+	for arch, want := range cases {
+		if got := getNfsProvisionerImage(arch); got != want {
+			t.Errorf("%s, want: %s, got: %s", arch, want, got)
+		}
+	}
+}
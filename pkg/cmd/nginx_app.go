@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path"
 
@@ -13,16 +12,19 @@ import (
 
 func makeInstallNginx() *cobra.Command {
 	var nginx = &cobra.Command{
-		Use:          "nginx-ingress",
-		Short:        "Install nginx-ingress",
-		Long:         `Install nginx-ingress. This app can be installed with Host networking for cases where an external LB is not available. please see the --host-mode flag and the nginx-ingress docs for more info`,
+		Use:   "nginx-ingress",
+		Short: "Install nginx-ingress",
+		Long: `Install nginx-ingress. This app can be installed with Host networking for cases where an external LB is not available. please see the --host-mode flag and the nginx-ingress docs for more info.
+
+With --host-mode a DaemonSet binds to ports 80 and 443 on every node, so on k3s install the server with --no-deploy traefik first. Otherwise a LoadBalancer service is created.`,
 		Example:      `  k3sup app install nginx-ingress --namespace default`,
 		SilenceUsage: true,
 	}
 
 	nginx.Flags().StringP("namespace", "n", "default", "The namespace used for installation")
+	nginx.Flags().String("version", "", "The version of the nginx-ingress chart (Default to the latest)")
 	nginx.Flags().Bool("update-repo", true, "Update the helm repo")
-	nginx.Flags().Bool("host-mode", false, "If we should install nginx-ingress in host mode.")
+	nginx.Flags().Bool("host-mode", false, "Run a DaemonSet with host ports 80 and 443 instead of using a LoadBalancer service, ideal for bare-metal k3s")
 
 	nginx.RunE = func(command *cobra.Command, args []string) error {
 		kubeConfigPath := getDefaultKubeconfig()
@@ -33,7 +35,7 @@ func makeInstallNginx() *cobra.Command {
 
 		updateRepo, _ := nginx.Flags().GetBool("update-repo")
 
-		fmt.Printf("Using kubeconfig: %s\n", kubeConfigPath)
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
 
 		userPath, err := config.InitUserDir()
 		if err != nil {
@@ -41,31 +43,45 @@ func makeInstallNginx() *cobra.Command {
 		}
 		namespace, _ := command.Flags().GetString("namespace")
 
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
 		if namespace != "default" {
 			return fmt.Errorf(`to override the "default", install via tiller`)
 		}
 
 		clientArch, clientOS := getClientArch()
 
-		fmt.Printf("Client: %s, %s\n", clientArch, clientOS)
-		log.Printf("User dir established as: %s\n", userPath)
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
 
 		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
 
-		_, err = tryDownloadHelm(userPath, clientArch, clientOS)
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
 		if err != nil {
 			return err
 		}
 
 		if updateRepo {
-			err = updateHelmRepos()
+			err = updateHelmRepos(helm3)
 			if err != nil {
 				return err
 			}
 		}
 
+		if !dryRun {
+			if err := createNamespace("nginx-ingress", namespace); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
 		chartPath := path.Join(os.TempDir(), "charts")
-		err = fetchChart(chartPath, "stable/nginx-ingress")
+		err = fetchChart(chartPath, "stable/nginx-ingress", version, helm3)
 
 		if err != nil {
 			return err
@@ -77,55 +93,93 @@ func makeInstallNginx() *cobra.Command {
 		if flagErr != nil {
 			return flagErr
 		}
+		overrides["controller.service.type"] = "LoadBalancer"
 		if hostMode {
-			fmt.Println("Running in host networking mode")
+			logInfo("Running in host networking mode")
 			overrides["controller.hostNetwork"] = "true"
 			overrides["controller.daemonset.useHostPort"] = "true"
-			overrides["dnsPolicy"] = "ClusterFirstWithHostNet"
+			overrides["controller.dnsPolicy"] = "ClusterFirstWithHostNet"
 			overrides["controller.kind"] = "DaemonSet"
+			overrides["controller.service.type"] = "ClusterIP"
 		}
-		fmt.Println("Chart path: ", chartPath)
+		logDebugf("Chart path: %s\n", chartPath)
 
 		outputPath := path.Join(chartPath, "nginx-ingress/rendered")
 
-		ns := "default"
-		err = templateChart(chartPath,
-			"nginx-ingress",
-			ns,
-			outputPath,
-			"values.yaml",
-			overrides)
-
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
 		if err != nil {
 			return err
 		}
 
-		err = kubectl("apply", "-R", "-f", outputPath)
+		ns := "default"
+		if helm3 {
+			err = helm3Install(chartPath,
+				"nginx-ingress",
+				ns,
+				"values.yaml",
+				overrides, userValues)
 
-		if err != nil {
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("nginx-ingress", "nginx-ingress", ns); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath,
+				"nginx-ingress",
+				ns,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, outputPath)
+			}
+
+			err = kubectl("apply", "-R", "-f", outputPath)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("nginx-ingress", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("nginx-ingress", chartVersion(chartPath, "nginx-ingress")); err != nil {
 			return err
 		}
 
-		fmt.Println(`=======================================================================
-= nginx-ingress has been installed.                                   =
-=======================================================================
+		logInfo(nginxIngressInstallMsg)
+
+		return nil
+	}
 
+	return nginx
+}
 
-# If you're using a local environment such as "minikube" or "KinD",
+const nginxIngressInfoMsg = `# If you're using a local environment such as "minikube" or "KinD",
 # then try the inlets operator with "k3sup app install inlets-operator"
 
-# If you're using a managed Kubernetes service, then you'll find 
+# If you're using a managed Kubernetes service, then you'll find
 # your LoadBalancer's IP under "EXTERNAL-IP" via:
 
 kubectl get svc nginx-ingress-controller
 
 # Find out more at:
-# https://github.com/helm/charts/tree/master/stable/nginx-ingress
+# https://github.com/helm/charts/tree/master/stable/nginx-ingress`
 
-Thank you for using k3sup!`)
+const nginxIngressInstallMsg = `=======================================================================
+= nginx-ingress has been installed.                                   =
+=======================================================================
 
-		return nil
-	}
+` + nginxIngressInfoMsg + `
 
-	return nginx
-}
+` + thanksForUsing
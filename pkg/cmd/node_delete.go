@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func MakeNode() *cobra.Command {
+	var command = &cobra.Command{
+		Use:          "node",
+		Short:        "Manage the nodes of a cluster",
+		Long:         `Manage the nodes of a cluster, through its kubeconfig and via SSH.`,
+		Example:      `  k3sup node delete --ip 192.168.0.101 --kubeconfig ./kubeconfig`,
+		SilenceUsage: false,
+	}
+
+	command.AddCommand(makeNodeDelete())
+
+	return command
+}
+
+func makeNodeDelete() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "delete",
+		Short: "Drain a node, uninstall k3s from it and remove it from the cluster",
+		Long: `Decommission a node: it is cordoned and drained through the kubeconfig,
+which respects PodDisruptionBudgets, then k3s is uninstalled via SSH and the
+Node object is deleted from the cluster.
+
+To delete a server, give a kubeconfig for one of the other servers, as the
+API of the server being deleted goes away with it.`,
+		Example: `  k3sup node delete --ip 192.168.0.101 --kubeconfig ./kubeconfig
+  k3sup node delete --ip 192.168.0.102 --node-name worker-2 --purge`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+	command.Flags().String("kubeconfig", "kubeconfig", "Local path to the kubeconfig for the cluster")
+	command.Flags().String("node-name", "", "The name of the node in the cluster (Default to its hostname)")
+	command.Flags().Duration("timeout", 5*time.Minute, "How long to wait for the node to be drained")
+	command.Flags().Bool("purge", false, "Also remove /var/lib/rancher and /etc/rancher from the node")
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		opts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+		sudoPrefix := escalationPrefix(escalation)
+		kubeconfigPath, _ := command.Flags().GetString("kubeconfig")
+		kubeconfigPath = expandPath(kubeconfigPath)
+		node, _ := command.Flags().GetString("node-name")
+		timeout, _ := command.Flags().GetDuration("timeout")
+		purge, _ := command.Flags().GetBool("purge")
+
+		operator, err := connectSSH(opts)
+		if err != nil {
+			return err
+		}
+		defer operator.Close()
+
+		if len(node) == 0 {
+			if node, err = k3sNodeName(operator, ""); err != nil {
+				return err
+			}
+		}
+
+		if res, err := kubectlTask("--kubeconfig", kubeconfigPath, "get", "node", node); err != nil || res.ExitCode != 0 {
+			return fmt.Errorf("node %s was not found in the cluster of %s, give its name with --node-name", node, kubeconfigPath)
+		}
+
+		script, err := findUninstallScript(operator)
+		if err != nil {
+			return err
+		}
+		if len(script) == 0 {
+			return fmt.Errorf("k3s does not appear to be installed on %s, no uninstall script found", opts.Host)
+		}
+
+		if script == serverUninstallScript {
+			server, err := kubeconfigServer(kubeconfigPath)
+			if err != nil {
+				return err
+			}
+			if sameHost(server, opts.Host) {
+				return fmt.Errorf("%s connects to %s, which is the server being deleted, give a kubeconfig for another server", kubeconfigPath, opts.Host)
+			}
+		}
+
+		if err := confirm("Delete node %s from the cluster, draining it and uninstalling k3s from %s?", node, opts.Host); err != nil {
+			return err
+		}
+
+		logInfof("Draining node: %s\n", node)
+		if err := kubectl("--kubeconfig", kubeconfigPath, "drain", node, "--ignore-daemonsets", "--delete-local-data",
+			"--timeout", timeout.String()); err != nil {
+			return fmt.Errorf("unable to drain %s, it is still cordoned: %s", node, err)
+		}
+
+		uninstallCommand := sudoPrefix + script
+		if purge {
+			uninstallCommand += " && " + sudoPrefix + "rm -rf /var/lib/rancher /etc/rancher"
+		}
+		if err := runInstallerWithProgress(operator, fmt.Sprintf("Uninstalling k3s from %s", opts.Host), func() error {
+			logDebugf("ssh: %s\n", uninstallCommand)
+			_, err := operator.Execute(uninstallCommand)
+			return err
+		}); err != nil {
+			return fmt.Errorf("unable to uninstall k3s from %s, it is still cordoned: %s", opts.Host, err)
+		}
+
+		// Deleted once k3s is gone, as a running kubelet would register it again
+		if err := kubectl("--kubeconfig", kubeconfigPath, "delete", "node", node); err != nil {
+			return fmt.Errorf("unable to delete node %s, k3s has been uninstalled: %s", node, err)
+		}
+
+		logInfof("Node %s has been deleted\n", node)
+
+		return nil
+	}
+
+	return command
+}
+
+// kubeconfigServer returns the address of the API server in the current
+// context of the kubeconfig
+func kubeconfigServer(kubeconfigPath string) (string, error) {
+	res, err := kubectlTask("--kubeconfig", kubeconfigPath, "config", "view", "--minify",
+		"--output", "jsonpath={.clusters[0].cluster.server}")
+	if err != nil {
+		return "", err
+	}
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("unable to read the server from %s: %s", kubeconfigPath, strings.TrimSpace(res.Stderr))
+	}
+
+	return strings.TrimSpace(res.Stdout), nil
+}
+
+// sameHost reports whether the server URL from a kubeconfig is host
+func sameHost(server, host string) bool {
+	u, err := url.Parse(server)
+	if err != nil {
+		return false
+	}
+
+	if u.Hostname() == host {
+		return true
+	}
+
+	ips, err := net.LookupHost(u.Hostname())
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		if ip == host {
+			return true
+		}
+	}
+	return false
+}
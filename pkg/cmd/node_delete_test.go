@@ -0,0 +1,23 @@
+package cmd
+
+import "testing"
+
+func Test_sameHost(t *testing.T) {
+	cases := []struct {
+		server string
+		host   string
+		want   bool
+	}{
+		{"https://192.168.0.100:6443", "192.168.0.100", true},
+		{"https://192.168.0.100:6443", "192.168.0.101", false},
+		{"https://[::1]:6443", "::1", true},
+		{"https://localhost:6443", "127.0.0.1", true},
+		{"://not a url", "192.168.0.100", false},
+	}
+
+	for _, c := range cases {
+		if got := sameHost(c.server, c.host); got != c.want {
+			t.Errorf("%s and %s: want %v, got %v", c.server, c.host, c.want, got)
+		}
+	}
+}
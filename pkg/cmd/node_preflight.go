@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// minDiskKB is the free space under /var/lib which k3s needs for its binaries
+// and the first images
+const minDiskKB = 1024 * 1024
+
+// Status of a nodeCheck, only a failure stops an install
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// nodeCheck is the result of one of the checks made of a node before k3s is
+// installed on it
+type nodeCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+func MakePreflight() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "preflight",
+		Short: "Check that a node can run k3s before installing",
+		Long: `Check that a node can run k3s via SSH, without changing anything on it. The
+OS and architecture, kernel modules, memory cgroup, free disk space, ports
+and time sync are checked. The same checks are made by install and join
+unless --skip-preflight is given.`,
+		Example: `  k3sup preflight --ip 192.168.0.100
+  k3sup preflight --ip 192.168.0.101 --agent`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+	command.Flags().Bool("agent", false, "Check the node as an agent, which does not serve the Kubernetes API on port 6443")
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		opts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		agent, _ := command.Flags().GetBool("agent")
+
+		operator, err := connectSSH(opts)
+		if err != nil {
+			return err
+		}
+
+		defer operator.Close()
+
+		_, err = preflightNode(operator, opts.Host, !agent)
+		if err != nil {
+			return err
+		}
+
+		logInfof("All checks passed for %s\n", opts.Host)
+		return nil
+	}
+
+	return command
+}
+
+// preflightNode runs the checks of the node at host and prints them, it
+// returns the node's architecture as from remoteArch, or an error when a
+// check failed
+func preflightNode(operator kssh.Operator, host string, server bool) (string, error) {
+	arch, checks := nodeChecks(operator, server)
+
+	failed := []string{}
+	for _, check := range checks {
+		logInfof("Pre-flight [%s] %s: %s\n", check.Status, check.Name, check.Detail)
+		if check.Status == checkFail {
+			failed = append(failed, check.Name)
+		}
+	}
+
+	updateResult(func(r *commandResult) {
+		r.Checks = append(r.Checks, checks...)
+	})
+
+	if len(failed) > 0 {
+		return "", fmt.Errorf("pre-flight checks failed for %s: %s, fix them or give --skip-preflight", host, strings.Join(failed, ", "))
+	}
+
+	return arch, nil
+}
+
+// nodeChecks checks the node, the platform is checked first as the other
+// checks need a Linux node
+func nodeChecks(operator kssh.Operator, server bool) (string, []nodeCheck) {
+	arch, err := remoteArch(operator)
+	if err != nil {
+		return "", []nodeCheck{{Name: "platform", Status: checkFail, Detail: err.Error()}}
+	}
+
+	checks := []nodeCheck{
+		{Name: "platform", Status: checkPass, Detail: "Linux " + arch},
+		checkKernelModules(operator),
+		checkMemoryCgroup(operator),
+		checkDiskSpace(operator),
+		checkPorts(operator, server),
+		checkTimeSync(operator),
+	}
+
+	return arch, checks
+}
+
+// kernelModules are needed by containerd and flannel, a module may be built
+// into the kernel in which case it is under /sys/module with no file
+var kernelModules = []string{"overlay", "br_netfilter"}
+
+func checkKernelModules(operator kssh.Operator) nodeCheck {
+	check := nodeCheck{Name: "kernel modules"}
+
+	res, err := operator.ExecuteQuiet(fmt.Sprintf("for m in %s; do [ -d /sys/module/$m ] || modinfo $m > /dev/null 2>&1 || /sbin/modinfo $m > /dev/null 2>&1 || echo $m; done", strings.Join(kernelModules, " ")))
+	if err != nil {
+		check.Status, check.Detail = checkWarn, fmt.Sprintf("unable to check: %s", err)
+		return check
+	}
+
+	if missing := strings.Fields(string(res.StdOut)); len(missing) > 0 {
+		check.Status, check.Detail = checkFail, fmt.Sprintf("%s not found, install the modules for the running kernel", strings.Join(missing, ", "))
+		return check
+	}
+
+	check.Status, check.Detail = checkPass, strings.Join(kernelModules, ", ")
+	return check
+}
+
+func checkMemoryCgroup(operator kssh.Operator) nodeCheck {
+	check := nodeCheck{Name: "memory cgroup"}
+
+	res, err := operator.ExecuteQuiet("cat /proc/cgroups; echo ---; cat /sys/fs/cgroup/cgroup.controllers 2> /dev/null; true")
+	if err != nil {
+		check.Status, check.Detail = checkWarn, fmt.Sprintf("unable to check: %s", err)
+		return check
+	}
+
+	if !memoryCgroupEnabled(string(res.StdOut)) {
+		check.Status, check.Detail = checkFail, "not enabled, on a Raspberry Pi add cgroup_memory=1 cgroup_enable=memory to /boot/cmdline.txt and reboot"
+		return check
+	}
+
+	check.Status, check.Detail = checkPass, "enabled"
+	return check
+}
+
+// memoryCgroupEnabled reads /proc/cgroups, where the fourth column of the
+// memory line is 1 when it is enabled, then after --- the controllers of a
+// cgroup v2 hierarchy
+func memoryCgroupEnabled(output string) bool {
+	parts := strings.SplitN(output, "---", 2)
+
+	for _, line := range strings.Split(parts[0], "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 4 && fields[0] == "memory" && fields[3] == "1" {
+			return true
+		}
+	}
+
+	if len(parts) == 2 {
+		for _, controller := range strings.Fields(parts[1]) {
+			if controller == "memory" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkDiskSpace(operator kssh.Operator) nodeCheck {
+	check := nodeCheck{Name: "disk space"}
+
+	res, err := operator.ExecuteQuiet("df -Pk /var/lib")
+	if err != nil {
+		check.Status, check.Detail = checkWarn, fmt.Sprintf("unable to check: %s", err)
+		return check
+	}
+
+	available, err := parseDfAvailable(string(res.StdOut))
+	if err != nil {
+		check.Status, check.Detail = checkWarn, err.Error()
+		return check
+	}
+
+	check.Detail = fmt.Sprintf("%d MB free under /var/lib", available/1024)
+	check.Status = checkPass
+	if available < minDiskKB {
+		check.Status = checkFail
+		check.Detail += fmt.Sprintf(", at least %d MB is needed", minDiskKB/1024)
+	}
+	return check
+}
+
+// parseDfAvailable returns the available KB from the output of df -Pk
+func parseDfAvailable(output string) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf("unable to read the output of df: %q", output)
+	}
+
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("unable to read the output of df: %q", output)
+	}
+
+	return strconv.ParseInt(fields[3], 10, 64)
+}
+
+func checkPorts(operator kssh.Operator, server bool) nodeCheck {
+	check := nodeCheck{Name: "ports"}
+
+	ports := []int{10250}
+	if server {
+		ports = []int{6443, 10250}
+	}
+
+	// The ports are in use by k3s itself when it is being installed again
+	if res, err := operator.ExecuteQuiet("systemctl is-active k3s k3s-agent 2> /dev/null; true"); err == nil {
+		for _, line := range strings.Fields(string(res.StdOut)) {
+			if line == "active" {
+				check.Status, check.Detail = checkPass, "k3s is already running"
+				return check
+			}
+		}
+	}
+
+	res, err := operator.ExecuteQuiet("ss -ltnH 2> /dev/null || netstat -ltn 2> /dev/null")
+	if err != nil {
+		check.Status, check.Detail = checkWarn, fmt.Sprintf("unable to check, neither ss nor netstat could be run: %s", err)
+		return check
+	}
+
+	listening := listeningPorts(string(res.StdOut))
+	used := []string{}
+	free := []string{}
+	for _, port := range ports {
+		if listening[port] {
+			used = append(used, strconv.Itoa(port))
+		} else {
+			free = append(free, strconv.Itoa(port))
+		}
+	}
+
+	if len(used) > 0 {
+		check.Status, check.Detail = checkFail, fmt.Sprintf("%s already in use by another process", strings.Join(used, ", "))
+		return check
+	}
+
+	check.Status, check.Detail = checkPass, strings.Join(free, ", ")+" free"
+	return check
+}
+
+// listeningPorts reads the output of ss -ltn or netstat -ltn, which both
+// give the local address in the fourth column
+func listeningPorts(output string) map[int]bool {
+	ports := map[int]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+
+		local := fields[3]
+		colon := strings.LastIndex(local, ":")
+		if colon < 0 {
+			continue
+		}
+
+		if port, err := strconv.Atoi(local[colon+1:]); err == nil {
+			ports[port] = true
+		}
+	}
+	return ports
+}
+
+// checkTimeSync only warns, as a freshly booted node may not have
+// synchronised its clock yet
+func checkTimeSync(operator kssh.Operator) nodeCheck {
+	check := nodeCheck{Name: "time sync"}
+
+	res, err := operator.ExecuteQuiet("timedatectl status")
+	if err != nil {
+		check.Status, check.Detail = checkWarn, "unable to check without timedatectl, make sure the clock is correct for the certificates of the cluster"
+		return check
+	}
+
+	if !strings.Contains(string(res.StdOut), "synchronized: yes") {
+		check.Status, check.Detail = checkWarn, "the clock is not synchronised, the certificates of the cluster may not be valid yet"
+		return check
+	}
+
+	check.Status, check.Detail = checkPass, "synchronised"
+	return check
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// nodeOperator answers each command with the output in replies, or with an
+// error for a command which is not there
+type nodeOperator struct {
+	scriptOperator
+	replies map[string]string
+}
+
+func (n *nodeOperator) ExecuteQuiet(command string) (kssh.CommandRes, error) {
+	for prefix, reply := range n.replies {
+		if strings.HasPrefix(command, prefix) {
+			return kssh.CommandRes{StdOut: []byte(reply)}, nil
+		}
+	}
+	return kssh.CommandRes{}, &kssh.CommandError{Command: command, ExitStatus: 127}
+}
+
+func raspberryPi() map[string]string {
+	return map[string]string{
+		"uname":       "Linux armv7l\n",
+		"for m in":    "",
+		"cat /proc":   "#subsys_name\thierarchy\tnum_cgroups\tenabled\ncpuset\t2\t1\t1\nmemory\t0\t1\t0\n---\n",
+		"df":          "Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/root 30000000 2000000 28000000 7% /\n",
+		"systemctl":   "inactive\ninactive\n",
+		"ss":          "LISTEN 0 128 0.0.0.0:22 0.0.0.0:*\nLISTEN 0 128 [::]:6443 [::]:*\n",
+		"timedatectl": "System clock synchronized: no\n",
+	}
+}
+
+func Test_nodeChecks_RaspberryPi(t *testing.T) {
+	arch, checks := nodeChecks(&nodeOperator{replies: raspberryPi()}, true)
+	if arch != "arm" {
+		t.Errorf("want arm, got: %q", arch)
+	}
+
+	want := map[string]string{
+		"platform":       checkPass,
+		"kernel modules": checkPass,
+		"memory cgroup":  checkFail,
+		"disk space":     checkPass,
+		"ports":          checkFail,
+		"time sync":      checkWarn,
+	}
+	for _, check := range checks {
+		if want[check.Name] != check.Status {
+			t.Errorf("%s: want %s, got %s: %s", check.Name, want[check.Name], check.Status, check.Detail)
+		}
+	}
+
+	if _, checks := nodeChecks(&nodeOperator{replies: raspberryPi()}, false); checks[4].Status != checkPass {
+		t.Errorf("want 6443 to be ignored for an agent, got: %+v", checks[4])
+	}
+}
+
+func Test_preflightNode_Fails(t *testing.T) {
+	_, err := preflightNode(&nodeOperator{replies: raspberryPi()}, "192.168.0.101", true)
+	if err == nil || !strings.Contains(err.Error(), "memory cgroup, ports") {
+		t.Errorf("want the failed checks in the error, got: %v", err)
+	}
+}
+
+func Test_memoryCgroupEnabled_V2(t *testing.T) {
+	if !memoryCgroupEnabled("---\ncpuset cpu io memory pids\n") {
+		t.Errorf("want the memory controller of cgroup v2 to be found")
+	}
+
+	if memoryCgroupEnabled("---\ncpuset cpu io pids\n") {
+		t.Errorf("want no memory controller")
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// nodeStatus is what k3sup status found on a node, Nodes and Pods are only
+// set for a server
+type nodeStatus struct {
+	Host      string         `json:"host"`
+	Installed bool           `json:"installed"`
+	Role      string         `json:"role,omitempty"`
+	Version   string         `json:"version,omitempty"`
+	Service   string         `json:"service,omitempty"`
+	Active    string         `json:"active,omitempty"`
+	Enabled   string         `json:"enabled,omitempty"`
+	Nodes     *nodeSummary   `json:"nodes,omitempty"`
+	Pods      map[string]int `json:"pods,omitempty"`
+}
+
+// nodeSummary counts the nodes of a cluster by whether they are Ready
+type nodeSummary struct {
+	Total    int      `json:"total"`
+	Ready    int      `json:"ready"`
+	NotReady []string `json:"not-ready,omitempty"`
+}
+
+func MakeStatus() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "status",
+		Short: "Report whether k3s is installed and healthy on a node via SSH",
+		Long: `Report whether k3s is installed on a node via SSH, as a server or an agent,
+with its version and the state of its systemd service. For a server the
+nodes of the cluster and its pods by phase are also summarised. Nothing is
+changed on the node, use --output json to audit many nodes from a script.`,
+		Example: `  k3sup status --ip 192.168.0.100
+  k3sup status --ip 192.168.0.101 --output json`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		opts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+		sudoPrefix := escalationPrefix(escalation)
+
+		operator, err := connectSSH(opts)
+		if err != nil {
+			return err
+		}
+
+		defer operator.Close()
+
+		status, err := k3sStatus(operator, opts.Host, sudoPrefix)
+		if err != nil {
+			return err
+		}
+
+		updateResult(func(r *commandResult) {
+			r.Status = &status
+		})
+
+		printNodeStatus(status)
+		return nil
+	}
+
+	return command
+}
+
+// k3sStatus finds the role of the node from the uninstall script which the
+// installer created, then asks systemd and, for a server, the API about it
+func k3sStatus(operator kssh.Operator, host, sudoPrefix string) (nodeStatus, error) {
+	status := nodeStatus{Host: host}
+
+	findCommand := fmt.Sprintf("ls %s %s 2>/dev/null || true", serverUninstallScript, agentUninstallScript)
+	logDebugf("ssh: %s\n", findCommand)
+	res, err := operator.ExecuteQuiet(findCommand)
+	if err != nil {
+		return status, fmt.Errorf("unable to check for k3s on %s: %s", host, err)
+	}
+
+	switch uninstallScript(string(res.StdOut)) {
+	case serverUninstallScript:
+		status.Role, status.Service = "server", "k3s"
+	case agentUninstallScript:
+		status.Role, status.Service = "agent", "k3s-agent"
+	default:
+		return status, nil
+	}
+	status.Installed = true
+
+	if res, err := operator.ExecuteQuiet("k3s --version 2> /dev/null || /usr/local/bin/k3s --version"); err == nil {
+		status.Version = parseK3sVersion(string(res.StdOut))
+	}
+
+	res, err = operator.ExecuteQuiet(fmt.Sprintf("systemctl is-active %s; systemctl is-enabled %s; true", status.Service, status.Service))
+	if err != nil {
+		return status, fmt.Errorf("unable to read the state of the %s service: %s", status.Service, err)
+	}
+	if states := strings.Fields(string(res.StdOut)); len(states) == 2 {
+		status.Active, status.Enabled = states[0], states[1]
+	}
+
+	if status.Role != "server" || status.Active != "active" {
+		return status, nil
+	}
+
+	if res, err := operator.ExecuteQuiet(sudoPrefix + "k3s kubectl get nodes --no-headers"); err == nil {
+		status.Nodes = summariseNodes(string(res.StdOut))
+	} else {
+		logInfof("Unable to list the nodes of the cluster: %s\n", err)
+	}
+
+	if res, err := operator.ExecuteQuiet(sudoPrefix + "k3s kubectl get pods --all-namespaces --no-headers"); err == nil {
+		status.Pods = summarisePods(string(res.StdOut))
+	} else {
+		logInfof("Unable to list the pods of the cluster: %s\n", err)
+	}
+
+	return status, nil
+}
+
+// parseK3sVersion reads "k3s version v1.19.5+k3s1 (b11612e2)"
+func parseK3sVersion(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) >= 3 && fields[0] == "k3s" && fields[1] == "version" {
+		return fields[2]
+	}
+	return ""
+}
+
+// summariseNodes reads kubectl get nodes --no-headers, where the second
+// column is the status such as Ready or NotReady,SchedulingDisabled
+func summariseNodes(output string) *nodeSummary {
+	summary := &nodeSummary{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		summary.Total++
+		if strings.Split(fields[1], ",")[0] == "Ready" {
+			summary.Ready++
+		} else {
+			summary.NotReady = append(summary.NotReady, fields[0])
+		}
+	}
+	return summary
+}
+
+// summarisePods counts kubectl get pods --all-namespaces --no-headers by
+// the fourth column, the status such as Running or CrashLoopBackOff
+func summarisePods(output string) map[string]int {
+	pods := map[string]int{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		pods[fields[3]]++
+	}
+	return pods
+}
+
+func printNodeStatus(status nodeStatus) {
+	if !status.Installed {
+		fmt.Fprintf(textOutput, "%s: k3s is not installed\n", status.Host)
+		return
+	}
+
+	fmt.Fprintf(textOutput, "%s: k3s %s %s\n", status.Host, status.Role, status.Version)
+	fmt.Fprintf(textOutput, "Service: %s is %s and %s\n", status.Service, status.Active, status.Enabled)
+
+	if status.Nodes != nil {
+		fmt.Fprintf(textOutput, "Nodes: %d of %d Ready\n", status.Nodes.Ready, status.Nodes.Total)
+		if len(status.Nodes.NotReady) > 0 {
+			fmt.Fprintf(textOutput, "Not Ready: %s\n", strings.Join(status.Nodes.NotReady, ", "))
+		}
+	}
+
+	if len(status.Pods) > 0 {
+		phases := []string{}
+		for phase := range status.Pods {
+			phases = append(phases, phase)
+		}
+		sort.Strings(phases)
+
+		counts := []string{}
+		for _, phase := range phases {
+			counts = append(counts, fmt.Sprintf("%d %s", status.Pods[phase], phase))
+		}
+		fmt.Fprintf(textOutput, "Pods: %s\n", strings.Join(counts, ", "))
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseK3sVersion(t *testing.T) {
+	cases := map[string]string{
+		"k3s version v1.19.5+k3s1 (b11612e2)\n": "v1.19.5+k3s1",
+		"k3s version v0.9.1 (755bd1c6)":         "v0.9.1",
+		"sh: k3s: not found":                    "",
+		"":                                      "",
+	}
+
+	for output, want := range cases {
+		if got := parseK3sVersion(output); got != want {
+			t.Errorf("output: %q, want: %q, got: %q", output, want, got)
+		}
+	}
+}
+
+func Test_summariseNodes(t *testing.T) {
+	output := `server-1   Ready                      master   10d   v1.19.5+k3s1
+agent-1    Ready,SchedulingDisabled   <none>   10d   v1.19.5+k3s1
+agent-2    NotReady                   <none>   9d    v1.19.5+k3s1
+`
+	want := &nodeSummary{Total: 3, Ready: 2, NotReady: []string{"agent-2"}}
+	if got := summariseNodes(output); !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v, got: %+v", want, got)
+	}
+}
+
+func Test_summarisePods(t *testing.T) {
+	output := `kube-system   coredns-66c464876b-7zbvn                  1/1   Running            0     10d
+kube-system   helm-install-traefik-4xv7t                0/1   Completed          0     10d
+default       web-7d8c6f8d4-q2m9x                       0/1   CrashLoopBackOff   12    1h
+kube-system   traefik-5dd496474-8vd2l                   1/1   Running            0     10d
+`
+	want := map[string]int{"Running": 2, "Completed": 1, "CrashLoopBackOff": 1}
+	if got := summarisePods(output); !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+}
+
+func Test_k3sStatus_NotInstalled(t *testing.T) {
+	operator := &nodeOperator{replies: map[string]string{"ls": ""}}
+
+	status, err := k3sStatus(operator, "192.168.0.101", "sudo ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Installed {
+		t.Errorf("want k3s not to be installed, got: %+v", status)
+	}
+}
+
+func Test_k3sStatus_Agent(t *testing.T) {
+	operator := &nodeOperator{replies: map[string]string{
+		"ls":          agentUninstallScript + "\n",
+		"k3s --":      "k3s version v1.19.5+k3s1 (b11612e2)\n",
+		"systemctl ":  "active\nenabled\n",
+		"sudo k3s ku": "unexpected",
+	}}
+
+	status, err := k3sStatus(operator, "192.168.0.101", "sudo ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := nodeStatus{Host: "192.168.0.101", Installed: true, Role: "agent", Version: "v1.19.5+k3s1", Service: "k3s-agent", Active: "active", Enabled: "enabled"}
+	if !reflect.DeepEqual(want, status) {
+		t.Errorf("want: %+v, got: %+v", want, status)
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func MakeNodeToken() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "node-token",
+		Short: "Fetch the join-token from a server via SSH",
+		Long: `Fetch the join-token from a server via SSH so that agents can be joined
+later without running a full install. Only the token is printed to stdout.`,
+		Example: `  k3sup node-token --ip 192.168.0.100 --user root
+  k3sup node-token --ip 192.168.0.100 --user root --token-file ./node-token`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+	command.Flags().String("token-file", "", "Write the token to this file instead of printing it")
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		opts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+		sudoPrefix := escalationPrefix(escalation)
+		tokenFile, _ := command.Flags().GetString("token-file")
+
+		fmt.Fprintf(os.Stderr, "ssh -i %s -p %d %s@%s\n", opts.SSHKeyPath, opts.Port, opts.User, opts.Host)
+
+		operator, err := connectSSH(opts)
+		if err != nil {
+			return err
+		}
+
+		defer operator.Close()
+
+		res, err := operator.ExecuteQuiet(sudoPrefix + "cat /var/lib/rancher/k3s/server/node-token")
+		if err != nil {
+			return fmt.Errorf("unable to get join-token from server, is %s a k3s server? %s", opts.Host, err)
+		}
+
+		token := strings.TrimSpace(string(res.StdOut))
+		updateResult(func(r *commandResult) {
+			r.NodeToken = token
+		})
+
+		if len(tokenFile) > 0 {
+			absPath := expandPath(tokenFile)
+			if err := ioutil.WriteFile(absPath, []byte(token+"\n"), 0600); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Saved token to: %s\n", absPath)
+			return nil
+		}
+
+		fmt.Fprintln(textOutput, token)
+
+		return nil
+	}
+
+	return command
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"strconv"
@@ -27,6 +26,7 @@ func makeInstallOpenFaaS() *cobra.Command {
 	openfaas.Flags().BoolP("basic-auth", "a", true, "Enable authentication")
 	openfaas.Flags().BoolP("load-balancer", "l", false, "Add a loadbalancer")
 	openfaas.Flags().StringP("namespace", "n", "openfaas", "The namespace for the core services")
+	openfaas.Flags().String("version", "", "The version of the openfaas chart (Default to the latest)")
 	openfaas.Flags().Bool("update-repo", true, "Update the helm repo")
 
 	openfaas.RunE = func(command *cobra.Command, args []string) error {
@@ -36,16 +36,21 @@ func makeInstallOpenFaaS() *cobra.Command {
 			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
 		}
 
-		fmt.Printf("Using kubeconfig: %s\n", kubeConfigPath)
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
 
 		namespace, _ := command.Flags().GetString("namespace")
 
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
 		if namespace != "openfaas" {
 			return fmt.Errorf(`to override the "openfaas", install OpenFaaS via helm manually`)
 		}
 
 		arch := getArchitecture()
-		fmt.Printf("Node architecture: %q\n", arch)
+		logDebugf("Node architecture: %q\n", arch)
 
 		valuesSuffix := getValuesSuffix(arch)
 
@@ -56,18 +61,20 @@ func makeInstallOpenFaaS() *cobra.Command {
 
 		clientArch, clientOS := getClientArch()
 
-		fmt.Printf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
 
-		log.Printf("User dir established as: %s\n", userPath)
+		logDebugf("User dir established as: %s\n", userPath)
 
 		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
 
-		_, err = tryDownloadHelm(userPath, clientArch, clientOS)
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
 		if err != nil {
 			return err
 		}
 
-		err = addHelmRepo("openfaas", "https://openfaas.github.io/faas-netes/")
+		err = addHelmRepo("openfaas", "https://openfaas.github.io/faas-netes/", helm3)
 		if err != nil {
 			return err
 		}
@@ -75,7 +82,7 @@ func makeInstallOpenFaaS() *cobra.Command {
 		updateRepo, _ := openfaas.Flags().GetBool("update-repo")
 
 		if updateRepo {
-			err = updateHelmRepos()
+			err = updateHelmRepos(helm3)
 			if err != nil {
 				return err
 			}
@@ -85,30 +92,34 @@ func makeInstallOpenFaaS() *cobra.Command {
 			return err
 		}
 
-		err = kubectl("apply", "-f",
-			"https://raw.githubusercontent.com/openfaas/faas-netes/master/namespaces.yml")
+		namespacesURL := "https://raw.githubusercontent.com/openfaas/faas-netes/master/namespaces.yml"
 
-		if err != nil {
-			return err
-		}
+		if !dryRun {
+			err = kubectl("apply", "-f", namespacesURL)
 
-		pass, err := password.Generate(25, 10, 0, false, true)
-		if err != nil {
-			return err
-		}
+			if err != nil {
+				return err
+			}
 
-		_, err = kubectlTask("-n", namespace, "create", "secret", "generic",
-			"basic-auth",
-			"--from-literal=basic-auth-user=admin",
-			`--from-literal=basic-auth-password=`+pass)
+			if err := recordNamespace("openfaas", "openfaas", "openfaas-fn"); err != nil {
+				return err
+			}
 
-		if err != nil {
-			return err
+			if err := createNamespace("openfaas", namespace); err != nil {
+				return err
+			}
+
+			if basicAuth, _ := command.Flags().GetBool("basic-auth"); basicAuth {
+				if err := createBasicAuthSecret(namespace); err != nil {
+					return err
+				}
+			}
 		}
 
+		version, _ := command.Flags().GetString("version")
 		chartPath := path.Join(os.TempDir(), "charts")
 
-		err = fetchChart(chartPath, "openfaas/openfaas")
+		err = fetchChart(chartPath, "openfaas/openfaas", version, helm3)
 
 		if err != nil {
 			return err
@@ -126,48 +137,56 @@ func makeInstallOpenFaaS() *cobra.Command {
 		}
 
 		outputPath := path.Join(chartPath, "openfaas/rendered")
-		err = templateChart(chartPath, "openfaas",
-			namespace,
-			outputPath,
-			"values"+valuesSuffix+".yaml",
-			overrides)
-
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
 		if err != nil {
 			return err
 		}
 
-		err = kubectl("apply", "-R", "-f", outputPath)
+		if helm3 {
+			err = helm3Install(chartPath, "openfaas",
+				namespace,
+				"values"+valuesSuffix+".yaml",
+				overrides, userValues)
 
-		if err != nil {
-			return err
-		}
+			if err != nil {
+				return err
+			}
 
-		fmt.Println(`=======================================================================
-= OpenFaaS has been installed.                                        =
-=======================================================================
+			if err := recordRelease("openfaas", "openfaas", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "openfaas",
+				namespace,
+				outputPath,
+				"values"+valuesSuffix+".yaml",
+				overrides, userValues)
 
-# Get the faas-cli
-curl -SLsf https://cli.openfaas.com | sudo sh
+			if err != nil {
+				return err
+			}
 
-# Forward the gateway to your machine
-kubectl rollout status -n openfaas deploy/gateway
-kubectl port-forward -n openfaas svc/gateway 8080:8080 &
+			if dryRun {
+				logWarn(`The "basic-auth" secret is generated when OpenFaaS is installed, so it is not included`)
+				return printManifests(command, namespacesURL, outputPath)
+			}
 
-# If basic auth is enabled, you can now log into your gateway:
-PASSWORD=$(kubectl get secret -n openfaas basic-auth -o jsonpath="{.data.basic-auth-password}" | base64 --decode; echo)
-echo -n $PASSWORD | faas-cli login --username admin --password-stdin
+			err = kubectl("apply", "-R", "-f", outputPath)
 
-faas-cli store deploy figlet
-faas-cli list
+			if err != nil {
+				return err
+			}
 
-# For Raspberry Pi
-faas-cli store deploy figlet \
- -u https://raw.githubusercontent.com/openfaas/store/master/store-armhf.json
+			if err := recordManifest("openfaas", outputPath); err != nil {
+				return err
+			}
+		}
 
-# Find out more at:
-# https://github.com/openfaas/faas
+		if err := recordInstalled("openfaas", chartVersion(chartPath, "openfaas")); err != nil {
+			return err
+		}
 
-Thank you for using k3sup!`)
+		logInfo(openfaasInstallMsg)
 
 		return nil
 	}
@@ -175,6 +194,34 @@ Thank you for using k3sup!`)
 	return openfaas
 }
 
+// createBasicAuthSecret generates the admin password for the gateway, an
+// existing secret is kept so that the password is not changed by
+// installing again
+func createBasicAuthSecret(namespace string) error {
+	pass, err := password.Generate(25, 10, 0, false, true)
+	if err != nil {
+		return err
+	}
+
+	res, err := kubectlTask("-n", namespace, "create", "secret", "generic",
+		"basic-auth",
+		"--from-literal=basic-auth-user=admin",
+		`--from-literal=basic-auth-password=`+pass)
+
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		if !strings.Contains(res.Stderr, "AlreadyExists") {
+			return fmt.Errorf("unable to create the basic-auth secret: %s", res.Stderr)
+		}
+		logInfo("Using the existing basic-auth secret")
+	}
+
+	return recordSecret("openfaas", "basic-auth", namespace)
+}
+
 func getValuesSuffix(arch string) string {
 	var valuesSuffix string
 	switch arch {
@@ -189,3 +236,33 @@ func getValuesSuffix(arch string) string {
 	}
 	return valuesSuffix
 }
+
+const openfaasInfoMsg = `# Get the faas-cli
+curl -SLsf https://cli.openfaas.com | sudo sh
+
+# Forward the gateway to your machine
+kubectl rollout status -n openfaas deploy/gateway
+kubectl port-forward -n openfaas svc/gateway 8080:8080 &
+
+# If basic auth is enabled, you can now log into your gateway with the
+# generated password for the admin user:
+PASSWORD=$(kubectl get secret -n openfaas basic-auth -o jsonpath="{.data.basic-auth-password}" | base64 --decode; echo)
+echo -n $PASSWORD | faas-cli login --username admin --password-stdin
+
+faas-cli store deploy figlet
+faas-cli list
+
+# For Raspberry Pi
+faas-cli store deploy figlet \
+ -u https://raw.githubusercontent.com/openfaas/store/master/store-armhf.json
+
+# Find out more at:
+# https://github.com/openfaas/faas`
+
+const openfaasInstallMsg = `=======================================================================
+= OpenFaaS has been installed.                                        =
+=======================================================================
+
+` + openfaasInfoMsg + `
+
+` + thanksForUsing
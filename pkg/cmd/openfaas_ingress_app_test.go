@@ -4,11 +4,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func Test_build_yaml_returns_correct_substitutions(t *testing.T) {
-	templBytes, _ := buildYaml("openfaas.subdomain.example.com", "openfaas@subdomain.example.com")
+	templBytes, _ := buildYaml(openfaasIngressData(newInputData("openfaas.subdomain.example.com", "openfaas@subdomain.example.com", "nginx", false, dns01Options{})))
 
 	got := string(templBytes)
 	if want != got {
@@ -16,6 +17,55 @@ func Test_build_yaml_returns_correct_substitutions(t *testing.T) {
 	}
 }
 
+func Test_build_yaml_uses_ingress_class(t *testing.T) {
+	templBytes, _ := buildYaml(openfaasIngressData(newInputData("openfaas.subdomain.example.com", "openfaas@subdomain.example.com", "traefik", false, dns01Options{})))
+
+	got := string(templBytes)
+	if !strings.Contains(got, "kubernetes.io/ingress.class: traefik") {
+		t.Errorf("want traefik ingress annotation, got: %q", got)
+	}
+	if !strings.Contains(got, "class: traefik") || strings.Contains(got, "nginx") {
+		t.Errorf("want traefik http01 solver, got: %q", got)
+	}
+}
+
+func Test_build_yaml_staging_issuer(t *testing.T) {
+	templBytes, _ := buildYaml(openfaasIngressData(newInputData("openfaas.subdomain.example.com", "openfaas@subdomain.example.com", "nginx", true, dns01Options{})))
+
+	got := string(templBytes)
+	if !strings.Contains(got, "cert-manager.io/cluster-issuer: letsencrypt-staging") {
+		t.Errorf("want staging issuer annotation, got: %q", got)
+	}
+	if !strings.Contains(got, "server: "+letsencryptStagingServer) || strings.Contains(got, "letsencrypt-prod") {
+		t.Errorf("want staging ACME server, got: %q", got)
+	}
+}
+
+func Test_build_yaml_dns01_route53(t *testing.T) {
+	dns01 := dns01Options{Provider: "route53", SecretFile: "aws.txt", AWSRegion: "eu-west-1", AWSAccessKeyID: "AKIAEXAMPLE"}
+	templBytes, err := buildYaml(openfaasIngressData(newInputData("openfaas.subdomain.example.com", "openfaas@subdomain.example.com", "nginx", false, dns01)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(templBytes)
+	wantSolver := `    solvers:
+    - dns01:
+        route53:
+          region: eu-west-1
+          accessKeyID: AKIAEXAMPLE
+          secretAccessKeySecretRef:
+            name: k3sup-dns01-route53
+            key: secret-access-key`
+
+	if !strings.HasSuffix(got, wantSolver) {
+		t.Errorf("want route53 solver, got: %q", got)
+	}
+	if strings.Contains(got, "http01") {
+		t.Errorf("want no http01 solver, got: %q", got)
+	}
+}
+
 var want = `
 apiVersion: extensions/v1beta1 
 kind: Ingress
@@ -56,7 +106,7 @@ spec:
 
 func Test_writeTempFile_writes_to_tmp(t *testing.T) {
 	var want = "some input string"
-	tmpLocation, _ := writeTempFile([]byte(want))
+	tmpLocation, _ := writeTempFile("test", []byte(want))
 
 	got, _ := ioutil.ReadFile(tmpLocation)
 	if string(got) != want {
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alexellis/k3sup/pkg/apps"
+
+	"github.com/spf13/cobra"
+)
+
+// outputFormat is set by --output. With json the text which commands print
+// as they run is written to stderr, and a commandResult is written to stdout
+// once the command completes.
+var outputFormat = "text"
+
+var (
+	resultStdout = os.Stdout
+
+	// textOutput is where commands print their progress and text output,
+	// it is stderr with --output json so that stdout only holds the result
+	textOutput = os.Stdout
+	result     = commandResult{}
+	resultLock sync.Mutex
+)
+
+// commandResult is printed by --output json, fields are only set by the
+// commands which they apply to
+type commandResult struct {
+	Command         string          `json:"command"`
+	Success         bool            `json:"success"`
+	Error           string          `json:"error,omitempty"`
+	DurationSeconds float64         `json:"duration-seconds"`
+	Kubeconfig      string          `json:"kubeconfig,omitempty"`
+	Context         string          `json:"context,omitempty"`
+	NodeToken       string          `json:"node-token,omitempty"`
+	Script          string          `json:"script,omitempty"`
+	Nodes           []nodeResult    `json:"nodes,omitempty"`
+	Checks          []nodeCheck     `json:"checks,omitempty"`
+	Status          *nodeStatus     `json:"status,omitempty"`
+	App             string          `json:"app,omitempty"`
+	Version         string          `json:"version,omitempty"`
+	LatestVersion   string          `json:"latest-version,omitempty"`
+	Namespaces      []string        `json:"namespaces,omitempty"`
+	Manifests       []string        `json:"manifests,omitempty"`
+	Releases        []apps.Release  `json:"releases,omitempty"`
+	Workloads       []apps.Resource `json:"workloads,omitempty"`
+	Snapshots       []etcdSnapshot  `json:"snapshots,omitempty"`
+}
+
+// nodeResult is a server or agent which was installed or joined
+type nodeResult struct {
+	Host            string  `json:"host"`
+	Role            string  `json:"role"`
+	DurationSeconds float64 `json:"duration-seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// AddOutputFlag adds the --output flag to root and all of its sub-commands
+func AddOutputFlag(root *cobra.Command) {
+	root.PersistentFlags().StringVar(&outputFormat, "output", "text", "Print the result as text or json, with json the progress is written to stderr")
+
+	flagHooks = append(flagHooks, func() error {
+		if outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("--output must be text or json, not %q", outputFormat)
+		}
+
+		if outputFormat == "json" {
+			progressToStderr()
+		}
+		return nil
+	})
+}
+
+// progressToStderr writes the text which commands print as they run to
+// stderr, so that only the result is written to stdout
+func progressToStderr() {
+	textOutput = os.Stderr
+}
+
+// PrintResult writes the result of command as JSON to stdout when --output
+// json was given, err is the error returned by the command
+func PrintResult(command *cobra.Command, err error, start time.Time) {
+	if outputFormat != "json" || command == nil {
+		return
+	}
+
+	resultLock.Lock()
+	defer resultLock.Unlock()
+
+	result.Command = command.CommandPath()
+	result.Success = err == nil
+	result.DurationSeconds = seconds(time.Since(start))
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if err := writeResult(resultStdout, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: unable to write the result: %s\n", err)
+	}
+}
+
+func writeResult(w io.Writer, r commandResult) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// updateResult changes the result printed by --output json, it is safe to
+// call from agents which are joined concurrently
+func updateResult(update func(r *commandResult)) {
+	resultLock.Lock()
+	defer resultLock.Unlock()
+
+	update(&result)
+}
+
+// recordNode adds a server or agent to the result, along with how long it
+// took to install or join
+func recordNode(host, role string, duration time.Duration, err error) {
+	node := nodeResult{Host: host, Role: role, DurationSeconds: seconds(duration)}
+	if err != nil {
+		node.Error = err.Error()
+	}
+
+	updateResult(func(r *commandResult) {
+		r.Nodes = append(r.Nodes, node)
+	})
+}
+
+// recordAppResult adds what was applied for app to the result, including
+// the workloads from the inventory
+func recordAppResult(app string) error {
+	if outputFormat != "json" {
+		return nil
+	}
+
+	state, err := loadAppState(app)
+	if err != nil {
+		return err
+	}
+
+	inventory, err := loadInventory()
+	if err != nil {
+		return err
+	}
+
+	updateResult(func(r *commandResult) {
+		r.App = app
+		r.Version = state.Version
+		r.Namespaces = state.Namespaces
+		r.Releases = state.Releases
+		r.Workloads = inventory[app].Workloads
+		for _, manifest := range state.Manifests {
+			r.Manifests = append(r.Manifests, manifest.Source)
+		}
+	})
+
+	return nil
+}
+
+func seconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_recordNode_AddsNodesToResult(t *testing.T) {
+	result = commandResult{}
+	defer func() { result = commandResult{} }()
+
+	recordNode("192.168.0.100", "server", 1500*time.Millisecond, nil)
+	recordNode("192.168.0.101", "agent", time.Second, fmt.Errorf("connection refused"))
+
+	want := []nodeResult{
+		{Host: "192.168.0.100", Role: "server", DurationSeconds: 1.5},
+		{Host: "192.168.0.101", Role: "agent", DurationSeconds: 1, Error: "connection refused"},
+	}
+
+	if len(result.Nodes) != len(want) {
+		t.Fatalf("want %d nodes, got: %v", len(want), result.Nodes)
+	}
+
+	for i, node := range want {
+		if result.Nodes[i] != node {
+			t.Errorf("want: %v, got: %v", node, result.Nodes[i])
+		}
+	}
+}
+
+func Test_writeResult_OmitsUnsetFields(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := writeResult(&buf, commandResult{Command: "k3sup node-token", Success: true, NodeToken: "K10abc::server:def"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["node-token"] != "K10abc::server:def" {
+		t.Errorf("want node-token, got: %v", got)
+	}
+
+	for _, field := range []string{"kubeconfig", "nodes", "app", "error"} {
+		if _, ok := got[field]; ok {
+			t.Errorf("want %s to be omitted, got: %v", field, got)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	config "github.com/alexellis/k3sup/pkg/config"
+	"github.com/alexellis/k3sup/pkg/provision"
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+)
+
+// clusterPlan describes the servers and agents of a cluster, it is read
+// from a YAML or JSON file by "k3sup plan". Values set at the top-level are used as
+// defaults for each node. Cluster or Datastore must be set for more than one
+// server, with Cluster the first server creates an HA cluster with embedded
+// etcd.
+type clusterPlan struct {
+	Cluster             bool                 `json:"cluster,omitempty"`
+	Datastore           *provision.Datastore `json:"datastore,omitempty"`
+	User                string               `json:"user,omitempty"`
+	SSHKey              string               `json:"ssh-key,omitempty"`
+	SSHPort             int                  `json:"ssh-port,omitempty"`
+	SSHAgent            bool                 `json:"ssh-agent,omitempty"`
+	Forwarding          bool                 `json:"ssh-agent-forwarding,omitempty"`
+	Sudo                *bool                `json:"sudo,omitempty"`
+	PrivilegeEscalation string               `json:"privilege-escalation,omitempty"`
+	K3sVersion          string               `json:"k3s-version,omitempty"`
+	K3sChannel          string               `json:"k3s-channel,omitempty"`
+	K3sExtraArgs        string               `json:"k3s-extra-args,omitempty"`
+	Disable             []string             `json:"disable,omitempty"`
+	TLSSANs             []string             `json:"tls-san,omitempty"`
+	Cache               bool                 `json:"cache,omitempty"`
+	FixRPiCgroups       bool                 `json:"fix-rpi-cgroups,omitempty"`
+	Servers             []planNode           `json:"servers"`
+	Agents              []planNode           `json:"agents,omitempty"`
+
+	// http-proxy, https-proxy and no-proxy, and registries-file or
+	// registry-mirrors for every node
+	provision.Proxy
+	provision.Registry
+
+	// password and keyPassphrase are given by flags, so that they are not
+	// saved in the plan file, as is the host key checking
+	password        string
+	keyPassphrase   string
+	hostKeyChecking string
+	knownHosts      string
+}
+
+// planNode is a single server or agent within a clusterPlan
+type planNode struct {
+	Host         string            `json:"host"`
+	User         string            `json:"user,omitempty"`
+	SSHKey       string            `json:"ssh-key,omitempty"`
+	SSHPort      int               `json:"ssh-port,omitempty"`
+	SSHAgent     bool              `json:"ssh-agent,omitempty"`
+	Forwarding   bool              `json:"ssh-agent-forwarding,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Taints       []string          `json:"taints,omitempty"`
+	K3sExtraArgs string            `json:"k3s-extra-args,omitempty"`
+
+	NodeIP           string `json:"node-ip,omitempty"`
+	NodeExternalIP   string `json:"node-external-ip,omitempty"`
+	AdvertiseAddress string `json:"advertise-address,omitempty"`
+
+	password        string
+	keyPassphrase   string
+	hostKeyChecking string
+	knownHosts      string
+}
+
+func MakePlan() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "plan",
+		Short: "Provision a cluster from a plan file",
+		Long: `Provision all the servers and agents described in a YAML or JSON plan
+file in one invocation, instead of running install and join for each node.`,
+		Example: `  k3sup plan cluster.yaml
+
+  # cluster.yaml
+  user: pi
+  ssh-key: ~/.ssh/id_rsa
+  servers:
+    - host: 192.168.0.100
+  agents:
+    - host: 192.168.0.101
+      labels:
+        disk: ssd
+    - host: 192.168.0.102
+      taints: ["gpu=true:NoSchedule"]`,
+		SilenceUsage: true,
+	}
+
+	command.Flags().String("local-path", "kubeconfig", "Local path to save the kubeconfig file")
+	command.Flags().String("context", "default", "Set the name of the kubeconfig context.")
+	command.Flags().String("cluster-name", "", "Set the name of the cluster in the kubeconfig (Default to --context)")
+	command.Flags().String("user-name", "", "Set the name of the user in the kubeconfig (Default to --context)")
+	command.Flags().Int("concurrency", 4, "The maximum number of agents to join at the same time")
+	command.Flags().Bool("skip-preflight", false, "Skip the checks of each node which are made before k3s is installed, see k3sup preflight")
+	command.Flags().Bool("merge", false, "Merge the config into an existing kubeconfig under the name given by --context.\nDefaults to $KUBECONFIG or ~/.kube/config, unless --local-path is given")
+	command.Flags().Bool("no-backup", false, "Do not copy an existing kubeconfig to a backup with a timestamp before it is overwritten or merged")
+	command.Flags().String("ssh-key-passphrase-file", "", "File holding the passphrase of the encrypted ssh-keys in the plan, instead of prompting for them")
+	addSSHPasswordFlags(command)
+	addHostKeyFlags(command)
+
+	command.Flags().SetNormalizeFunc(contextNameAlias)
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("give the path to a plan file, i.e. k3sup plan cluster.yaml")
+		}
+
+		data, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		plan, err := parsePlan(data)
+		if err != nil {
+			return err
+		}
+
+		if plan.password, err = sshPasswordFromFlags(command); err != nil {
+			return err
+		}
+
+		if plan.keyPassphrase, err = sshKeyPassphraseFromFlags(command); err != nil {
+			return err
+		}
+
+		if plan.hostKeyChecking, plan.knownHosts, err = hostKeyOptionsFromFlags(command); err != nil {
+			return err
+		}
+
+		localKubeconfig := localKubeconfigFromFlags(command)
+		context, _ := command.Flags().GetString("context")
+		clusterName, _ := command.Flags().GetString("cluster-name")
+		userName, _ := command.Flags().GetString("user-name")
+		merge, _ := command.Flags().GetBool("merge")
+		noBackup, _ := command.Flags().GetBool("no-backup")
+		skipPreflight, _ := command.Flags().GetBool("skip-preflight")
+
+		server := plan.node(plan.Servers[0])
+		logInfof("Installing server: %s\n", server.Host)
+
+		err = installK3s(installOptions{
+			SSH:                 server.sshOptions(),
+			PrivilegeEscalation: plan.privilegeEscalation(),
+			K3sVersion:          plan.K3sVersion,
+			K3sChannel:          plan.K3sChannel,
+			Proxy:               plan.Proxy,
+			Registry:            plan.Registry,
+			Cache:               plan.Cache,
+			SkipPreflight:       skipPreflight,
+			FixRPiCgroups:       plan.FixRPiCgroups,
+			K3sExtraArgs:        plan.serverArgs(server),
+			TLSSANs:             plan.TLSSANs,
+			Cluster:             plan.Cluster,
+			Datastore:           plan.datastore(),
+			LocalPath:           localKubeconfig,
+			Names: kubeconfigNames{
+				Context: context,
+				Cluster: clusterName,
+				User:    userName,
+			},
+			Merge:    merge,
+			NoBackup: noBackup,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to install server %s: %s", server.Host, err)
+		}
+
+		for _, n := range plan.Servers[1:] {
+			extra := plan.node(n)
+			logInfof("Joining server: %s\n", extra.Host)
+
+			err = joinAgent(joinOptions{
+				Agent:               extra.sshOptions(),
+				Server:              server.sshOptions(),
+				PrivilegeEscalation: plan.privilegeEscalation(),
+				JoinAsServer:        true,
+				Datastore:           plan.datastore(),
+				K3sVersion:          plan.K3sVersion,
+				K3sChannel:          plan.K3sChannel,
+				Proxy:               plan.Proxy,
+				Registry:            plan.Registry,
+				Cache:               plan.Cache,
+				SkipPreflight:       skipPreflight,
+				FixRPiCgroups:       plan.FixRPiCgroups,
+				K3sExtraArgs:        plan.serverArgs(extra),
+				TLSSANs:             plan.TLSSANs,
+			})
+			if err != nil {
+				return fmt.Errorf("unable to join server %s: %s", extra.Host, err)
+			}
+		}
+
+		agents := []joinOptions{}
+		for _, n := range plan.Agents {
+			agent := plan.node(n)
+
+			agents = append(agents, joinOptions{
+				Agent:               agent.sshOptions(),
+				Server:              server.sshOptions(),
+				PrivilegeEscalation: plan.privilegeEscalation(),
+				K3sVersion:          plan.K3sVersion,
+				K3sChannel:          plan.K3sChannel,
+				Proxy:               plan.Proxy,
+				Registry:            plan.Registry,
+				Cache:               plan.Cache,
+				SkipPreflight:       skipPreflight,
+				FixRPiCgroups:       plan.FixRPiCgroups,
+				K3sExtraArgs:        agent.k3sArgs(),
+			})
+		}
+
+		if len(agents) > 0 {
+			concurrency, _ := command.Flags().GetInt("concurrency")
+			if err := joinAgents(agents, concurrency); err != nil {
+				return err
+			}
+		}
+
+		logInfof("Provisioned %d server(s) and %d agent(s)\n", len(plan.Servers), len(plan.Agents))
+
+		return nil
+	}
+
+	return command
+}
+
+func parsePlan(data []byte) (*clusterPlan, error) {
+	// JSON is also valid YAML
+	plan := clusterPlan{}
+	if err := yaml.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("unable to parse plan file: %s", err)
+	}
+
+	if len(plan.Servers) == 0 {
+		return nil, fmt.Errorf("a plan must have at least one server")
+	}
+
+	if plan.Datastore != nil && len(plan.Datastore.Endpoint) == 0 {
+		return nil, fmt.Errorf("the datastore in a plan needs an endpoint")
+	}
+
+	if plan.Cluster && plan.Datastore != nil {
+		return nil, fmt.Errorf("a plan can set cluster or datastore, not both")
+	}
+
+	if len(plan.Servers) > 1 && !plan.Cluster && plan.Datastore == nil {
+		return nil, fmt.Errorf("set \"cluster\": true or a datastore in the plan to provision %d servers", len(plan.Servers))
+	}
+
+	// A host may be written as user@host:port instead of setting user and
+	// ssh-port on the node
+	for _, nodes := range [][]planNode{plan.Servers, plan.Agents} {
+		for i := range nodes {
+			n := &nodes[i]
+			if len(n.Host) == 0 {
+				return nil, fmt.Errorf("every server and agent in a plan needs a host")
+			}
+
+			host, user, port, err := parseSSHAddress(n.Host, n.User, n.SSHPort)
+			if err != nil {
+				return nil, fmt.Errorf("invalid host in plan: %s", err)
+			}
+			n.Host, n.User, n.SSHPort = host, user, port
+
+			if _, err := nodeAddressArgs(n.NodeIP, n.NodeExternalIP, n.AdvertiseAddress); err != nil {
+				return nil, fmt.Errorf("invalid address for %s in plan: %s", n.Host, err)
+			}
+		}
+	}
+
+	for _, n := range plan.Agents {
+		if len(n.AdvertiseAddress) > 0 {
+			return nil, fmt.Errorf("advertise-address can only be set for servers, not agent %s", n.Host)
+		}
+	}
+
+	if len(plan.User) == 0 {
+		plan.User = "root"
+	}
+	if len(plan.SSHKey) == 0 {
+		plan.SSHKey = "~/.ssh/id_rsa"
+	}
+	if plan.SSHPort == 0 {
+		plan.SSHPort = 22
+	}
+	if err := validTLSSANs(plan.TLSSANs); err != nil {
+		return nil, err
+	}
+	if err := plan.Proxy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := plan.Registry.Validate(); err != nil {
+		return nil, err
+	}
+	for _, component := range plan.Disable {
+		if err := validDisableComponent(component); err != nil {
+			return nil, err
+		}
+	}
+	if len(plan.PrivilegeEscalation) > 0 {
+		if err := validPrivilegeEscalation(plan.PrivilegeEscalation); err != nil {
+			return nil, err
+		}
+		if plan.Sudo != nil && !*plan.Sudo && plan.PrivilegeEscalation != escalateNone {
+			return nil, fmt.Errorf("a plan can not set \"sudo\": false with \"privilege-escalation\": %q", plan.PrivilegeEscalation)
+		}
+	}
+	if len(plan.K3sVersion) > 0 && len(plan.K3sChannel) > 0 {
+		return nil, fmt.Errorf("a plan can set k3s-version or k3s-channel, not both")
+	}
+	if len(plan.K3sVersion) == 0 && len(plan.K3sChannel) == 0 {
+		plan.K3sVersion = config.K3sVersion
+	}
+	if plan.Cache && len(plan.K3sChannel) > 0 {
+		return nil, fmt.Errorf("a plan with cache needs a k3s-version rather than a k3s-channel")
+	}
+
+	return &plan, nil
+}
+
+// node returns n with any unset values defaulted from the plan
+func (p *clusterPlan) node(n planNode) planNode {
+	if len(n.User) == 0 {
+		n.User = p.User
+	}
+	if len(n.SSHKey) == 0 {
+		n.SSHKey = p.SSHKey
+	}
+	if n.SSHPort == 0 {
+		n.SSHPort = p.SSHPort
+	}
+	n.K3sExtraArgs = strings.TrimSpace(p.K3sExtraArgs + " " + n.K3sExtraArgs)
+	n.SSHAgent = n.SSHAgent || p.SSHAgent
+	n.Forwarding = n.Forwarding || p.Forwarding
+	n.password = p.password
+	n.keyPassphrase = p.keyPassphrase
+	n.hostKeyChecking = p.hostKeyChecking
+	n.knownHosts = p.knownHosts
+	return n
+}
+
+func (p *clusterPlan) datastore() provision.Datastore {
+	if p.Datastore == nil {
+		return provision.Datastore{}
+	}
+	return *p.Datastore
+}
+
+// privilegeEscalation returns the tool used to run commands as root, which
+// is none when the plan sets "sudo": false
+func (p *clusterPlan) privilegeEscalation() string {
+	if p.Sudo != nil && !*p.Sudo {
+		return escalateNone
+	}
+	if len(p.PrivilegeEscalation) == 0 {
+		return escalateSudo
+	}
+	return p.PrivilegeEscalation
+}
+
+func (n planNode) sshOptions() sshOptions {
+	return sshOptions{
+		Host:            n.Host,
+		Port:            n.SSHPort,
+		User:            n.User,
+		SSHKeyPath:      expandPath(n.SSHKey),
+		UseAgent:        n.SSHAgent,
+		ForwardAgent:    n.Forwarding,
+		Password:        n.password,
+		KeyPassphrase:   n.keyPassphrase,
+		HostKeyChecking: n.hostKeyChecking,
+		KnownHostsFile:  n.knownHosts,
+	}
+}
+
+// serverArgs returns the k3s flags for a server, which disable the
+// components listed in the plan
+func (p *clusterPlan) serverArgs(n planNode) string {
+	return strings.TrimSpace(disableArgs(p.Disable) + " " + n.k3sArgs())
+}
+
+// k3sArgs returns the node's addresses, labels and taints as k3s flags,
+// followed by any extra arguments
+func (n planNode) k3sArgs() string {
+	args := []string{}
+
+	// The addresses are checked by parsePlan
+	if addresses, _ := nodeAddressArgs(n.NodeIP, n.NodeExternalIP, n.AdvertiseAddress); len(addresses) > 0 {
+		args = append(args, addresses)
+	}
+
+	keys := []string{}
+	for k := range n.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		args = append(args, fmt.Sprintf("--node-label %s=%s", k, n.Labels[k]))
+	}
+
+	for _, taint := range n.Taints {
+		args = append(args, "--node-taint "+taint)
+	}
+
+	if extra := strings.TrimSpace(n.K3sExtraArgs); len(extra) > 0 {
+		args = append(args, extra)
+	}
+
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func Test_parsePlan_defaults_nodes_from_plan(t *testing.T) {
+	plan, err := parsePlan([]byte(`{
+  "user": "pi",
+  "servers": [ { "host": "192.168.0.100" } ],
+  "agents": [ { "host": "192.168.0.101", "user": "ubuntu", "ssh-port": 2222 } ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := plan.node(plan.Servers[0])
+	if server.User != "pi" || server.SSHPort != 22 || server.SSHKey != "~/.ssh/id_rsa" {
+		t.Errorf("server not defaulted from plan, got: %+v", server)
+	}
+
+	agent := plan.node(plan.Agents[0])
+	if agent.User != "ubuntu" || agent.SSHPort != 2222 {
+		t.Errorf("agent values should not be overridden, got: %+v", agent)
+	}
+
+	if plan.privilegeEscalation() != escalateSudo {
+		t.Errorf("want sudo to be used by default, got: %s", plan.privilegeEscalation())
+	}
+}
+
+func Test_parsePlan_YAML(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/cluster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := parsePlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !plan.Cluster || plan.K3sVersion != "v1.19.5+k3s1" || len(plan.TLSSANs) != 1 {
+		t.Errorf("top-level values not read from YAML, got: %+v", plan)
+	}
+
+	if len(plan.Servers) != 3 || len(plan.Agents) != 2 {
+		t.Fatalf("want 3 servers and 2 agents, got %d and %d", len(plan.Servers), len(plan.Agents))
+	}
+
+	server := plan.node(plan.Servers[2])
+	if server.Host != "192.168.0.102" || server.User != "ubuntu" || server.SSHPort != 2222 || server.AdvertiseAddress != "10.0.0.2" {
+		t.Errorf("server not read from YAML, got: %+v", server)
+	}
+
+	agent := plan.node(plan.Agents[0])
+	if agent.User != "pi" || agent.SSHKey != "~/.ssh/id_ed25519" || agent.Labels["disk"] != "ssd" {
+		t.Errorf("agent not defaulted from YAML plan, got: %+v", agent)
+	}
+
+	if got := plan.Agents[1].K3sExtraArgs; got != "--node-label zone=a" {
+		t.Errorf("want k3s-extra-args from YAML, got: %q", got)
+	}
+
+	if _, err := parsePlan([]byte("servers:\n  - host: [192.168.0.100\n")); err == nil {
+		t.Errorf("want an error for invalid YAML")
+	}
+}
+
+func Test_parsePlan_requires_one_server(t *testing.T) {
+	_, err := parsePlan([]byte(`{"agents": [ { "host": "192.168.0.101" } ]}`))
+	if err == nil {
+		t.Errorf("want error for plan without a server")
+	}
+}
+
+func Test_planNode_k3sArgs(t *testing.T) {
+	n := planNode{
+		Labels:       map[string]string{"zone": "a", "disk": "ssd"},
+		Taints:       []string{"gpu=true:NoSchedule"},
+		K3sExtraArgs: " --no-deploy traefik ",
+	}
+
+	want := "--node-label disk=ssd --node-label zone=a --node-taint gpu=true:NoSchedule --no-deploy traefik"
+	got := n.k3sArgs()
+	if want != got {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+}
+
+func Test_parsePlan_several_servers_need_cluster(t *testing.T) {
+	servers := `"servers": [ { "host": "192.168.0.100" }, { "host": "192.168.0.101" }, { "host": "192.168.0.102" } ]`
+
+	if _, err := parsePlan([]byte(`{` + servers + `}`)); err == nil {
+		t.Errorf("want error for several servers without cluster")
+	}
+
+	if _, err := parsePlan([]byte(`{"cluster": true, ` + servers + `}`)); err != nil {
+		t.Errorf("want no error with cluster set, got: %s", err)
+	}
+
+	if _, err := parsePlan([]byte(`{"datastore": {}, ` + servers + `}`)); err == nil {
+		t.Errorf("want error for a datastore without an endpoint")
+	}
+}
+
+func Test_parsePlan_user_and_port_in_host(t *testing.T) {
+	plan, err := parsePlan([]byte(`{
+  "user": "pi",
+  "servers": [ { "host": "ubuntu@192.168.0.100:2222" } ],
+  "agents": [ { "host": "192.168.0.101", "user": "alpine" } ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := plan.node(plan.Servers[0])
+	if server.Host != "192.168.0.100" || server.User != "ubuntu" || server.SSHPort != 2222 {
+		t.Errorf("want the user and port from the host, got: %+v", server)
+	}
+
+	agent := plan.node(plan.Agents[0])
+	if agent.Host != "192.168.0.101" || agent.User != "alpine" || agent.SSHPort != 22 {
+		t.Errorf("want the agent's own user and the plan's port, got: %+v", agent)
+	}
+}
+
+func Test_parsePlan_k3s_extra_args_for_every_node(t *testing.T) {
+	plan, err := parsePlan([]byte(`{
+  "k3s-extra-args": "--flannel-backend=wireguard",
+  "servers": [ { "host": "192.168.0.100", "k3s-extra-args": "--no-deploy traefik" } ],
+  "agents": [ { "host": "192.168.0.101" } ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := plan.node(plan.Servers[0]).k3sArgs(); got != "--flannel-backend=wireguard --no-deploy traefik" {
+		t.Errorf("want the plan's args before the node's, got: %q", got)
+	}
+
+	if got := plan.node(plan.Agents[0]).k3sArgs(); got != "--flannel-backend=wireguard" {
+		t.Errorf("want the plan's args, got: %q", got)
+	}
+}
+
+func Test_parsePlan_disable_for_servers(t *testing.T) {
+	plan, err := parsePlan([]byte(`{
+  "disable": ["traefik", "servicelb"],
+  "servers": [ { "host": "192.168.0.100", "labels": { "disk": "ssd" } } ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "--disable traefik --disable servicelb --node-label disk=ssd"
+	if got := plan.serverArgs(plan.node(plan.Servers[0])); got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+
+	if _, err := parsePlan([]byte(`{"disable": ["coredns"], "servers": [ { "host": "192.168.0.100" } ]}`)); err == nil {
+		t.Errorf("want an error for an unknown component")
+	}
+}
+
+func Test_parsePlan_node_addresses(t *testing.T) {
+	plan, err := parsePlan([]byte(`{
+  "servers": [ { "host": "192.168.0.100", "node-ip": "10.0.0.1", "advertise-address": "10.0.0.1" } ],
+  "agents": [ { "host": "192.168.0.101", "node-ip": "10.0.0.2", "node-external-ip": "203.0.113.11" } ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "--node-ip 10.0.0.2 --node-external-ip 203.0.113.11"
+	if got := plan.node(plan.Agents[0]).k3sArgs(); got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+
+	if _, err := parsePlan([]byte(`{"servers": [ { "host": "192.168.0.100" } ], "agents": [ { "host": "192.168.0.101", "advertise-address": "10.0.0.2" } ]}`)); err == nil {
+		t.Errorf("want an error for an agent with advertise-address")
+	}
+}
+
+func Test_parsePlan_proxy(t *testing.T) {
+	plan, err := parsePlan([]byte(`{"https-proxy": "http://proxy:3128", "no-proxy": "10.0.0.0/8", "servers": [ { "host": "192.168.0.100" } ]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.HTTPS != "http://proxy:3128" || plan.NoProxy != "10.0.0.0/8" {
+		t.Errorf("want the proxy from the plan, got: %+v", plan.Proxy)
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/provision"
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// remoteArch checks that the node runs Linux on an architecture which k3s
+// is released for, so that the installer does not leave a binary which can
+// not run. It returns the architecture as named by the k3s releases, i.e.
+// amd64, arm64 or arm.
+func remoteArch(operator kssh.Operator) (string, error) {
+	res, err := operator.ExecuteQuiet("uname -sm")
+	if err != nil {
+		return "", fmt.Errorf("unable to detect the OS and architecture of the node: %s", err)
+	}
+
+	fields := strings.Fields(string(res.StdOut))
+	if len(fields) != 2 {
+		return "", fmt.Errorf("unable to detect the OS and architecture of the node from uname: %q", strings.TrimSpace(string(res.StdOut)))
+	}
+
+	os, machine := fields[0], fields[1]
+	if os != "Linux" {
+		return "", fmt.Errorf("k3s only runs on Linux, but the node runs %s", os)
+	}
+
+	logDebugf("Node platform: %q, %q\n", os, machine)
+
+	switch {
+	case machine == "x86_64" || machine == "amd64":
+		return "amd64", nil
+	case machine == "aarch64" || machine == "arm64":
+		return "arm64", nil
+	case strings.HasPrefix(machine, "armv7") || strings.HasPrefix(machine, "armv6"):
+		return "arm", nil
+	}
+
+	return "", fmt.Errorf("k3s is not released for the %s architecture of the node, only for x86_64, aarch64 and armv7l", machine)
+}
+
+// k3sBinaryName returns the name of the k3s binary released for arch, which
+// is one of the names returned by remoteArch
+func k3sBinaryName(arch string) string {
+	switch arch {
+	case "arm64":
+		return "k3s-arm64"
+	case "arm":
+		return "k3s-armhf"
+	}
+	return "k3s"
+}
+
+// checkAirgapBinary returns an error when the --airgap-binary has the name
+// of a k3s release for another architecture than arch, a binary which has
+// been renamed is not checked
+func checkAirgapBinary(airgap provision.Airgap, arch string) error {
+	if !airgap.Enabled {
+		return nil
+	}
+
+	name := filepath.Base(airgap.Binary)
+	for _, other := range []string{"amd64", "arm64", "arm"} {
+		if name == k3sBinaryName(other) && other != arch {
+			return fmt.Errorf("--airgap-binary %s is for %s, but the node is %s, use %s", airgap.Binary, other, arch, k3sBinaryName(arch))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alexellis/k3sup/pkg/provision"
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// unameOperator answers uname with output
+type unameOperator struct {
+	scriptOperator
+	output string
+}
+
+func (u *unameOperator) ExecuteQuiet(command string) (kssh.CommandRes, error) {
+	return kssh.CommandRes{StdOut: []byte(u.output + "\n")}, nil
+}
+
+func Test_remoteArch(t *testing.T) {
+	cases := map[string]string{
+		"Linux x86_64":  "amd64",
+		"Linux aarch64": "arm64",
+		"Linux armv7l":  "arm",
+	}
+
+	for output, want := range cases {
+		got, err := remoteArch(&unameOperator{output: output})
+		if err != nil || got != want {
+			t.Errorf("%s: want: %q, got: %q %v", output, want, got, err)
+		}
+	}
+
+	for _, output := range []string{"Linux riscv64", "Darwin x86_64", ""} {
+		if _, err := remoteArch(&unameOperator{output: output}); err == nil {
+			t.Errorf("want an error for %q", output)
+		}
+	}
+}
+
+func Test_checkAirgapBinary(t *testing.T) {
+	if err := checkAirgapBinary(provision.Airgap{Enabled: true, Binary: "./bin/k3s"}, "arm64"); err == nil {
+		t.Errorf("want an error for the amd64 binary on an arm64 node")
+	}
+
+	if err := checkAirgapBinary(provision.Airgap{Enabled: true, Binary: "./bin/k3s-arm64"}, "arm64"); err != nil {
+		t.Errorf("want the arm64 binary to be accepted, got: %s", err)
+	}
+
+	if err := checkAirgapBinary(provision.Airgap{Enabled: true, Binary: "./k3s-pi"}, "arm"); err != nil {
+		t.Errorf("want a renamed binary to be accepted, got: %s", err)
+	}
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/alexellis/k3sup/pkg/config"
+	"github.com/sethvargo/go-password/password"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallPostgresql() *cobra.Command {
+	var postgresql = &cobra.Command{
+		Use:   "postgresql",
+		Short: "Install postgresql",
+		Long: `Install postgresql from the bitnami chart. A password is generated for the
+postgres user, or the password from an earlier install is kept.`,
+		Example:      `  k3sup app install postgresql --persistence --storage-class local-path`,
+		SilenceUsage: true,
+	}
+
+	postgresql.Flags().StringP("namespace", "n", "default", "The namespace used for installation")
+	postgresql.Flags().String("version", "", "The version of the postgresql chart (Default to the latest)")
+	postgresql.Flags().Bool("persistence", false, "Store data in a PersistentVolumeClaim, so that it is kept when the Pod restarts")
+	postgresql.Flags().String("storage-class", "", "The StorageClass for the PersistentVolumeClaim, the default class is used when not given")
+	postgresql.Flags().Bool("update-repo", true, "Update the helm repo")
+
+	postgresql.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		persistence, _ := command.Flags().GetBool("persistence")
+		storageClass, _ := command.Flags().GetString("storage-class")
+
+		if len(storageClass) > 0 && !persistence {
+			return fmt.Errorf("--storage-class can only be used with --persistence")
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		arch := getArchitecture()
+		logDebugf("Node architecture: %q\n", arch)
+
+		if !dryRun && arch != "x86_64" && arch != "amd64" {
+			return fmt.Errorf("The bitnami images for postgresql are only published for amd64, not %s", arch)
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("bitnami", "https://charts.bitnami.com/bitnami", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("postgresql", namespace); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "bitnami/postgresql", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		pass := ""
+		if !dryRun {
+			// The data directory is only initialised once, so the password
+			// from an earlier install is kept
+			pass, err = secretValue(namespace, "postgresql", "postgresql-password")
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(pass) == 0 {
+			pass, err = password.Generate(25, 10, 0, false, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		overrides := map[string]string{}
+		overrides["postgresqlPassword"] = pass
+		overrides["persistence.enabled"] = strconv.FormatBool(persistence)
+		if len(storageClass) > 0 {
+			overrides["persistence.storageClass"] = storageClass
+		}
+
+		outputPath := path.Join(chartPath, "postgresql/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "postgresql",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("postgresql", "postgresql", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "postgresql",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, outputPath)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("postgresql", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("postgresql", chartVersion(chartPath, "postgresql")); err != nil {
+			return err
+		}
+
+		logInfo(postgresqlInstallMsg)
+		logInfof(`# Connect with:
+#   host: postgresql.%s.svc.cluster.local
+#   port: 5432
+#   user: postgres
+#   password: %s
+`, namespace, pass)
+
+		return nil
+	}
+
+	return postgresql
+}
+
+const postgresqlInfoMsg = `# Get the password for the postgres user
+export POSTGRES_PASSWORD=$(kubectl get secret postgresql \
+  -o jsonpath="{.data.postgresql-password}" | base64 --decode)
+
+# Connect with a psql client
+kubectl run postgresql-client --rm --tty -i --restart='Never' \
+  --image docker.io/bitnami/postgresql --env="PGPASSWORD=$POSTGRES_PASSWORD" \
+  --command -- psql --host postgresql -U postgres -d postgres -p 5432
+
+# Or forward the port to your machine
+kubectl port-forward svc/postgresql 5432:5432 &
+
+# Give -n to kubectl when installed with --namespace
+
+# Find out more at:
+# https://github.com/bitnami/charts/tree/master/bitnami/postgresql`
+
+const postgresqlInstallMsg = `=======================================================================
+= postgresql has been installed.                                      =
+=======================================================================
+
+` + postgresqlInfoMsg + `
+
+` + thanksForUsing
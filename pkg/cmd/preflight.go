@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// minKubernetesMinor is the oldest minor release of Kubernetes 1.x which
+// the apps are tested with, it is that of the default config.K3sVersion
+const minKubernetesMinor = 15
+
+// preflightChecks verifies that the cluster can be reached and can run an
+// app before anything is installed, so that a problem is reported with what
+// to do about it instead of as an error from kubectl apply
+func preflightChecks(command *cobra.Command, args []string) error {
+	if err := applyFlags(command, args); err != nil {
+		return err
+	}
+
+	if command.Name() == "install" {
+		return nil
+	}
+
+	dryRun, _ := command.Flags().GetBool("dry-run")
+	skip, _ := command.Flags().GetBool("skip-preflight")
+	if dryRun || skip {
+		return nil
+	}
+
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		return fmt.Errorf("kubectl was not found in your PATH, get it from https://kubernetes.io/docs/tasks/tools/")
+	}
+
+	res, err := kubectlTask("version", "--output", "json")
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		return fmt.Errorf("unable to connect to the cluster, check that it is running and that KUBECONFIG or --kubeconfig is set: %s", strings.TrimSpace(res.Stderr))
+	}
+
+	serverVersion, err := checkServerVersion([]byte(res.Stdout))
+	if err != nil {
+		return err
+	}
+
+	res, err = kubectlTask("get", "nodes", "--output", "json")
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		return fmt.Errorf("unable to list the nodes of the cluster: %s", strings.TrimSpace(res.Stderr))
+	}
+
+	ready, total, archs, err := parseNodes([]byte(res.Stdout))
+	if err != nil {
+		return err
+	}
+
+	if ready == 0 {
+		return fmt.Errorf("none of the %d nodes in the cluster are Ready, check them with: kubectl describe nodes", total)
+	}
+
+	logInfof("Pre-flight: Kubernetes %s, %d/%d nodes Ready (%s)\n", serverVersion, ready, total, strings.Join(archs, ", "))
+
+	// Apps which add an Ingress with TLS need cert-manager's ClusterIssuer
+	if email, _ := command.Flags().GetString("email"); len(email) > 0 {
+		res, err := kubectlTask("get", "crd", "clusterissuers.cert-manager.io", "--ignore-not-found", "--output", "name")
+		if err != nil {
+			return err
+		}
+
+		if res.ExitCode != 0 || len(strings.TrimSpace(res.Stdout)) == 0 {
+			return fmt.Errorf("cert-manager is needed for a TLS certificate with --email, install it first with: k3sup app install cert-manager")
+		}
+	}
+
+	return nil
+}
+
+// checkServerVersion returns the server's version from the output of
+// kubectl version --output json, or an error when it is too old
+func checkServerVersion(data []byte) (string, error) {
+	version := struct {
+		ServerVersion *struct {
+			Major      string `json:"major"`
+			Minor      string `json:"minor"`
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}{}
+
+	if err := json.Unmarshal(data, &version); err != nil {
+		return "", fmt.Errorf("unable to parse kubectl version: %s", err)
+	}
+
+	if version.ServerVersion == nil {
+		return "", fmt.Errorf("unable to find the version of the cluster, check that KUBECONFIG or --kubeconfig is set")
+	}
+
+	// Managed clusters report versions such as 1.19+
+	major, _ := strconv.Atoi(strings.TrimRight(version.ServerVersion.Major, "+"))
+	minor, _ := strconv.Atoi(strings.TrimRight(version.ServerVersion.Minor, "+"))
+
+	if major == 1 && minor < minKubernetesMinor {
+		return "", fmt.Errorf("the cluster runs Kubernetes %s, apps need v1.%d or newer, upgrade it with: k3sup upgrade",
+			version.ServerVersion.GitVersion, minKubernetesMinor)
+	}
+
+	return version.ServerVersion.GitVersion, nil
+}
+
+// parseNodes returns the number of Ready nodes, the total number of nodes
+// and their architectures from the output of kubectl get nodes --output json
+func parseNodes(data []byte) (int, int, []string, error) {
+	nodes := struct {
+		Items []struct {
+			Status struct {
+				NodeInfo struct {
+					Architecture string `json:"architecture"`
+				} `json:"nodeInfo"`
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}{}
+
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return 0, 0, nil, fmt.Errorf("unable to parse kubectl output: %s", err)
+	}
+
+	ready := 0
+	seen := map[string]bool{}
+	archs := []string{}
+
+	for _, node := range nodes.Items {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == "Ready" && condition.Status == "True" {
+				ready++
+			}
+		}
+
+		arch := node.Status.NodeInfo.Architecture
+		if len(arch) > 0 && !seen[arch] {
+			seen[arch] = true
+			archs = append(archs, arch)
+		}
+	}
+
+	sort.Strings(archs)
+
+	return ready, len(nodes.Items), archs, nil
+}
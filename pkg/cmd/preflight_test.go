@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexellis/k3sup/pkg/config"
+)
+
+func Test_checkServerVersion(t *testing.T) {
+	got, err := checkServerVersion([]byte(`{"clientVersion":{"major":"1","minor":"20"},"serverVersion":{"major":"1","minor":"19+","gitVersion":"v1.19.5+k3s1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != "v1.19.5+k3s1" {
+		t.Errorf("want: %q, got: %q", "v1.19.5+k3s1", got)
+	}
+}
+
+func Test_checkServerVersion_TooOld(t *testing.T) {
+	_, err := checkServerVersion([]byte(`{"serverVersion":{"major":"1","minor":"14","gitVersion":"v1.14.6-k3s.1"}}`))
+	if err == nil || !strings.Contains(err.Error(), "v1.15 or newer") {
+		t.Errorf("want error for an old server, got: %v", err)
+	}
+}
+
+func Test_checkServerVersion_DefaultK3sVersion(t *testing.T) {
+	// The server version reported by each release of k3s used as the default
+	serverVersions := map[string]string{
+		"v0.9.1": `{"major":"1","minor":"15","gitVersion":"v1.15.4-k3s.1"}`,
+	}
+
+	server, ok := serverVersions[config.K3sVersion]
+	if !ok {
+		t.Fatalf("add the Kubernetes version of k3s %s", config.K3sVersion)
+	}
+
+	if _, err := checkServerVersion([]byte(`{"serverVersion":` + server + `}`)); err != nil {
+		t.Errorf("want a cluster installed with k3s %s to pass, got: %s", config.K3sVersion, err)
+	}
+}
+
+func Test_checkServerVersion_NoServer(t *testing.T) {
+	if _, err := checkServerVersion([]byte(`{"clientVersion":{"major":"1","minor":"20"}}`)); err == nil {
+		t.Errorf("want error without a server version")
+	}
+}
+
+func Test_parseNodes(t *testing.T) {
+	data := `{"items":[
+  {"status":{"nodeInfo":{"architecture":"arm64"},"conditions":[{"type":"Ready","status":"True"}]}},
+  {"status":{"nodeInfo":{"architecture":"amd64"},"conditions":[{"type":"Ready","status":"False"}]}},
+  {"status":{"nodeInfo":{"architecture":"arm64"},"conditions":[{"type":"Ready","status":"True"}]}}
+]}`
+
+	ready, total, archs, err := parseNodes([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ready != 2 || total != 3 {
+		t.Errorf("want 2/3 nodes Ready, got: %d/%d", ready, total)
+	}
+
+	if strings.Join(archs, ",") != "amd64,arm64" {
+		t.Errorf("want amd64,arm64, got: %v", archs)
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/provision"
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// scriptOperator records the commands which would be run on a node instead
+// of running them, for --print-command
+type scriptOperator struct {
+	lines []string
+}
+
+func (s *scriptOperator) Execute(command string) (kssh.CommandRes, error) {
+	s.lines = append(s.lines, strings.TrimSpace(command))
+	return kssh.CommandRes{}, nil
+}
+
+func (s *scriptOperator) ExecuteQuiet(command string) (kssh.CommandRes, error) {
+	return s.Execute(command)
+}
+
+// Upload notes where a file has to be copied to, as the script can not carry
+// files such as the k3s binary
+func (s *scriptOperator) Upload(r io.Reader, remotePath string) error {
+	s.lines = append(s.lines, fmt.Sprintf("# Copy the file to %s before running this script", remotePath))
+	return nil
+}
+
+// Download can not be recorded, as a script has nowhere to copy the file to
+func (s *scriptOperator) Download(remotePath string, w io.Writer) error {
+	return fmt.Errorf("unable to download %s: it can not be done by a script", remotePath)
+}
+
+func (s *scriptOperator) SetOutput(stdout, stderr io.Writer) {}
+
+func (s *scriptOperator) Close() error {
+	return nil
+}
+
+// redactDatastore leaves the credentials of datastore out of the recorded
+// commands, with a note to fill them in before the script is run
+func (s *scriptOperator) redactDatastore(datastore provision.Datastore) {
+	redacted := false
+	for i, line := range s.lines {
+		s.lines[i] = datastore.Redact(line)
+		redacted = redacted || s.lines[i] != line
+	}
+
+	if redacted {
+		s.lines = append([]string{"# Replace *** in K3S_DATASTORE_ENDPOINT with the user and password of the datastore"}, s.lines...)
+	}
+}
+
+// redactToken leaves the join-token out of the recorded commands, with a
+// note to fill it in before the script is run
+func (s *scriptOperator) redactToken(token string) {
+	redacted := false
+	for i, line := range s.lines {
+		s.lines[i] = provision.RedactToken(line, token)
+		redacted = redacted || s.lines[i] != line
+	}
+
+	if redacted {
+		s.lines = append([]string{"# Replace *** in K3S_TOKEN with the join-token, from: k3sup node-token"}, s.lines...)
+	}
+}
+
+// Script returns the recorded commands as a shell script, which can be run
+// by hand or given as cloud-init user-data
+func (s *scriptOperator) Script() string {
+	return "#!/bin/sh\nset -e\n\n" + strings.Join(s.lines, "\n") + "\n"
+}
+
+// printScript prints the script recorded by operator as the result of the
+// command, with --output json it is part of the result instead
+func printScript(operator *scriptOperator) {
+	script := operator.Script()
+	updateResult(func(r *commandResult) {
+		r.Script = script
+	})
+
+	if outputFormat != "json" {
+		fmt.Fprint(resultStdout, script)
+	}
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// progressInterval is how often a spinner or progress bar is redrawn
+const progressInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+var (
+	// progressLock guards activeProgress, which is the progress line being
+	// drawn, so that other output can clear it first
+	progressLock   sync.Mutex
+	activeProgress *progress
+)
+
+// progressTerminal reports whether spinners and progress bars can be drawn
+// on stdout. When stdout is not a terminal, as in CI or when piped to a
+// file, each step is printed on a line of its own instead.
+var progressTerminal = func() bool {
+	return terminal.IsTerminal(int(textOutput.Fd())) && os.Getenv("TERM") != "dumb"
+}
+
+// progress is a line which shows a spinner, how long a step has taken and
+// the latest detail, such as the last line printed by the k3s installer or
+// how much of a download has been received
+type progress struct {
+	out     *os.File
+	message string
+	detail  string
+	output  bytes.Buffer
+	start   time.Time
+	lock    sync.Mutex
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// startProgress prints message, then redraws it with a spinner until Stop
+// is called. Only one line is drawn at a time, so while another is active,
+// as for the agents of a plan, message is printed as it is with --quiet or
+// without a terminal.
+func startProgress(format string, a ...interface{}) *progress {
+	p := &progress{
+		out:     textOutput,
+		message: fmt.Sprintf(format, a...),
+		start:   time.Now(),
+	}
+
+	progressLock.Lock()
+	draw := level == infoLevel && activeProgress == nil && progressTerminal()
+	if draw {
+		activeProgress = p
+	}
+	progressLock.Unlock()
+
+	if !draw {
+		logInfof("%s\n", p.message)
+		return p
+	}
+
+	p.done = make(chan struct{})
+	p.stopped = make(chan struct{})
+	go p.run()
+
+	return p
+}
+
+// drawing reports whether p is drawn as a spinner, rather than printed once
+func (p *progress) drawing() bool {
+	return p.done != nil
+}
+
+func (p *progress) run() {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	defer close(p.stopped)
+
+	for frame := 0; ; frame++ {
+		p.redraw(spinnerFrames[frame%len(spinnerFrames)])
+
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *progress) redraw(symbol string) {
+	p.lock.Lock()
+	line := fmt.Sprintf("%s %s (%s)", symbol, p.message, time.Since(p.start).Round(time.Second))
+	if len(p.detail) > 0 {
+		line += " " + p.detail
+	}
+	p.lock.Unlock()
+
+	progressLock.Lock()
+	defer progressLock.Unlock()
+
+	fmt.Fprintf(p.out, "\r\x1b[K%s", fitTerminal(p.out, line))
+}
+
+// fitTerminal cuts line to the width of the terminal, as a line which wraps
+// can not be redrawn with a carriage return
+func fitTerminal(out *os.File, line string) string {
+	width, _, err := terminal.GetSize(int(out.Fd()))
+	if err != nil || width <= 1 {
+		return line
+	}
+
+	runes := []rune(line)
+	if len(runes) >= width {
+		return string(runes[:width-1])
+	}
+	return line
+}
+
+// Update sets the detail shown after the message
+func (p *progress) Update(format string, a ...interface{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.detail = fmt.Sprintf(format, a...)
+}
+
+// Write shows the last line written as the detail, so that p can be given
+// to SetOutput for the output of a script. The output is kept, to be
+// printed by Stop if the script fails.
+func (p *progress) Write(data []byte) (int, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); len(last) > 0 {
+		p.Update("%s", last)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.output.Write(data)
+}
+
+// Stop replaces the spinner with a tick, or a cross when err is set. The
+// error itself is returned to the caller to be printed. Only the first call
+// has any effect.
+func (p *progress) Stop(err error) {
+	if !p.drawing() {
+		return
+	}
+
+	close(p.done)
+	<-p.stopped
+	p.done = nil
+
+	symbol := "✔"
+	if err != nil {
+		symbol = "✘"
+	}
+
+	p.lock.Lock()
+	p.detail = ""
+	p.lock.Unlock()
+	p.redraw(symbol)
+
+	progressLock.Lock()
+	fmt.Fprintln(p.out)
+	activeProgress = nil
+	progressLock.Unlock()
+
+	if err != nil && p.output.Len() > 0 {
+		fmt.Fprint(p.out, p.output.String())
+	}
+}
+
+// clearProgress removes the line being drawn, so that a message can be
+// printed in its place. The line is drawn again below the message.
+func clearProgress() {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+
+	if activeProgress != nil {
+		fmt.Fprint(activeProgress.out, "\r\x1b[K")
+	}
+}
+
+// startDownloadProgress draws a progress bar for a download of url, which
+// is only drawn on a terminal, as the callers of downloadFile print which
+// file is being downloaded
+func startDownloadProgress(url string) *progress {
+	progressLock.Lock()
+	draw := level == infoLevel && activeProgress == nil && progressTerminal()
+	progressLock.Unlock()
+
+	if !draw {
+		return &progress{}
+	}
+	return startProgress("Downloading %s", path.Base(url))
+}
+
+// runInstallerWithProgress runs install with a spinner which shows the last
+// line printed by the k3s installer on operator
+func runInstallerWithProgress(operator kssh.Operator, message string, install func() error) error {
+	p := startProgress("%s", message)
+	if p.drawing() {
+		operator.SetOutput(p, p)
+		defer operator.SetOutput(textOutput, os.Stderr)
+	}
+
+	err := install()
+	p.Stop(err)
+	return err
+}
+
+// downloadProgress counts the bytes read from a download into the detail
+// of a progress, total is -1 when the size is not known
+type downloadProgress struct {
+	reader   io.Reader
+	progress *progress
+	read     int64
+	total    int64
+}
+
+func (d *downloadProgress) Read(data []byte) (int, error) {
+	n, err := d.reader.Read(data)
+	d.read += int64(n)
+
+	if d.total > 0 {
+		d.progress.Update("%s %3d%% of %s", progressBar(d.read, d.total, 20), d.read*100/d.total, formatBytes(d.total))
+	} else {
+		d.progress.Update("%s", formatBytes(d.read))
+	}
+
+	return n, err
+}
+
+// progressBar draws a bar of width characters for read of total
+func progressBar(read, total int64, width int) string {
+	filled := int(read * int64(width) / total)
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d B", n)
+}
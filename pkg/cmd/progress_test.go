@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_progressBar(t *testing.T) {
+	tests := map[int64]string{
+		0:   "[          ]",
+		50:  "[=====     ]",
+		100: "[==========]",
+		150: "[==========]",
+	}
+
+	for read, want := range tests {
+		if got := progressBar(read, 100, 10); got != want {
+			t.Errorf("%d of 100: want %q, got %q", read, want, got)
+		}
+	}
+}
+
+func Test_formatBytes(t *testing.T) {
+	tests := map[int64]string{
+		512:              "512 B",
+		2048:             "2.0 KB",
+		27 * 1024 * 1024: "27.0 MB",
+	}
+
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("%d: want %q, got %q", n, want, got)
+		}
+	}
+}
+
+// captureProgress runs fn with stdout written to a file, and a terminal
+// when drawing is set
+func captureProgress(t *testing.T, drawing bool, fn func()) string {
+	file, err := ioutil.TempFile("", "k3sup-progress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	stdout, terminal := textOutput, progressTerminal
+	textOutput = file
+	progressTerminal = func() bool { return drawing }
+	defer func() {
+		textOutput, progressTerminal = stdout, terminal
+	}()
+
+	fn()
+	file.Close()
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func Test_startProgress_Plain(t *testing.T) {
+	output := captureProgress(t, false, func() {
+		p := startProgress("Installing k3s on %s", "192.168.0.100")
+		fmt.Fprintln(p, "[INFO]  Using v1.19.5+k3s1 as release")
+		p.Stop(nil)
+	})
+
+	if output != "Installing k3s on 192.168.0.100\n" {
+		t.Errorf("want only the message without a terminal, got %q", output)
+	}
+}
+
+func Test_startProgress_Terminal(t *testing.T) {
+	output := captureProgress(t, true, func() {
+		p := startProgress("Installing k3s on %s", "192.168.0.100")
+		p.Stop(nil)
+		p.Stop(nil)
+	})
+
+	if !strings.Contains(output, "✔ Installing k3s on 192.168.0.100") || !strings.HasSuffix(output, "\n") {
+		t.Errorf("want a tick for the step, got %q", output)
+	}
+	if activeProgress != nil {
+		t.Errorf("want no active progress after Stop")
+	}
+}
+
+func Test_startProgress_TerminalFailed(t *testing.T) {
+	output := captureProgress(t, true, func() {
+		p := startProgress("Installing k3s on %s", "192.168.0.100")
+		fmt.Fprintln(p, "[ERROR] Can not find systemd")
+		p.Stop(fmt.Errorf("exit status 1"))
+	})
+
+	if !strings.Contains(output, "✘ Installing k3s") || !strings.HasSuffix(output, "[ERROR] Can not find systemd\n") {
+		t.Errorf("want a cross and the output of the failed step, got %q", output)
+	}
+}
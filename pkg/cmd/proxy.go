@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/alexellis/k3sup/pkg/provision"
+	"github.com/spf13/cobra"
+)
+
+// addProxyFlags adds the flags for nodes which reach the internet through a
+// proxy
+func addProxyFlags(command *cobra.Command) {
+	command.Flags().String("http-proxy", "", "The proxy for HTTP requests made by the installer and k3s, i.e. http://proxy.example.com:3128")
+	command.Flags().String("https-proxy", "", "The proxy for HTTPS requests made by the installer and k3s")
+	command.Flags().String("no-proxy", "", "A comma-separated list of hosts and networks which are reached without the proxy, such as the cluster and service networks")
+}
+
+// proxyFromFlags reads the flags added by addProxyFlags
+func proxyFromFlags(command *cobra.Command) (provision.Proxy, error) {
+	httpProxy, _ := command.Flags().GetString("http-proxy")
+	httpsProxy, _ := command.Flags().GetString("https-proxy")
+	noProxy, _ := command.Flags().GetString("no-proxy")
+
+	proxy := provision.Proxy{
+		HTTP:    httpProxy,
+		HTTPS:   httpsProxy,
+		NoProxy: noProxy,
+	}
+
+	return proxy, proxy.Validate()
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallRancher() *cobra.Command {
+	var rancher = &cobra.Command{
+		Use:   "rancher",
+		Short: "Install rancher",
+		Long: `Install rancher to manage the cluster from a web UI, at --domain. cert-manager
+is installed first when it is missing, to issue rancher's certificate. Give
+--letsencrypt-email to use a certificate from Let's Encrypt instead of one
+signed by rancher's own CA.`,
+		Example: `  k3sup app install rancher --domain rancher.example.com
+  k3sup app install rancher --domain rancher.example.com --letsencrypt-email admin@example.com`,
+		SilenceUsage: true,
+	}
+
+	rancher.Flags().StringP("domain", "d", "", "The hostname for rancher, which must resolve to the cluster")
+	rancher.Flags().String("letsencrypt-email", "", "Use a certificate from Let's Encrypt, registered with this email")
+	rancher.Flags().Int("replicas", 1, "The number of rancher replicas")
+	rancher.Flags().String("version", "", "The version of the rancher chart (Default to the latest)")
+	rancher.Flags().Bool("update-repo", true, "Update the helm repo")
+
+	rancher.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		domain, _ := command.Flags().GetString("domain")
+		email, _ := command.Flags().GetString("letsencrypt-email")
+		replicas, _ := command.Flags().GetInt("replicas")
+		version, _ := command.Flags().GetString("version")
+		namespace := "cattle-system"
+
+		if len(domain) == 0 {
+			return fmt.Errorf("--domain is required, give the hostname for rancher i.e. rancher.example.com")
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			logWarn("cert-manager is needed by rancher, and is not included")
+		} else {
+			installed, err := certManagerInstalled()
+			if err != nil {
+				return err
+			}
+
+			if !installed {
+				logInfo("Installing cert-manager, which is needed by rancher")
+				if err := installDependency(command, makeInstallCertManager()); err != nil {
+					return fmt.Errorf("unable to install cert-manager: %s", err)
+				}
+			}
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("rancher-stable", "https://releases.rancher.com/server-charts/stable", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("rancher", namespace); err != nil {
+				return err
+			}
+		}
+
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "rancher-stable/rancher", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		overrides := map[string]string{}
+		overrides["hostname"] = domain
+		overrides["replicas"] = strconv.Itoa(replicas)
+		if len(email) > 0 {
+			overrides["ingress.tls.source"] = "letsEncrypt"
+			overrides["letsEncrypt.email"] = email
+		}
+
+		outputPath := path.Join(chartPath, "rancher/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "rancher",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("rancher", "rancher", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "rancher",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				return printManifests(command, outputPath)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("rancher", outputPath); err != nil {
+				return err
+			}
+		}
+
+		logInfo("Waiting for rancher to start, it can take a few minutes")
+		err = kubectl("rollout", "status", "--namespace", namespace, "deploy/rancher", "--timeout", "10m")
+		if err != nil {
+			return fmt.Errorf("rancher did not become ready: %s", err)
+		}
+
+		if err := recordInstalled("rancher", chartVersion(chartPath, "rancher")); err != nil {
+			return err
+		}
+
+		logInfo(rancherInstallMsg)
+		logInfof("# Then open: https://%s\n", domain)
+
+		return nil
+	}
+
+	return rancher
+}
+
+// certManagerInstalled reports whether the cert-manager CRDs exist, which
+// may have been installed without k3sup
+func certManagerInstalled() (bool, error) {
+	res, err := kubectlTask("get", "crd", "certificates.cert-manager.io", "--ignore-not-found", "--output", "name")
+	if err != nil {
+		return false, err
+	}
+
+	if res.ExitCode != 0 {
+		return false, fmt.Errorf("unable to check for cert-manager: %s", res.Stderr)
+	}
+
+	return len(res.Stdout) > 0, nil
+}
+
+const rancherInfoMsg = `# Your domain must point to your cluster and be accessible through ports
+# 80 and 443 to log in.
+
+# Get the bootstrap password for the admin user, you will be asked to
+# change it when you first log in
+kubectl get secret --namespace cattle-system bootstrap-secret \
+  -o go-template='{{.data.bootstrapPassword|base64decode}}{{"\n"}}'
+
+# Find out more at:
+# https://rancher.com/docs/rancher/v2.x/en/`
+
+const rancherInstallMsg = `=======================================================================
+= rancher has been installed.                                         =
+=======================================================================
+
+` + rancherInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/alexellis/k3sup/pkg/provision"
+	"github.com/spf13/cobra"
+)
+
+// addRegistryFlags adds the flags to upload registries.yaml to a node
+func addRegistryFlags(command *cobra.Command) {
+	command.Flags().String("registry-mirrors-file", "", "Local path to a registries.yaml for private registries and mirrors, uploaded to "+provision.RegistriesPath)
+	command.Flags().StringArray("registry-mirror", []string{}, "A mirror for a registry as registry=endpoint, i.e. docker.io=https://mirror.example.com:5000, can be repeated")
+}
+
+// registryFromFlags reads the flags added by addRegistryFlags
+func registryFromFlags(command *cobra.Command) (provision.Registry, error) {
+	file, _ := command.Flags().GetString("registry-mirrors-file")
+	mirrors, _ := command.Flags().GetStringArray("registry-mirror")
+
+	registry := provision.Registry{
+		File:    file,
+		Mirrors: mirrors,
+	}
+
+	return registry, registry.Validate()
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/config"
+	"github.com/sethvargo/go-password/password"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallRegistry() *cobra.Command {
+	var registry = &cobra.Command{
+		Use:   "registry",
+		Short: "Install a private docker registry with TLS",
+		Long: `Install a private docker registry from the docker-registry chart, with an
+Ingress and a TLS certificate from Let's Encrypt, which requires cert-manager
+and nginx-ingress. A password is generated for the admin user, and kept
+when installing again.`,
+		Example:      `  k3sup app install registry --domain registry.example.com --email admin@example.com`,
+		SilenceUsage: true,
+	}
+
+	registry.Flags().StringP("namespace", "n", "default", "The namespace used for installation")
+	registry.Flags().String("version", "", "The version of the docker-registry chart (Default to the latest)")
+	registry.Flags().String("username", "admin", "The user for docker login")
+	registry.Flags().Bool("persistence", true, "Store images in a PersistentVolumeClaim, so that they are kept when the Pod restarts")
+	registry.Flags().String("size", "10Gi", "The size of the PersistentVolumeClaim")
+	registry.Flags().Bool("update-repo", true, "Update the helm repo")
+	addIngressFlags(registry)
+
+	registry.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		username, _ := command.Flags().GetString("username")
+		persistence, _ := command.Flags().GetBool("persistence")
+		size, _ := command.Flags().GetString("size")
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		inputData, err := ingressFromFlags(command)
+		if err != nil {
+			return err
+		}
+		inputData.IngressName = "docker-registry"
+		inputData.Namespace = namespace
+		inputData.ServiceName = "docker-registry"
+		inputData.ServicePort = 5000
+		inputData.TLSSecret = "docker-registry-tls"
+		// Image layers are larger than the default limit of nginx
+		inputData.Annotations = map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "0"}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("registry", namespace); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "stable/docker-registry", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		pass := ""
+		if !dryRun {
+			pass, err = createRegistryLoginSecret(namespace, username)
+			if err != nil {
+				return err
+			}
+		} else {
+			pass, err = password.Generate(25, 10, 0, false, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		htpasswd, err := registryHtpasswd(username, pass)
+		if err != nil {
+			return err
+		}
+
+		overrides := map[string]string{}
+		overrides["secrets.htpasswd"] = htpasswd
+		overrides["persistence.enabled"] = strconv.FormatBool(persistence)
+		if persistence {
+			overrides["persistence.size"] = size
+		}
+
+		outputPath := path.Join(chartPath, "docker-registry/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, overrides)
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "docker-registry",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("registry", "docker-registry", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "docker-registry",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				ingressYaml, err := buildYaml(inputData)
+				if err != nil {
+					return err
+				}
+
+				ingressFile, err := writeTempFile("registry", ingressYaml)
+				if err != nil {
+					return err
+				}
+
+				logWarn(`The "docker-registry-login" secret is generated when the registry is installed, so it is not included`)
+				return printManifests(command, outputPath, ingressFile)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("registry", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if err := applyIngress(command, "registry", inputData); err != nil {
+			return err
+		}
+
+		if err := recordInstalled("registry", chartVersion(chartPath, "docker-registry")); err != nil {
+			return err
+		}
+
+		logInfo(registryInstallMsg)
+		logInfof(`# Log in with:
+echo -n %s | docker login %s --username %s --password-stdin
+`, pass, inputData.IngressDomain, username)
+
+		return nil
+	}
+
+	return registry
+}
+
+// createRegistryLoginSecret generates the password for docker login, the
+// password from an existing secret is kept as only its hash is given to the
+// registry
+func createRegistryLoginSecret(namespace, username string) (string, error) {
+	pass, err := secretValue(namespace, "docker-registry-login", "password")
+	if err != nil {
+		return "", err
+	}
+
+	if len(pass) > 0 {
+		logInfo("Using the existing docker-registry-login secret")
+		return pass, recordSecret("registry", "docker-registry-login", namespace)
+	}
+
+	pass, err = password.Generate(25, 10, 0, false, true)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := kubectlTask("-n", namespace, "create", "secret", "generic",
+		"docker-registry-login",
+		"--from-literal=username="+username,
+		"--from-literal=password="+pass)
+	if err != nil {
+		return "", err
+	}
+
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("unable to create the docker-registry-login secret: %s", res.Stderr)
+	}
+
+	return pass, recordSecret("registry", "docker-registry-login", namespace)
+}
+
+// registryHtpasswd returns an htpasswd entry, the registry only accepts
+// bcrypt hashes
+func registryHtpasswd(username, pass string) (string, error) {
+	if len(username) == 0 || strings.Contains(username, ":") {
+		return "", fmt.Errorf("--username must be set and cannot contain a colon")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return username + ":" + string(hash), nil
+}
+
+const registryInfoMsg = `# Get the password for docker login
+export PASSWORD=$(kubectl get secret docker-registry-login \
+  -o jsonpath="{.data.password}" | base64 --decode)
+
+# Your domain must point to your cluster and be accessible through ports
+# 80 and 443, unless you used --dns01-provider. Check the certificate with:
+kubectl describe certificate docker-registry-tls
+
+# Log in, then push an image
+echo -n $PASSWORD | docker login REGISTRY_DOMAIN --username admin --password-stdin
+docker tag alpine:3.11 REGISTRY_DOMAIN/alpine:3.11
+docker push REGISTRY_DOMAIN/alpine:3.11
+
+# Give -n to kubectl when installed with --namespace
+
+# Find out more at:
+# https://github.com/helm/charts/tree/master/stable/docker-registry`
+
+const registryInstallMsg = `=======================================================================
+= registry has been installed.                                        =
+=======================================================================
+
+` + registryInfoMsg + `
+
+` + thanksForUsing
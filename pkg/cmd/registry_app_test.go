@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_registryHtpasswd_MatchesPassword(t *testing.T) {
+	entry, err := registryHtpasswd("admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.SplitN(entry, ":", 2)
+	if parts[0] != "admin" {
+		t.Errorf("want user admin, got: %q", parts[0])
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(parts[1]), []byte("secret")); err != nil {
+		t.Errorf("want hash of the password, got: %s", err)
+	}
+}
+
+func Test_registryHtpasswd_RejectsColon(t *testing.T) {
+	if _, err := registryHtpasswd("ad:min", "secret"); err == nil {
+		t.Errorf("want error for a username with a colon")
+	}
+}
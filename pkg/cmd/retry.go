@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// maxRetryInterval caps the backoff, so that a slow node is still polled
+// regularly
+const maxRetryInterval = 30 * time.Second
+
+var (
+	sshConnectTimeout = 30 * time.Second
+	retries           = 0
+	retryInterval     = 2 * time.Second
+)
+
+// AddRetryFlags adds --ssh-connect-timeout, --retries and --retry-interval
+// to root and all of its sub-commands
+func AddRetryFlags(root *cobra.Command) {
+	root.PersistentFlags().DurationVar(&sshConnectTimeout, "ssh-connect-timeout", sshConnectTimeout, "How long to wait for each SSH connection to be established")
+	root.PersistentFlags().IntVar(&retries, "retries", retries, "Retry an SSH connection which is refused or times out this many times, as for a node which is still booting")
+	root.PersistentFlags().DurationVar(&retryInterval, "retry-interval", retryInterval, "The wait before the first retry, doubled for each one after it, also the first interval when waiting for a node to be Ready")
+
+	flagHooks = append(flagHooks, func() error {
+		if retries < 0 || retryInterval <= 0 || sshConnectTimeout <= 0 {
+			return fmt.Errorf("--retries can not be negative, and --retry-interval and --ssh-connect-timeout must be more than 0")
+		}
+		return nil
+	})
+}
+
+// retryBackoff returns the wait before retry number attempt, counting from
+// 0, which is retryInterval doubled for each attempt
+func retryBackoff(attempt int) time.Duration {
+	wait := retryInterval
+	for i := 0; i < attempt && wait < maxRetryInterval; i++ {
+		wait *= 2
+	}
+	if wait > maxRetryInterval {
+		wait = maxRetryInterval
+	}
+	return wait
+}
+
+// withRetries runs fn, and again up to --retries times with a backoff while
+// it returns an error for which retryable is true
+func withRetries(retryable func(error) bool, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retries || !retryable(err) {
+			return err
+		}
+
+		wait := retryBackoff(attempt)
+		logInfof("%s, retrying in %s (%d/%d)\n", err, wait, attempt+1, retries)
+		time.Sleep(wait)
+	}
+}
+
+// retryableSSHError reports whether a connection may succeed later, as when
+// sshd has not started yet. Authentication and host key errors are not
+// retried.
+func retryableSSHError(err error) bool {
+	connErr, ok := err.(*kssh.ConnectionError)
+	if !ok {
+		return false
+	}
+
+	switch connErr.Reason {
+	case "connection refused", "timed out", "host unreachable":
+		return true
+	}
+
+	msg := connErr.Err.Error()
+	return strings.HasSuffix(msg, "EOF") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+func Test_retryBackoff(t *testing.T) {
+	defer func(interval time.Duration) { retryInterval = interval }(retryInterval)
+	retryInterval = 2 * time.Second
+
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	for attempt, w := range want {
+		if got := retryBackoff(attempt); got != w {
+			t.Errorf("attempt %d: want %s, got %s", attempt, w, got)
+		}
+	}
+}
+
+func Test_withRetries(t *testing.T) {
+	defer func(n int, interval time.Duration) { retries, retryInterval = n, interval }(retries, retryInterval)
+	retries, retryInterval = 3, time.Millisecond
+
+	refused := &kssh.ConnectionError{Address: "192.168.0.100:22", Reason: "connection refused", Err: fmt.Errorf("dial tcp: connection refused")}
+
+	calls := 0
+	err := withRetries(retryableSSHError, func() error {
+		calls++
+		if calls < 3 {
+			return refused
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("want success on the third call, got %d: %v", calls, err)
+	}
+
+	calls = 0
+	withRetries(retryableSSHError, func() error {
+		calls++
+		return refused
+	})
+	if calls != 4 {
+		t.Errorf("want one call and 3 retries, got %d", calls)
+	}
+
+	calls = 0
+	withRetries(retryableSSHError, func() error {
+		calls++
+		return &kssh.ConnectionError{Reason: "authentication failed", Err: fmt.Errorf("ssh: unable to authenticate")}
+	})
+	if calls != 1 {
+		t.Errorf("want no retry for an authentication failure, got %d calls", calls)
+	}
+}
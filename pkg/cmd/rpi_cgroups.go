@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// rpiCgroupArgs are the kernel arguments which Raspberry Pi OS leaves out,
+// without them the memory cgroup is disabled and k3s will not start
+var rpiCgroupArgs = []string{"cgroup_memory=1", "cgroup_enable=memory"}
+
+// rebootTimeout is how long to wait for a node to come back after it was
+// rebooted by --fix-rpi-cgroups
+var rebootTimeout = 5 * time.Minute
+
+// rebootPollInterval is the time between attempts to reconnect to a node
+// which is rebooting
+var rebootPollInterval = 5 * time.Second
+
+// fixRPiCgroups adds rpiCgroupArgs to cmdline.txt of a Raspberry Pi which
+// is missing them, and reboots it. It returns true when the node is
+// rebooting, other nodes are left as they are.
+func fixRPiCgroups(operator kssh.Operator, host, sudoPrefix string) (bool, error) {
+	res, err := operator.ExecuteQuiet("cat /proc/device-tree/model 2> /dev/null; true")
+	if err != nil {
+		return false, err
+	}
+
+	model := strings.Trim(string(res.StdOut), "\x00\n")
+	if !strings.Contains(model, "Raspberry Pi") {
+		logDebugf("%s is not a Raspberry Pi, cmdline.txt is not changed\n", host)
+		return false, nil
+	}
+
+	// Raspberry Pi OS Bookworm moved the boot partition to /boot/firmware
+	res, err = operator.ExecuteQuiet("if [ -f /boot/firmware/cmdline.txt ]; then echo /boot/firmware/cmdline.txt; else echo /boot/cmdline.txt; fi")
+	if err != nil {
+		return false, err
+	}
+	cmdlinePath := strings.TrimSpace(string(res.StdOut))
+
+	res, err = operator.ExecuteQuiet("cat " + cmdlinePath)
+	if err != nil {
+		return false, fmt.Errorf("unable to read %s on %s: %s", cmdlinePath, host, err)
+	}
+
+	missing := missingCmdlineArgs(string(res.StdOut))
+	if len(missing) == 0 {
+		logDebugf("The memory cgroup is already enabled in %s on %s\n", cmdlinePath, host)
+		return false, nil
+	}
+
+	logInfof("Adding %s to %s on %s (%s), then rebooting\n", strings.Join(missing, " "), cmdlinePath, host, model)
+
+	commands := []string{
+		fmt.Sprintf("%scp %s %s.k3sup.bak", sudoPrefix, cmdlinePath, cmdlinePath),
+		fmt.Sprintf("%ssed -i '1 s/$/ %s/' %s", sudoPrefix, strings.Join(missing, " "), cmdlinePath),
+		// The reboot is delayed so that the command returns before the
+		// connection is dropped
+		fmt.Sprintf("nohup %ssh -c 'sleep 2; reboot' > /dev/null 2>&1 &", sudoPrefix),
+	}
+
+	for _, command := range commands {
+		logDebugf("ssh: %s\n", command)
+		if _, err := operator.Execute(command); err != nil {
+			return false, fmt.Errorf("unable to enable the memory cgroup on %s: %s", host, err)
+		}
+	}
+
+	return true, nil
+}
+
+// missingCmdlineArgs returns the rpiCgroupArgs which are not in cmdline
+func missingCmdlineArgs(cmdline string) []string {
+	given := map[string]bool{}
+	for _, arg := range strings.Fields(cmdline) {
+		given[arg] = true
+	}
+
+	missing := []string{}
+	for _, arg := range rpiCgroupArgs {
+		if !given[arg] {
+			missing = append(missing, arg)
+		}
+	}
+	return missing
+}
+
+// waitForReboot reconnects with connect once the node is back up after a
+// reboot, giving up after rebootTimeout
+func waitForReboot(host string, connect func() (kssh.Operator, error)) (kssh.Operator, error) {
+	logInfof("Waiting up to %s for %s to reboot\n", rebootTimeout, host)
+
+	deadline := time.Now().Add(rebootTimeout)
+
+	// Give the node time to go down, so that the old sshd is not reached
+	time.Sleep(2 * rebootPollInterval)
+
+	for {
+		operator, err := connect()
+		if err == nil {
+			logInfof("%s is back up\n", host)
+			return operator, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for %s to reboot: %s", rebootTimeout, host, err)
+		}
+
+		logDebugf("Waiting for %s: %s\n", host, err)
+		time.Sleep(rebootPollInterval)
+	}
+}
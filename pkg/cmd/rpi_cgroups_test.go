@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+func Test_missingCmdlineArgs(t *testing.T) {
+	cmdline := "console=serial0,115200 console=tty1 root=PARTUUID=6c586e13-02 rootfstype=ext4 cgroup_enable=memory rootwait\n"
+
+	got := missingCmdlineArgs(cmdline)
+	if len(got) != 1 || got[0] != "cgroup_memory=1" {
+		t.Errorf("want cgroup_memory=1 to be missing, got: %v", got)
+	}
+
+	if got := missingCmdlineArgs(cmdline + " cgroup_memory=1"); len(got) != 0 {
+		t.Errorf("want nothing missing, got: %v", got)
+	}
+}
+
+func Test_fixRPiCgroups(t *testing.T) {
+	pi := &nodeOperator{replies: map[string]string{
+		"cat /proc/device-tree/model": "Raspberry Pi 4 Model B Rev 1.4\x00",
+		"if [ -f /boot/firmware":      "/boot/firmware/cmdline.txt\n",
+		"cat /boot/firmware":          "console=tty1 root=PARTUUID=6c586e13-02 rootwait\n",
+	}}
+
+	rebooted, err := fixRPiCgroups(pi, "192.168.0.101", "sudo ")
+	if err != nil || !rebooted {
+		t.Fatalf("want a reboot, got: %v %v", rebooted, err)
+	}
+
+	script := pi.Script()
+	for _, want := range []string{
+		"sudo cp /boot/firmware/cmdline.txt /boot/firmware/cmdline.txt.k3sup.bak",
+		"sudo sed -i '1 s/$/ cgroup_memory=1 cgroup_enable=memory/' /boot/firmware/cmdline.txt",
+		"nohup sudo sh -c 'sleep 2; reboot'",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("want %q in: %q", want, script)
+		}
+	}
+
+	other := &nodeOperator{replies: map[string]string{"cat /proc/device-tree/model": ""}}
+	if rebooted, err := fixRPiCgroups(other, "192.168.0.102", "sudo "); err != nil || rebooted || len(other.lines) != 0 {
+		t.Errorf("want other machines to be left alone, got: %v %v %v", rebooted, err, other.lines)
+	}
+}
+
+func Test_waitForReboot(t *testing.T) {
+	defer func(timeout, interval time.Duration) {
+		rebootTimeout, rebootPollInterval = timeout, interval
+	}(rebootTimeout, rebootPollInterval)
+	rebootTimeout, rebootPollInterval = time.Second, time.Millisecond
+
+	attempts := 0
+	operator, err := waitForReboot("192.168.0.101", func() (kssh.Operator, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return &scriptOperator{}, nil
+	})
+	if err != nil || operator == nil || attempts != 3 {
+		t.Errorf("want to reconnect on the third attempt, got: %d %v", attempts, err)
+	}
+
+	_, err = waitForReboot("192.168.0.101", func() (kssh.Operator, error) {
+		return nil, fmt.Errorf("connection refused")
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("want a timeout, got: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallSealedSecrets() *cobra.Command {
+	var sealedSecrets = &cobra.Command{
+		Use:   "sealed-secrets",
+		Short: "Install sealed-secrets",
+		Long: `Install the sealed-secrets controller, which decrypts SealedSecrets made
+with kubeseal into Secrets, so that they can be kept in git.
+
+Give --recover-keys with a backup of the controller's keys to decrypt
+SealedSecrets which were made for another cluster, or before a reinstall.`,
+		Example: `  k3sup app install sealed-secrets
+  k3sup app install sealed-secrets --recover-keys ./sealed-secrets-keys.yaml`,
+		SilenceUsage: true,
+	}
+
+	sealedSecrets.Flags().StringP("namespace", "n", "kube-system", "The namespace used for installation, give kubeseal the same --controller-namespace")
+	sealedSecrets.Flags().String("version", "v0.16.0", "The version of sealed-secrets to install")
+	sealedSecrets.Flags().String("recover-keys", "", "A file with the keys from an earlier install, to restore before the controller starts")
+
+	sealedSecrets.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		version, _ := command.Flags().GetString("version")
+		recoverKeys, _ := command.Flags().GetString("recover-keys")
+
+		manifests, err := setManifestNamespace("kube-system", namespace,
+			fmt.Sprintf("https://github.com/bitnami-labs/sealed-secrets/releases/download/%s/controller.yaml", version))
+		if err != nil {
+			return err
+		}
+
+		if len(recoverKeys) > 0 {
+			recoverKeys = expandPath(recoverKeys)
+			if _, err := os.Stat(recoverKeys); err != nil {
+				return fmt.Errorf("unable to read --recover-keys: %s", err)
+			}
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			if len(recoverKeys) > 0 {
+				logWarn("The keys from --recover-keys are not included")
+			}
+			return printManifests(command, manifests...)
+		}
+
+		if err := createNamespace("sealed-secrets", namespace); err != nil {
+			return err
+		}
+
+		// The keys are not recorded, so that they are kept by
+		// "k3sup app uninstall" for SealedSecrets which are still in git
+		if len(recoverKeys) > 0 {
+			if err := kubectl("apply", "--namespace", namespace, "-f", recoverKeys); err != nil {
+				return fmt.Errorf("unable to restore the keys: %s", err)
+			}
+		}
+
+		if err := applyManifests("sealed-secrets", manifests...); err != nil {
+			return err
+		}
+
+		// The controller only loads its keys when it starts, which it may
+		// already have done for an earlier install
+		if len(recoverKeys) > 0 {
+			err := kubectl("rollout", "restart", "deploy/sealed-secrets-controller", "--namespace", namespace)
+			if err != nil {
+				return fmt.Errorf("unable to restart the sealed-secrets controller: %s", err)
+			}
+		}
+
+		if err := recordInstalled("sealed-secrets", version); err != nil {
+			return err
+		}
+
+		logInfo(sealedSecretsInstallMsg)
+
+		return nil
+	}
+
+	return sealedSecrets
+}
+
+const sealedSecretsInfoMsg = `# Get the kubeseal CLI, then fetch the public certificate, which can be
+# shared to seal secrets without access to the cluster
+kubeseal --fetch-cert > pub-cert.pem
+
+# Seal a secret, the SealedSecret can be committed to git
+kubectl create secret generic my-secret --dry-run -o yaml \
+  --from-literal=password=s3cr3t | kubeseal --cert pub-cert.pem -o yaml > my-sealed-secret.yaml
+kubectl apply -f my-sealed-secret.yaml
+
+# Give kubeseal --controller-namespace when installed with --namespace
+
+# Back up the keys, to restore them with --recover-keys
+kubectl get secret -n kube-system -l sealedsecrets.bitnami.com/sealed-secrets-key \
+  -o yaml > sealed-secrets-keys.yaml
+
+# Find out more at:
+# https://github.com/bitnami-labs/sealed-secrets`
+
+const sealedSecretsInstallMsg = `=======================================================================
+= sealed-secrets has been installed.                                  =
+=======================================================================
+
+` + sealedSecretsInfoMsg + `
+
+` + thanksForUsing
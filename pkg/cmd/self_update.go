@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// k3supReleasesURL is where the releases of k3sup are published, the
+// latest release redirects to its tag
+var k3supReleasesURL = "https://github.com/alexellis/k3sup/releases"
+
+// latestK3supVersion reads the tag of the latest release from the redirect
+// of /releases/latest, as get.sh does, which is not rate-limited like the
+// GitHub API
+func latestK3supVersion() (string, error) {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	res, err := client.Get(k3supReleasesURL + "/latest")
+	if err != nil {
+		return "", fmt.Errorf("unable to check for the latest release: %s", err)
+	}
+	defer res.Body.Close()
+
+	location := res.Header.Get("Location")
+	if res.StatusCode < 300 || res.StatusCode > 399 || len(location) == 0 {
+		return "", fmt.Errorf("unable to check for the latest release: %s", res.Status)
+	}
+
+	return path.Base(strings.TrimRight(location, "/")), nil
+}
+
+// newerK3supVersion reports whether latest is newer than current, both as
+// tagged like 0.9.8. A build without a version, such as dev, is never up to
+// date.
+func newerK3supVersion(current, latest string) bool {
+	currentParts, ok := versionParts(current)
+	if !ok {
+		return true
+	}
+
+	latestParts, ok := versionParts(latest)
+	if !ok {
+		return false
+	}
+
+	for i := range latestParts {
+		if latestParts[i] != currentParts[i] {
+			return latestParts[i] > currentParts[i]
+		}
+	}
+	return false
+}
+
+// versionParts splits v0.9.8 or 0.9.8 into its numbers, anything after
+// them such as -dirty from git describe is ignored
+func versionParts(version string) ([3]int, bool) {
+	parts := [3]int{}
+
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+
+	fields := strings.Split(version, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// k3supBinaryName returns the name of the release binary for a platform,
+// as built by make dist
+func k3supBinaryName(goos, goarch string) (string, error) {
+	switch {
+	case goos == "windows":
+		return "k3sup.exe", nil
+	case goos == "darwin":
+		return "k3sup-darwin", nil
+	case goos == "linux" && goarch == "amd64":
+		return "k3sup", nil
+	case goos == "linux" && goarch == "arm64":
+		return "k3sup-arm64", nil
+	case goos == "linux" && goarch == "arm":
+		return "k3sup-armhf", nil
+	}
+	return "", fmt.Errorf("there is no release of k3sup for %s/%s", goos, goarch)
+}
+
+// selfUpdate downloads version and replaces the running binary with it. The
+// download is written next to the binary, then renamed over it.
+func selfUpdate(version string) (string, error) {
+	name, err := k3supBinaryName(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/download/%s/%s", k3supReleasesURL, version, name)
+	logInfof("Downloading %s\n", url)
+
+	tmp := executable + ".new"
+	if err := downloadFile(url, url+".sha256", tmp, 0755); err != nil {
+		if os.IsPermission(err) {
+			return "", fmt.Errorf("unable to write to %s, try again with sudo: %s", filepath.Dir(executable), err)
+		}
+		return "", err
+	}
+
+	// Windows will not replace a running binary, but it can be moved aside
+	if runtime.GOOS == "windows" {
+		old := executable + ".old"
+		os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			os.Remove(tmp)
+			return "", err
+		}
+	}
+
+	if err := os.Rename(tmp, executable); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	return executable, nil
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_newerK3supVersion(t *testing.T) {
+	cases := []struct {
+		current string
+		latest  string
+		want    bool
+	}{
+		{"0.9.8", "0.9.8", false},
+		{"0.9.7", "0.9.8", true},
+		{"0.9.8", "0.10.0", true},
+		{"0.10.0", "0.9.8", false},
+		{"0.9.8-3-gabc123-dirty", "0.9.8", false},
+		{"v0.9.7", "0.9.8", true},
+		{"", "0.9.8", true},
+		{"dev", "0.9.8", true},
+		{"0.9.8", "nightly", false},
+	}
+
+	for _, c := range cases {
+		if got := newerK3supVersion(c.current, c.latest); got != c.want {
+			t.Errorf("current: %q, latest: %q, want: %t, got: %t", c.current, c.latest, c.want, got)
+		}
+	}
+}
+
+func Test_k3supBinaryName(t *testing.T) {
+	cases := map[[2]string]string{
+		{"linux", "amd64"}:   "k3sup",
+		{"linux", "arm"}:     "k3sup-armhf",
+		{"linux", "arm64"}:   "k3sup-arm64",
+		{"darwin", "amd64"}:  "k3sup-darwin",
+		{"windows", "amd64"}: "k3sup.exe",
+	}
+
+	for platform, want := range cases {
+		got, err := k3supBinaryName(platform[0], platform[1])
+		if err != nil || got != want {
+			t.Errorf("%s/%s: want: %q, got: %q, %v", platform[0], platform[1], want, got, err)
+		}
+	}
+
+	if _, err := k3supBinaryName("linux", "s390x"); err == nil {
+		t.Errorf("want an error for linux/s390x")
+	}
+}
+
+func Test_latestK3supVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/releases/tag/0.9.13", http.StatusFound)
+	}))
+	defer server.Close()
+
+	defer func(url string) { k3supReleasesURL = url }(k3supReleasesURL)
+	k3supReleasesURL = server.URL + "/releases"
+
+	got, err := latestK3supVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "0.9.13" {
+		t.Errorf("want: %q, got: %q", "0.9.13", got)
+	}
+}
@@ -0,0 +1,549 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// snapshotDir is where k3s saves etcd snapshots on a server
+const snapshotDir = "/var/lib/rancher/k3s/server/db/snapshots"
+
+var (
+	// validSnapshotName and validSnapshotPath keep the names given to k3s
+	// etcd-snapshot safe to pass to the shell on the node
+	validSnapshotName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	validSnapshotPath = regexp.MustCompile(`^/[A-Za-z0-9._/-]+$`)
+)
+
+// etcdSnapshot is a snapshot listed by k3s etcd-snapshot ls, Downloaded is
+// set once it has been copied to this machine
+type etcdSnapshot struct {
+	Name       string `json:"name"`
+	Location   string `json:"location"`
+	Size       int64  `json:"size,omitempty"`
+	Created    string `json:"created,omitempty"`
+	Downloaded string `json:"downloaded,omitempty"`
+}
+
+func MakeSnapshot() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save, list and restore etcd snapshots of a k3s server",
+		Long: `Save, list and restore snapshots of the embedded etcd of a k3s server via
+SSH, with k3s etcd-snapshot. The cluster has to have been created with
+k3sup install --cluster.`,
+		Example: `  k3sup snapshot save --ip 192.168.0.100 --download ./snapshots
+  k3sup snapshot list --ip 192.168.0.100
+  k3sup snapshot restore --ip 192.168.0.110 --snapshot ./snapshots/on-demand-node1-1606815000`,
+		SilenceUsage: false,
+	}
+
+	command.AddCommand(makeSnapshotSave())
+	command.AddCommand(makeSnapshotList())
+	command.AddCommand(makeSnapshotRestore())
+
+	return command
+}
+
+func makeSnapshotSave() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "save",
+		Short: "Save an etcd snapshot on a server",
+		Long: `Save a snapshot of the embedded etcd of a server with k3s etcd-snapshot save.
+The snapshot is kept in ` + snapshotDir + ` on the server, give
+--download to copy it to this machine as well.`,
+		Example: `  k3sup snapshot save --ip 192.168.0.100
+  k3sup snapshot save --ip 192.168.0.100 --name before-upgrade --download ./snapshots`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+	command.Flags().String("name", "on-demand", "The name of the snapshot, k3s adds the node's name and a timestamp to it")
+	command.Flags().String("download", "", "Local folder to copy the snapshot to once it has been saved")
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		opts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+		sudoPrefix := escalationPrefix(escalation)
+		name, _ := command.Flags().GetString("name")
+		download, _ := command.Flags().GetString("download")
+
+		operator, err := connectSSH(opts)
+		if err != nil {
+			return err
+		}
+		defer operator.Close()
+
+		before, err := listSnapshotFiles(operator, sudoPrefix)
+		if err != nil {
+			return err
+		}
+
+		saveCommand := fmt.Sprintf("%sk3s etcd-snapshot save --name %s", sudoPrefix, name)
+		if err := runInstallerWithProgress(operator, fmt.Sprintf("Saving an etcd snapshot on %s", opts.Host), func() error {
+			logDebugf("ssh: %s\n", saveCommand)
+			_, err := operator.Execute(saveCommand)
+			return err
+		}); err != nil {
+			return fmt.Errorf("unable to save a snapshot on %s, was it installed with --cluster? %s", opts.Host, err)
+		}
+
+		after, err := listSnapshotFiles(operator, sudoPrefix)
+		if err != nil {
+			return err
+		}
+
+		saved := newSnapshots(before, after)
+		if len(saved) == 0 && len(download) > 0 {
+			return fmt.Errorf("no new snapshot was found in %s on %s to download", snapshotDir, opts.Host)
+		}
+
+		var snapshots []etcdSnapshot
+		for _, file := range saved {
+			snapshot := etcdSnapshot{Name: file, Location: "file://" + path.Join(snapshotDir, file)}
+			logInfof("Saved the snapshot %s on %s\n", path.Join(snapshotDir, file), opts.Host)
+
+			if len(download) > 0 {
+				if snapshot.Downloaded, err = downloadSnapshot(operator, sudoPrefix, path.Join(snapshotDir, file), expandPath(download)); err != nil {
+					return err
+				}
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+
+		updateResult(func(r *commandResult) {
+			r.Snapshots = snapshots
+		})
+
+		return nil
+	}
+
+	command.PreRunE = func(command *cobra.Command, args []string) error {
+		if name, _ := command.Flags().GetString("name"); !validSnapshotName.MatchString(name) {
+			return fmt.Errorf("--name may only have letters, numbers, '.', '_' and '-', not %q", name)
+		}
+		return nil
+	}
+
+	return command
+}
+
+func makeSnapshotList() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "list",
+		Short: "List the etcd snapshots of a server",
+		Long: `List the snapshots of the embedded etcd of a server with
+k3s etcd-snapshot ls, including those in S3 when k3s is configured for it.`,
+		Example:      `  k3sup snapshot list --ip 192.168.0.100`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		opts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		operator, err := connectSSH(opts)
+		if err != nil {
+			return err
+		}
+		defer operator.Close()
+
+		listCommand := escalationPrefix(escalation) + "k3s etcd-snapshot ls"
+		logDebugf("ssh: %s\n", listCommand)
+
+		res, err := operator.ExecuteQuiet(listCommand)
+		if err != nil {
+			return fmt.Errorf("unable to list the snapshots on %s, which needs k3s v1.21 or newer: %s", opts.Host, err)
+		}
+
+		snapshots := parseSnapshotList(string(res.StdOut))
+		updateResult(func(r *commandResult) {
+			r.Snapshots = snapshots
+		})
+
+		if outputFormat == "json" {
+			return nil
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Fprintf(textOutput, "No snapshots have been saved on %s\n", opts.Host)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(textOutput, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSIZE\tCREATED\tLOCATION")
+		for _, snapshot := range snapshots {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", snapshot.Name, formatBytes(snapshot.Size), snapshot.Created, snapshot.Location)
+		}
+		return w.Flush()
+	}
+
+	return command
+}
+
+func makeSnapshotRestore() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a server from an etcd snapshot",
+		Long: `Restore the embedded etcd of a server from a snapshot with
+k3s server --cluster-reset, then fetch its kubeconfig as for k3sup get-config.
+
+--snapshot is the name of a snapshot on the server, a path on the server, or a
+local file saved by k3sup snapshot save --download, which is uploaded first.
+
+To restore onto a fresh node, install it with k3sup install --cluster and the
+same --k3s-version first, then give --token with the token of the cluster the
+snapshot came from. The other servers of an HA cluster have to be uninstalled
+and joined again once it has been restored.`,
+		Example: `  k3sup snapshot restore --ip 192.168.0.100 --snapshot on-demand-node1-1606815000
+  k3sup snapshot restore --ip 192.168.0.110 --snapshot ./snapshots/on-demand-node1-1606815000 \
+    --token-file ./node-token --merge --context k3s-prod`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+	command.Flags().String("snapshot", "", "The snapshot to restore: its name or path on the server, or a local file to upload")
+	command.Flags().String("token", "", "The token of the cluster the snapshot was saved from, needed on a fresh node")
+	command.Flags().String("token-file", "", "Read --token from this file")
+	command.Flags().String("local-path", "kubeconfig", "Local path to save the kubeconfig file")
+	command.Flags().String("context", "default", "Set the name of the kubeconfig context.")
+	command.Flags().String("cluster-name", "", "Set the name of the cluster in the kubeconfig (Default to --context)")
+	command.Flags().String("user-name", "", "Set the name of the user in the kubeconfig (Default to --context)")
+	command.Flags().Bool("merge", false, "Merge the config into an existing kubeconfig under the name given by --context.\nDefaults to $KUBECONFIG or ~/.kube/config, unless --local-path is given")
+	command.Flags().Bool("no-backup", false, "Do not copy an existing kubeconfig to a backup with a timestamp before it is overwritten or merged")
+	command.Flags().Bool("print-kubeconfig", false, "Write the kubeconfig to stdout instead of saving it, i.e. to pipe it into a secret store, the progress is written to stderr")
+	addWaitReadyFlags(command)
+
+	command.Flags().SetNormalizeFunc(contextNameAlias)
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		printKubeconfig, _ := command.Flags().GetBool("print-kubeconfig")
+		if printKubeconfig {
+			progressToStderr()
+		}
+
+		sshOpts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+		sudoPrefix := escalationPrefix(escalation)
+
+		snapshot, _ := command.Flags().GetString("snapshot")
+		token, err := snapshotTokenFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		merge, _ := command.Flags().GetBool("merge")
+		noBackup, _ := command.Flags().GetBool("no-backup")
+		context, _ := command.Flags().GetString("context")
+		clusterName, _ := command.Flags().GetString("cluster-name")
+		userName, _ := command.Flags().GetString("user-name")
+		waitReady, _ := command.Flags().GetBool("wait-ready")
+		timeout, _ := command.Flags().GetDuration("timeout")
+
+		opts := installOptions{
+			SSH:                 sshOpts,
+			PrivilegeEscalation: escalation,
+			LocalPath:           localKubeconfigFromFlags(command),
+			Names: kubeconfigNames{
+				Context: context,
+				Cluster: clusterName,
+				User:    userName,
+			},
+			Merge:           merge,
+			NoBackup:        noBackup,
+			PrintKubeconfig: printKubeconfig,
+			WaitReady:       waitReady,
+			Timeout:         timeout,
+		}
+
+		// Both are asked before k3s is stopped
+		absPath, _ := filepath.Abs(opts.LocalPath)
+		if err := confirmOverwriteKubeconfig(absPath, opts); err != nil {
+			return err
+		}
+		if err := confirm("Restore %s onto %s? Its datastore will be replaced and k3s restarted.", snapshot, sshOpts.Host); err != nil {
+			return err
+		}
+
+		operator, err := connectSSH(sshOpts)
+		if err != nil {
+			return err
+		}
+		defer operator.Close()
+
+		if _, err := operator.ExecuteQuiet(sudoPrefix + "test -d /var/lib/rancher/k3s/server/db/etcd"); err != nil {
+			return fmt.Errorf("%s has no embedded etcd to restore to, install it with k3sup install --cluster first", sshOpts.Host)
+		}
+
+		remotePath, err := remoteSnapshotPath(snapshot)
+		if err != nil {
+			return err
+		}
+
+		if _, statErr := os.Stat(snapshot); statErr == nil {
+			if err := uploadSnapshot(operator, sudoPrefix, snapshot, remotePath); err != nil {
+				return err
+			}
+		}
+
+		if err := restoreSnapshot(operator, sshOpts.Host, sudoPrefix, remotePath, token); err != nil {
+			return err
+		}
+
+		if err := fetchKubeconfig(operator, absPath, opts); err != nil {
+			return err
+		}
+
+		updateResult(func(r *commandResult) {
+			if !opts.PrintKubeconfig {
+				r.Kubeconfig = absPath
+			}
+			r.Context = opts.Names.Context
+		})
+
+		logInfof("Restored %s onto %s\n", snapshot, sshOpts.Host)
+
+		return nil
+	}
+
+	command.PreRunE = func(command *cobra.Command, args []string) error {
+		snapshot, _ := command.Flags().GetString("snapshot")
+		if len(snapshot) == 0 {
+			return fmt.Errorf("give the snapshot to restore with --snapshot")
+		}
+
+		if _, err := remoteSnapshotPath(snapshot); err != nil {
+			return err
+		}
+
+		if command.Flags().Changed("token") && command.Flags().Changed("token-file") {
+			return fmt.Errorf("give either --token or --token-file, not both")
+		}
+
+		if err := validPrintKubeconfigFlags(command); err != nil {
+			return err
+		}
+
+		return validWaitReadyFlags(command)
+	}
+
+	return command
+}
+
+// snapshotTokenFromFlags returns --token, or reads it from --token-file
+func snapshotTokenFromFlags(command *cobra.Command) (string, error) {
+	token, _ := command.Flags().GetString("token")
+	tokenFile, _ := command.Flags().GetString("token-file")
+	if len(tokenFile) == 0 {
+		return token, nil
+	}
+
+	data, err := ioutil.ReadFile(expandPath(tokenFile))
+	if err != nil {
+		return "", fmt.Errorf("unable to read --token-file: %s", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// remoteSnapshotPath returns where snapshot is on the server. A local file
+// is uploaded to snapshotDir under its own name, a name without a folder is
+// in snapshotDir, otherwise it is a path on the server.
+func remoteSnapshotPath(snapshot string) (string, error) {
+	if _, err := os.Stat(snapshot); err == nil || !strings.Contains(snapshot, "/") {
+		name := filepath.Base(snapshot)
+		if !validSnapshotName.MatchString(name) {
+			return "", fmt.Errorf("the name of a snapshot may only have letters, numbers, '.', '_' and '-', not %q", name)
+		}
+		return path.Join(snapshotDir, name), nil
+	}
+
+	if !validSnapshotPath.MatchString(snapshot) {
+		return "", fmt.Errorf("%s is not a local file, or an absolute path on the server of letters, numbers, '.', '_', '-' and '/'", snapshot)
+	}
+	return path.Clean(snapshot), nil
+}
+
+// listSnapshotFiles returns the names of the snapshots in snapshotDir, the
+// folder is missing until the first snapshot has been saved
+func listSnapshotFiles(operator kssh.Operator, sudoPrefix string) ([]string, error) {
+	res, err := operator.ExecuteQuiet(fmt.Sprintf("%sls -1 %s 2> /dev/null; true", sudoPrefix, snapshotDir))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %s: %s", snapshotDir, err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(res.StdOut), "\n") {
+		if file := strings.TrimSpace(line); len(file) > 0 {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// newSnapshots returns the files in after which are not in before
+func newSnapshots(before, after []string) []string {
+	existing := map[string]bool{}
+	for _, file := range before {
+		existing[file] = true
+	}
+
+	var added []string
+	for _, file := range after {
+		if !existing[file] {
+			added = append(added, file)
+		}
+	}
+	return added
+}
+
+// parseSnapshotList reads the table printed by k3s etcd-snapshot ls, with
+// the columns Name, Location, Size and Created
+func parseSnapshotList(output string) []etcdSnapshot {
+	var snapshots []etcdSnapshot
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] == "Name" {
+			continue
+		}
+
+		size, _ := strconv.ParseInt(fields[2], 10, 64)
+		snapshots = append(snapshots, etcdSnapshot{
+			Name:     fields[0],
+			Location: fields[1],
+			Size:     size,
+			Created:  fields[3],
+		})
+	}
+	return snapshots
+}
+
+// downloadSnapshot copies the snapshot at remotePath to dir, it is owned by
+// root so a copy which the SSH user can read is made first. The snapshot
+// holds the cluster's secrets, so only the user can read the local file.
+func downloadSnapshot(operator kssh.Operator, sudoPrefix, remotePath, dir string) (string, error) {
+	name := path.Base(remotePath)
+	tmp := "/tmp/k3sup-snapshot-" + name
+
+	copyCommand := fmt.Sprintf("%sinstall -m 600 -o $(id -u) %s %s", sudoPrefix, remotePath, tmp)
+	logDebugf("ssh: %s\n", copyCommand)
+	if _, err := operator.ExecuteQuiet(copyCommand); err != nil {
+		return "", fmt.Errorf("unable to copy %s for download: %s", remotePath, err)
+	}
+	defer operator.ExecuteQuiet("rm -f " + tmp)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	local := filepath.Join(dir, name)
+	file, err := os.OpenFile(local+".download", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	p := startProgress("Downloading %s to %s", name, local)
+	err = operator.Download(tmp, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	p.Stop(err)
+
+	if err != nil {
+		os.Remove(local + ".download")
+		return "", err
+	}
+
+	return local, os.Rename(local+".download", local)
+}
+
+// uploadSnapshot copies the local snapshot file to remotePath on the server
+func uploadSnapshot(operator kssh.Operator, sudoPrefix, local, remotePath string) error {
+	file, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tmp := "/tmp/k3sup-snapshot-" + path.Base(remotePath)
+	logInfof("Uploading %s to %s\n", local, remotePath)
+	if err := operator.Upload(file, tmp); err != nil {
+		return err
+	}
+
+	for _, command := range []string{
+		fmt.Sprintf("%smkdir -p %s", sudoPrefix, path.Dir(remotePath)),
+		fmt.Sprintf("%sinstall -m 600 %s %s", sudoPrefix, tmp, remotePath),
+		"rm " + tmp,
+	} {
+		logDebugf("ssh: %s\n", command)
+		if _, err := operator.ExecuteQuiet(command); err != nil {
+			return fmt.Errorf("unable to upload %s: %s", local, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreSnapshot stops k3s, resets its etcd from the snapshot at
+// remotePath, then starts it again. token is only needed when the snapshot
+// was saved by another cluster.
+func restoreSnapshot(operator kssh.Operator, host, sudoPrefix, remotePath, token string) error {
+	stop, start := sudoPrefix+"systemctl stop k3s", sudoPrefix+"systemctl start k3s"
+	reset := fmt.Sprintf("%sk3s server --cluster-reset --cluster-reset-restore-path=%s", sudoPrefix, remotePath)
+	if len(token) > 0 {
+		reset += " --token=" + token
+	}
+
+	return runInstallerWithProgress(operator, fmt.Sprintf("Restoring %s on %s", path.Base(remotePath), host), func() error {
+		for _, command := range []string{stop, reset, start} {
+			if len(token) > 0 {
+				// The token is a secret
+				logDebugf("ssh: %s\n", strings.Replace(command, token, "<token>", 1))
+			} else {
+				logDebugf("ssh: %s\n", command)
+			}
+
+			if _, err := operator.Execute(command); err != nil {
+				if command == reset {
+					// Start k3s again with the datastore it had
+					operator.Execute(start)
+				}
+				return fmt.Errorf("unable to restore %s on %s: %s", remotePath, host, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+func Test_parseSnapshotList(t *testing.T) {
+	output := `Name                              Location                                                                         Size    Created
+on-demand-node1-1606815000 file:///var/lib/rancher/k3s/server/db/snapshots/on-demand-node1-1606815000 2940960 2020-12-01T09:30:00Z
+etcd-snapshot-node1-1606820000    s3://k3s/etcd-snapshot-node1-1606820000                                          2957344 2020-12-01T10:53:20Z
+`
+
+	want := []etcdSnapshot{
+		{Name: "on-demand-node1-1606815000", Location: "file:///var/lib/rancher/k3s/server/db/snapshots/on-demand-node1-1606815000", Size: 2940960, Created: "2020-12-01T09:30:00Z"},
+		{Name: "etcd-snapshot-node1-1606820000", Location: "s3://k3s/etcd-snapshot-node1-1606820000", Size: 2957344, Created: "2020-12-01T10:53:20Z"},
+	}
+
+	if got := parseSnapshotList(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	if got := parseSnapshotList("Name Location Size Created\n"); len(got) != 0 {
+		t.Errorf("want no snapshots, got %+v", got)
+	}
+}
+
+func Test_newSnapshots(t *testing.T) {
+	got := newSnapshots([]string{"a", "b"}, []string{"a", "b", "c"})
+	if !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("want [c], got %v", got)
+	}
+}
+
+func Test_remoteSnapshotPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k3sup-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	local := filepath.Join(dir, "on-demand-node1-1606815000")
+	if err := ioutil.WriteFile(local, []byte("etcd"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"on-demand-node1-1606815000": snapshotDir + "/on-demand-node1-1606815000",
+		local:                        snapshotDir + "/on-demand-node1-1606815000",
+		"/data/snapshots/./before":   "/data/snapshots/before",
+	}
+	for snapshot, want := range cases {
+		got, err := remoteSnapshotPath(snapshot)
+		if err != nil || got != want {
+			t.Errorf("%s: want %s, got %s %v", snapshot, want, got, err)
+		}
+	}
+
+	for _, snapshot := range []string{"before;reboot", "./missing/snapshot", "/data/$(reboot)"} {
+		if _, err := remoteSnapshotPath(snapshot); err == nil {
+			t.Errorf("want an error for %q", snapshot)
+		}
+	}
+}
+
+func Test_downloadSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k3sup-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	remote := filepath.Join(dir, "on-demand-node1-1606815000")
+	if err := ioutil.WriteFile(remote, []byte("etcd"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := downloadSnapshot(kssh.NewLocalOperator(), "", remote, filepath.Join(dir, "downloads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := ioutil.ReadFile(local); string(data) != "etcd" {
+		t.Errorf("want the snapshot downloaded to %s, got %q", local, data)
+	}
+	if info, err := os.Stat(local); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("want the snapshot only readable by the user, got %v", info.Mode())
+	}
+	if _, err := os.Stat("/tmp/k3sup-snapshot-on-demand-node1-1606815000"); !os.IsNotExist(err) {
+		t.Errorf("want the copy on the node removed, got: %v", err)
+	}
+}
+
+func Test_uploadSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k3sup-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	local := filepath.Join(dir, "before")
+	if err := ioutil.WriteFile(local, []byte("etcd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	remote := filepath.Join(dir, "snapshots", "before")
+	if err := uploadSnapshot(kssh.NewLocalOperator(), "", local, remote); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := ioutil.ReadFile(remote); string(data) != "etcd" {
+		t.Errorf("want the snapshot uploaded to %s, got %q", remote, data)
+	}
+	if info, err := os.Stat(remote); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("want the snapshot only readable by its owner, got %v", info.Mode())
+	}
+}
+
+func Test_restoreSnapshot(t *testing.T) {
+	operator := &nodeOperator{}
+
+	if err := restoreSnapshot(operator, "192.168.0.110", "sudo ", snapshotDir+"/before", "K10secret::server:token"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sudo systemctl stop k3s",
+		"sudo k3s server --cluster-reset --cluster-reset-restore-path=" + snapshotDir + "/before --token=K10secret::server:token",
+		"sudo systemctl start k3s",
+	}
+	if !reflect.DeepEqual(operator.lines, want) {
+		t.Errorf("want %v, got %v", want, operator.lines)
+	}
+}
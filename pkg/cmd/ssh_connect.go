@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/provision"
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// sshOptions are the options for connecting to a host over SSH
+type sshOptions struct {
+	Host       string
+	Port       int
+	User       string
+	SSHKeyPath string
+
+	// JumpHost is an optional bastion through which to connect to Host,
+	// JumpUser defaults to User when not set.
+	JumpHost string
+	JumpPort int
+	JumpUser string
+
+	// UseAgent authenticates with the keys held by ssh-agent instead of
+	// SSHKeyPath, ForwardAgent makes the agent available to commands run
+	// on Host, like ssh -A.
+	UseAgent     bool
+	ForwardAgent bool
+
+	// Password is used when a node only allows password login, the key is
+	// still tried first when SSHKeyPath exists
+	Password string
+
+	// KeyPassphrase decrypts SSHKeyPath, when it is not set an encrypted
+	// key is prompted for
+	KeyPassphrase string
+
+	// HostKeyChecking is no, yes or accept-new, the keys of the host and
+	// jump host are checked against KnownHostsFile unless it is no
+	HostKeyChecking string
+	KnownHostsFile  string
+}
+
+// connectSSH opens an SSH connection to opts.Host using the key at
+// opts.SSHKeyPath or ssh-agent, going through opts.JumpHost when one is
+// given
+func connectSSH(opts sshOptions) (*kssh.SSHOperator, error) {
+	var sshAgent agent.ExtendedAgent
+	var agentConn net.Conn
+
+	if opts.UseAgent || opts.ForwardAgent {
+		var err error
+		if sshAgent, agentConn, err = connectAgent(); err != nil {
+			return nil, err
+		}
+	}
+
+	authMethods := []ssh.AuthMethod{}
+	if opts.UseAgent {
+		authMethods = append(authMethods, ssh.PublicKeysCallback(sshAgent.Signers))
+	} else if _, statErr := os.Stat(opts.SSHKeyPath); len(opts.Password) == 0 || statErr == nil {
+		keyAuth, closeSSHAgent, err := loadPublickey(opts.SSHKeyPath, opts.KeyPassphrase)
+		if err != nil {
+			if agentConn != nil {
+				agentConn.Close()
+			}
+			return nil, errors.Wrapf(err, "unable to load the ssh key with path %q", opts.SSHKeyPath)
+		}
+
+		defer closeSSHAgent()
+		authMethods = append(authMethods, keyAuth)
+	}
+
+	if len(opts.Password) > 0 {
+		authMethods = append(authMethods, ssh.Password(opts.Password), ssh.KeyboardInteractive(passwordChallenge(opts.Password)))
+	}
+
+	operator, err := dialSSH(opts, authMethods)
+	if err != nil {
+		if agentConn != nil {
+			agentConn.Close()
+		}
+		return nil, err
+	}
+
+	if opts.ForwardAgent {
+		if err := operator.ForwardAgent(sshAgent, agentConn); err != nil {
+			operator.Close()
+			return nil, errors.Wrap(err, "unable to forward ssh-agent")
+		}
+	} else if agentConn != nil {
+		agentConn.Close()
+	}
+
+	return quietOperator(operator, nil)
+}
+
+// connectAgent connects to the ssh-agent listening on SSH_AUTH_SOCK
+func connectAgent() (agent.ExtendedAgent, net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if len(socket) == 0 {
+		return nil, nil, fmt.Errorf("SSH_AUTH_SOCK is not set, start ssh-agent and add a key with ssh-add")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to connect to ssh-agent")
+	}
+
+	return agent.NewClient(conn), conn, nil
+}
+
+// passwordChallenge answers every keyboard-interactive question with
+// password, as some servers only offer password login this way
+func passwordChallenge(password string) ssh.KeyboardInteractiveChallenge {
+	return func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i := range questions {
+			answers[i] = password
+		}
+		return answers, nil
+	}
+}
+
+// dialSSH connects to the node, retrying with --retries while the
+// connection is refused or times out
+func dialSSH(opts sshOptions, authMethods []ssh.AuthMethod) (*kssh.SSHOperator, error) {
+	var operator *kssh.SSHOperator
+
+	err := withRetries(retryableSSHError, func() error {
+		var err error
+		operator, err = dialSSHOnce(opts, authMethods)
+		return err
+	})
+
+	return operator, err
+}
+
+func dialSSHOnce(opts sshOptions, authMethods []ssh.AuthMethod) (*kssh.SSHOperator, error) {
+	config := &ssh.ClientConfig{
+		User:            opts.User,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback(opts.HostKeyChecking, opts.KnownHostsFile),
+		Timeout:         sshConnectTimeout,
+	}
+
+	address := fmt.Sprintf("%s:%d", opts.Host, opts.Port)
+
+	if len(opts.JumpHost) == 0 {
+		return kssh.NewSSHOperator(address, config)
+	}
+
+	jumpUser := opts.JumpUser
+	if len(jumpUser) == 0 {
+		jumpUser = opts.User
+	}
+
+	jumpPort := opts.JumpPort
+	if jumpPort == 0 {
+		jumpPort = 22
+	}
+
+	jumpConfig := &ssh.ClientConfig{
+		User:            jumpUser,
+		Auth:            config.Auth,
+		HostKeyCallback: config.HostKeyCallback,
+		Timeout:         sshConnectTimeout,
+	}
+
+	jumpAddress := fmt.Sprintf("%s:%d", opts.JumpHost, jumpPort)
+	logInfof("Connecting to %s via jump host %s@%s\n", address, jumpUser, jumpAddress)
+
+	return kssh.NewSSHOperatorWithJump(jumpAddress, jumpConfig, address, config)
+}
+
+// quietOperator copies the output of remote commands to textOutput, or
+// discards it with --quiet
+func quietOperator(operator *kssh.SSHOperator, err error) (*kssh.SSHOperator, error) {
+	if err == nil {
+		operator.SetOutput(textOutput, os.Stderr)
+		if level == quietLevel {
+			operator.SetOutput(ioutil.Discard, ioutil.Discard)
+		}
+	}
+	return operator, err
+}
+
+// addSSHFlags adds the flags needed to connect to a single node over SSH
+func addSSHFlags(command *cobra.Command) {
+	command.Flags().String("ip", "", "Public IP or hostname of node, as user@host:port to override --user and --ssh-port")
+	command.Flags().String("user", "root", "Username for SSH login")
+
+	command.Flags().String("ssh-key", "~/.ssh/id_rsa", "The ssh key to use for remote login")
+	command.Flags().String("ssh-key-passphrase-file", "", "File holding the passphrase of an encrypted --ssh-key, instead of prompting for it")
+	command.Flags().Int("ssh-port", 22, "The port on which to connect for ssh")
+	addPrivilegeEscalationFlags(command)
+	command.Flags().String("ssh-jump-host", "", "Connect through this bastion or jump host when the node is not directly reachable")
+	command.Flags().String("ssh-jump-user", "", "Username for SSH login to the jump host (Default to --user)")
+	command.Flags().Int("ssh-jump-port", 22, "The port on which to connect to the jump host for ssh")
+	addSSHAgentFlags(command)
+	addSSHPasswordFlags(command)
+	addHostKeyFlags(command)
+}
+
+// addSSHPasswordFlags adds the flags to log in with a password
+func addSSHPasswordFlags(command *cobra.Command) {
+	command.Flags().String("ssh-password", "", "Password for SSH login, for nodes which do not accept keys. Prefer --ask-pass or K3SUP_SSH_PASSWORD")
+	command.Flags().Bool("ask-pass", false, "Prompt for the password for SSH login")
+}
+
+// sshPasswordFromFlags returns --ssh-password, or prompts for the password
+// once with --ask-pass
+func sshPasswordFromFlags(command *cobra.Command) (string, error) {
+	password, _ := command.Flags().GetString("ssh-password")
+	askPass, _ := command.Flags().GetBool("ask-pass")
+
+	if len(password) > 0 || !askPass {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "SSH password: ")
+	bytePassword, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read the SSH password")
+	}
+
+	return string(bytePassword), nil
+}
+
+// sshKeyPassphraseFromFlags reads the file given by --ssh-key-passphrase-file
+func sshKeyPassphraseFromFlags(command *cobra.Command) (string, error) {
+	passphraseFile, _ := command.Flags().GetString("ssh-key-passphrase-file")
+	if len(passphraseFile) == 0 {
+		return "", nil
+	}
+
+	data, err := ioutil.ReadFile(expandPath(passphraseFile))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read --ssh-key-passphrase-file")
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// addSSHAgentFlags adds the flags to authenticate with ssh-agent
+func addSSHAgentFlags(command *cobra.Command) {
+	command.Flags().Bool("ssh-agent", false, "Authenticate with the keys held by ssh-agent at SSH_AUTH_SOCK instead of --ssh-key")
+	command.Flags().Bool("ssh-agent-forwarding", false, "Forward ssh-agent to the node, through any jump host, like ssh -A")
+}
+
+// sshOptionsFromFlags reads the flags added by addSSHFlags
+func sshOptionsFromFlags(command *cobra.Command) (sshOptions, error) {
+	ip, _ := command.Flags().GetString("ip")
+	if len(ip) == 0 {
+		return sshOptions{}, fmt.Errorf("--ip is required")
+	}
+
+	user, _ := command.Flags().GetString("user")
+	sshKey, _ := command.Flags().GetString("ssh-key")
+	port, _ := command.Flags().GetInt("ssh-port")
+
+	host, user, port, err := parseSSHAddress(ip, user, port)
+	if err != nil {
+		return sshOptions{}, err
+	}
+
+	jumpHost, _ := command.Flags().GetString("ssh-jump-host")
+	jumpPort, _ := command.Flags().GetInt("ssh-jump-port")
+	jumpUser, _ := command.Flags().GetString("ssh-jump-user")
+	useAgent, _ := command.Flags().GetBool("ssh-agent")
+	forwardAgent, _ := command.Flags().GetBool("ssh-agent-forwarding")
+
+	password, err := sshPasswordFromFlags(command)
+	if err != nil {
+		return sshOptions{}, err
+	}
+
+	keyPassphrase, err := sshKeyPassphraseFromFlags(command)
+	if err != nil {
+		return sshOptions{}, err
+	}
+
+	hostKeyChecking, knownHosts, err := hostKeyOptionsFromFlags(command)
+	if err != nil {
+		return sshOptions{}, err
+	}
+
+	return sshOptions{
+		Host:            host,
+		Port:            port,
+		User:            user,
+		SSHKeyPath:      expandPath(sshKey),
+		JumpHost:        jumpHost,
+		JumpPort:        jumpPort,
+		JumpUser:        jumpUser,
+		UseAgent:        useAgent,
+		ForwardAgent:    forwardAgent,
+		Password:        password,
+		KeyPassphrase:   keyPassphrase,
+		HostKeyChecking: hostKeyChecking,
+		KnownHostsFile:  knownHosts,
+	}, nil
+}
+
+// parseSSHAddress splits an address given as host, user@host, host:port or
+// user@host:port, and defaults the user and port which are not given. An
+// IPv6 address with a port is written as [::1]:22.
+func parseSSHAddress(address, user string, port int) (string, string, int, error) {
+	host := address
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		user, host = host[:at], host[at+1:]
+		if len(user) == 0 {
+			return "", "", 0, fmt.Errorf("no user before @ in %q", address)
+		}
+	}
+
+	if strings.HasPrefix(host, "[") || strings.Count(host, ":") == 1 {
+		h, p, err := net.SplitHostPort(host)
+		if err != nil {
+			return "", "", 0, fmt.Errorf("invalid address %q, give host, user@host or user@host:port", address)
+		}
+
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return "", "", 0, fmt.Errorf("invalid port %q in %q", p, address)
+		}
+		host, port = h, n
+	}
+
+	if len(host) == 0 {
+		return "", "", 0, fmt.Errorf("no host in %q", address)
+	}
+
+	return host, user, port, nil
+}
+
+// Values for --privilege-escalation, the tool used to run commands which
+// need root on a node
+const (
+	escalateSudo = provision.EscalateSudo
+	escalateDoas = provision.EscalateDoas
+	escalateNone = provision.EscalateNone
+)
+
+// addPrivilegeEscalationFlags adds --privilege-escalation, and --sudo which
+// is kept so that --sudo=false still works
+func addPrivilegeEscalationFlags(command *cobra.Command) {
+	command.Flags().String("privilege-escalation", escalateSudo, "How to run remote commands as root: sudo, doas (i.e. Alpine) or none when the user is already root")
+	command.Flags().Bool("sudo", true, "Use sudo for remote commands. e.g. set to false when using the root user and no sudo is available. (Same as --privilege-escalation none)")
+}
+
+// privilegeEscalationFromFlags returns --privilege-escalation, or none when
+// only --sudo=false was given
+func privilegeEscalationFromFlags(command *cobra.Command) (string, error) {
+	escalation, _ := command.Flags().GetString("privilege-escalation")
+	useSudo, _ := command.Flags().GetBool("sudo")
+
+	if err := validPrivilegeEscalation(escalation); err != nil {
+		return "", err
+	}
+
+	if !useSudo {
+		if command.Flags().Changed("privilege-escalation") && escalation != escalateNone {
+			return "", fmt.Errorf("--sudo=false can not be used with --privilege-escalation %s", escalation)
+		}
+		return escalateNone, nil
+	}
+
+	return escalation, nil
+}
+
+func validPrivilegeEscalation(escalation string) error {
+	switch escalation {
+	case escalateSudo, escalateDoas, escalateNone:
+		return nil
+	}
+	return fmt.Errorf("privilege escalation must be sudo, doas or none, not %q", escalation)
+}
+
+// escalationPrefix returns the prefix for remote commands which need root
+func escalationPrefix(escalation string) string {
+	if escalation == escalateNone || len(escalation) == 0 {
+		return ""
+	}
+	return escalation + " "
+}
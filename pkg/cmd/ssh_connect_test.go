@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// startAgent serves a keyring holding key on a unix socket, like ssh-agent
+func startAgent(t *testing.T, key *rsa.PrivateKey) (string, func()) {
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "k3sup-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socket := path.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	return socket, func() {
+		listener.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// startSSHServer accepts one connection authenticated with key, or the
+// password "raspberry". Commands print the number of keys in a forwarded
+// agent, or "no agent".
+func startSSHServer(t *testing.T, key *rsa.PrivateKey) (int, func()) {
+	hostKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "raspberry" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("wrong password")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(requests)
+
+		for newChannel := range channels {
+			channel, channelRequests, err := newChannel.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				forwarded := false
+				for req := range channelRequests {
+					switch req.Type {
+					case "auth-agent-req@openssh.com":
+						forwarded = true
+						req.Reply(true, nil)
+					case "exec":
+						req.Reply(true, nil)
+
+						output := "no agent"
+						if forwarded {
+							agentChannel, agentRequests, err := serverConn.OpenChannel("auth-agent@openssh.com", nil)
+							if err == nil {
+								go ssh.DiscardRequests(agentRequests)
+								keys, _ := agent.NewClient(agentChannel).List()
+								output = fmt.Sprintf("%d key(s)", len(keys))
+								agentChannel.Close()
+							}
+						}
+
+						channel.Write([]byte(output))
+						channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+						channel.Close()
+					default:
+						req.Reply(false, nil)
+					}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, func() { listener.Close() }
+}
+
+func Test_connectSSH_WithAgent(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, forward := range []bool{false, true} {
+		socket, stopAgent := startAgent(t, key)
+		defer stopAgent()
+		port, stopServer := startSSHServer(t, key)
+		defer stopServer()
+
+		os.Setenv("SSH_AUTH_SOCK", socket)
+		defer os.Unsetenv("SSH_AUTH_SOCK")
+
+		operator, err := connectSSH(sshOptions{
+			Host:         "127.0.0.1",
+			Port:         port,
+			User:         "pi",
+			SSHKeyPath:   "/does/not/exist",
+			UseAgent:     true,
+			ForwardAgent: forward,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := operator.ExecuteQuiet("ssh-add -l")
+		operator.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := "no agent"
+		if forward {
+			want = "1 key(s)"
+		}
+
+		if got := strings.TrimSpace(string(res.StdOut)); got != want {
+			t.Errorf("forward: %v want: %q, got: %q", forward, want, got)
+		}
+	}
+}
+
+func Test_connectSSH_AgentNotRunning(t *testing.T) {
+	socket, ok := os.LookupEnv("SSH_AUTH_SOCK")
+	os.Unsetenv("SSH_AUTH_SOCK")
+	if ok {
+		defer os.Setenv("SSH_AUTH_SOCK", socket)
+	}
+
+	_, err := connectSSH(sshOptions{Host: "127.0.0.1", Port: 22, User: "pi", UseAgent: true})
+	if err == nil || !strings.Contains(err.Error(), "SSH_AUTH_SOCK") {
+		t.Errorf("want error about SSH_AUTH_SOCK, got: %v", err)
+	}
+}
+
+func Test_connectSSH_WithPassword(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, password := range []string{"raspberry", "wrong"} {
+		port, stopServer := startSSHServer(t, key)
+		defer stopServer()
+
+		operator, err := connectSSH(sshOptions{
+			Host:       "127.0.0.1",
+			Port:       port,
+			User:       "pi",
+			SSHKeyPath: "/does/not/exist",
+			Password:   password,
+		})
+
+		if password == "wrong" {
+			if err == nil {
+				operator.Close()
+				t.Errorf("want error for the wrong password")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := operator.ExecuteQuiet("hostname")
+		operator.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := strings.TrimSpace(string(res.StdOut)); got != "no agent" {
+			t.Errorf("want: %q, got: %q", "no agent", got)
+		}
+	}
+}
+
+func Test_parseSSHAddress(t *testing.T) {
+	cases := []struct {
+		address string
+		host    string
+		user    string
+		port    int
+	}{
+		{"192.168.0.100", "192.168.0.100", "root", 22},
+		{"pi@192.168.0.100", "192.168.0.100", "pi", 22},
+		{"node-1.local:2222", "node-1.local", "root", 2222},
+		{"pi@node-1.local:2222", "node-1.local", "pi", 2222},
+		{"fd00::1", "fd00::1", "root", 22},
+		{"pi@[fd00::1]:2222", "fd00::1", "pi", 2222},
+	}
+
+	for _, c := range cases {
+		host, user, port, err := parseSSHAddress(c.address, "root", 22)
+		if err != nil {
+			t.Errorf("%s: %s", c.address, err)
+			continue
+		}
+		if host != c.host || user != c.user || port != c.port {
+			t.Errorf("%s: want %s@%s:%d, got %s@%s:%d", c.address, c.user, c.host, c.port, user, host, port)
+		}
+	}
+
+	for _, address := range []string{"@192.168.0.100", "pi@", "192.168.0.100:ssh", "192.168.0.100:70000", "[fd00::1"} {
+		if _, _, _, err := parseSSHAddress(address, "root", 22); err == nil {
+			t.Errorf("%s: want an error", address)
+		}
+	}
+}
+
+func Test_privilegeEscalationFromFlags(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{}, escalateSudo},
+		{[]string{"--privilege-escalation", "doas"}, escalateDoas},
+		{[]string{"--sudo=false"}, escalateNone},
+		{[]string{"--sudo=false", "--privilege-escalation", "none"}, escalateNone},
+	}
+
+	for _, c := range cases {
+		command := MakeUninstall()
+		if err := command.Flags().Parse(c.args); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			t.Errorf("%v: %s", c.args, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%v: want %s, got %s", c.args, c.want, got)
+		}
+	}
+
+	for _, args := range [][]string{{"--privilege-escalation", "su"}, {"--sudo=false", "--privilege-escalation", "doas"}} {
+		command := MakeUninstall()
+		if err := command.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := privilegeEscalationFromFlags(command); err == nil {
+			t.Errorf("%v: want an error", args)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func makeInstallTekton() *cobra.Command {
+	var tekton = &cobra.Command{
+		Use:   "tekton",
+		Short: "Install tekton pipelines",
+		Long: `Install tekton pipelines for CI within the cluster. Use --triggers to run
+pipelines from webhooks, and --dashboard for a web UI.`,
+		Example: `  k3sup app install tekton
+  k3sup app install tekton --triggers --dashboard`,
+		SilenceUsage: true,
+	}
+
+	tekton.Flags().String("version", "v0.22.0", "The version of tekton pipelines to install")
+	tekton.Flags().Bool("triggers", false, "Also install tekton triggers")
+	tekton.Flags().String("triggers-version", "v0.12.1", "The version of tekton triggers to install")
+	tekton.Flags().Bool("dashboard", false, "Also install the tekton dashboard")
+	tekton.Flags().String("dashboard-version", "v0.15.0", "The version of the tekton dashboard to install")
+
+	tekton.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		version, _ := command.Flags().GetString("version")
+		triggers, _ := command.Flags().GetBool("triggers")
+		triggersVersion, _ := command.Flags().GetString("triggers-version")
+		dashboard, _ := command.Flags().GetBool("dashboard")
+		dashboardVersion, _ := command.Flags().GetString("dashboard-version")
+
+		manifests := getTektonManifests(version, triggers, triggersVersion, dashboard, dashboardVersion)
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return printManifests(command, manifests...)
+		}
+
+		if err := applyManifests("tekton", manifests[0]); err != nil {
+			return err
+		}
+
+		// triggers and the dashboard use the pipeline CRDs
+		if len(manifests) > 1 {
+			if err := waitForCRDs("pipelines.tekton.dev", "tasks.tekton.dev"); err != nil {
+				return err
+			}
+
+			if err := applyManifests("tekton", manifests[1:]...); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("tekton", version); err != nil {
+			return err
+		}
+
+		logInfo(tektonInstallMsg)
+
+		return nil
+	}
+
+	return tekton
+}
+
+// getTektonManifests returns the release manifests, pipelines come first
+func getTektonManifests(version string, triggers bool, triggersVersion string, dashboard bool, dashboardVersion string) []string {
+	const releases = "https://storage.googleapis.com/tekton-releases"
+
+	manifests := []string{
+		fmt.Sprintf("%s/pipeline/previous/%s/release.yaml", releases, version),
+	}
+
+	if triggers {
+		manifests = append(manifests,
+			fmt.Sprintf("%s/triggers/previous/%s/release.yaml", releases, triggersVersion),
+			fmt.Sprintf("%s/triggers/previous/%s/interceptors.yaml", releases, triggersVersion))
+	}
+
+	if dashboard {
+		manifests = append(manifests,
+			fmt.Sprintf("%s/dashboard/previous/%s/tekton-dashboard-release.yaml", releases, dashboardVersion))
+	}
+
+	return manifests
+}
+
+const tektonInfoMsg = `# Wait for the tekton controllers to start
+kubectl get pods -n tekton-pipelines -w
+
+# Get the tkn CLI, then run a Task
+tkn task start TASK --showlog
+
+# If you installed with --dashboard, forward it to your machine then
+# open http://127.0.0.1:9097
+kubectl port-forward -n tekton-pipelines svc/tekton-dashboard 9097:9097 &
+
+# Find out more at:
+# https://tekton.dev/docs/`
+
+const tektonInstallMsg = `=======================================================================
+= tekton has been installed.                                          =
+=======================================================================
+
+` + tektonInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,33 @@
+package cmd
+
+import "testing"
+
+func Test_getTektonManifests_PipelinesOnly(t *testing.T) {
+	got := getTektonManifests("v0.22.0", false, "v0.12.1", false, "v0.15.0")
+
+	want := "https://storage.googleapis.com/tekton-releases/pipeline/previous/v0.22.0/release.yaml"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("want: [%s], got: %v", want, got)
+	}
+}
+
+func Test_getTektonManifests_TriggersAndDashboard(t *testing.T) {
+	got := getTektonManifests("v0.22.0", true, "v0.12.1", true, "v0.15.0")
+
+	want := []string{
+		"https://storage.googleapis.com/tekton-releases/pipeline/previous/v0.22.0/release.yaml",
+		"https://storage.googleapis.com/tekton-releases/triggers/previous/v0.12.1/release.yaml",
+		"https://storage.googleapis.com/tekton-releases/triggers/previous/v0.12.1/interceptors.yaml",
+		"https://storage.googleapis.com/tekton-releases/dashboard/previous/v0.15.0/tekton-dashboard-release.yaml",
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("want %d manifests, got: %v", len(want), got)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("manifest %d, want: %s, got: %s", i, want[i], got[i])
+		}
+	}
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path"
 
@@ -23,16 +22,28 @@ func makeInstallTiller() *cobra.Command {
 	}
 
 	tiller.RunE = func(command *cobra.Command, args []string) error {
+		if err := rejectValuesFlags(command); err != nil {
+			return err
+		}
+
+		if dryRun, _ := command.Flags().GetBool("dry-run"); dryRun {
+			return fmt.Errorf("--dry-run is not supported for tiller, which is installed by helm init")
+		}
+
+		if helm3, _ := command.Flags().GetBool("helm3"); helm3 {
+			return fmt.Errorf("tiller is not used by helm 3, there is no need to install it")
+		}
+
 		kubeConfigPath := getDefaultKubeconfig()
 
 		if command.Flags().Changed("kubeconfig") {
 			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
 		}
 
-		fmt.Printf("Using kubeconfig: %s\n", kubeConfigPath)
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
 
 		arch := getArchitecture()
-		fmt.Printf("Node architecture: %q\n", arch)
+		logDebugf("Node architecture: %q\n", arch)
 
 		if arch != "x86_64" && arch != "amd64" {
 			return fmt.Errorf("This app is not known to work with the %s architecture", arch)
@@ -45,9 +56,9 @@ func makeInstallTiller() *cobra.Command {
 
 		clientArch, clientOS := getClientArch()
 
-		fmt.Printf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
 
-		log.Printf("User dir established as: %s\n", userPath)
+		logDebugf("User dir established as: %s\n", userPath)
 
 		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
 
@@ -56,13 +67,21 @@ func makeInstallTiller() *cobra.Command {
 			return err
 		}
 
-		fmt.Println(task.Stdout, task.Stderr)
+		logDebugf("%s %s\n", task.Stdout, task.Stderr)
+
+		if err := recordResource("tiller", "serviceaccount", "tiller", "kube-system"); err != nil {
+			return err
+		}
 
 		task, err = kubectlTask("create", "clusterrolebinding", "tiller", "--clusterrole", "cluster-admin", "--serviceaccount=kube-system:tiller")
 		if err != nil {
 			return err
 		}
-		fmt.Println(task.Stdout, task.Stderr)
+		logDebugf("%s %s\n", task.Stdout, task.Stderr)
+
+		if err := recordResource("tiller", "clusterrolebinding", "tiller", ""); err != nil {
+			return err
+		}
 
 		k3supBin := path.Join(userPath, ".bin")
 		helmInit := execute.ExecTask{
@@ -72,30 +91,46 @@ func makeInstallTiller() *cobra.Command {
 				"--skip-refresh", "--upgrade", "--service-account", "tiller",
 			},
 		}
-		res, err := helmInit.Execute()
+		res, err := runTask(helmInit)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println(res.Stdout, res.Stderr)
+		logDebugf("%s %s\n", res.Stdout, res.Stderr)
 
-		helmBinary, err := tryDownloadHelm(userPath, clientArch, clientOS)
-		if err != nil {
+		if err := recordResource("tiller", "deployment", "tiller-deploy", "kube-system"); err != nil {
 			return err
 		}
 
-		fmt.Println(`=======================================================================
-tiller has been installed
-=======================================================================
+		if err := recordResource("tiller", "service", "tiller-deploy", "kube-system"); err != nil {
+			return err
+		}
 
-# You can now use helm with tiller from the installation directory
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, false)
+		if err != nil {
+			return err
+		}
 
-` + helmBinary + `
+		if err := recordInstalled("tiller", helmVersion); err != nil {
+			return err
+		}
 
-Thank you for using k3sup!`)
+		logInfo(tillerInstallMsg)
 
 		return nil
 	}
 
 	return tiller
 }
+
+const tillerInfoMsg = `# You can now use helm with tiller from the installation directory
+
+$HOME/.k3sup/.bin/helm`
+
+const tillerInstallMsg = `=======================================================================
+tiller has been installed
+=======================================================================
+
+` + tillerInfoMsg + `
+
+` + thanksForUsing
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+const (
+	serverUninstallScript = "/usr/local/bin/k3s-uninstall.sh"
+	agentUninstallScript  = "/usr/local/bin/k3s-agent-uninstall.sh"
+)
+
+func MakeUninstall() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "uninstall",
+		Short: "Uninstall k3s from a server or agent via SSH",
+		Long: `Uninstall k3s from a server or agent via SSH by running the uninstall
+script which was created by the k3s installer.`,
+		Example: `  k3sup uninstall --ip 192.168.0.101 --user root
+  k3sup uninstall --ip 192.168.0.101 --user root --purge`,
+		SilenceUsage: true,
+	}
+
+	addSSHFlags(command)
+	command.Flags().Bool("purge", false, "Also remove /var/lib/rancher and /etc/rancher from the node")
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		opts, err := sshOptionsFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		escalation, err := privilegeEscalationFromFlags(command)
+		if err != nil {
+			return err
+		}
+		sudoPrefix := escalationPrefix(escalation)
+		purge, _ := command.Flags().GetBool("purge")
+
+		logDebugf("ssh -i %s -p %d %s@%s\n", opts.SSHKeyPath, opts.Port, opts.User, opts.Host)
+
+		operator, err := connectSSH(opts)
+		if err != nil {
+			return err
+		}
+
+		defer operator.Close()
+
+		script, err := findUninstallScript(operator)
+		if err != nil {
+			return err
+		}
+
+		if len(script) == 0 {
+			return fmt.Errorf("k3s does not appear to be installed on %s, no uninstall script found", opts.Host)
+		}
+
+		deleted := "its workloads"
+		if purge {
+			deleted = "its workloads, /var/lib/rancher and /etc/rancher"
+		}
+		if err := confirm("Uninstall k3s from %s, deleting %s?", opts.Host, deleted); err != nil {
+			return err
+		}
+
+		uninstallCommand := sudoPrefix + script
+		logDebugf("ssh: %s\n", uninstallCommand)
+
+		if _, err := operator.Execute(uninstallCommand); err != nil {
+			return fmt.Errorf("unable to uninstall k3s: %s", err)
+		}
+
+		if purge {
+			purgeCommand := sudoPrefix + "rm -rf /var/lib/rancher /etc/rancher"
+			logDebugf("ssh: %s\n", purgeCommand)
+
+			if _, err := operator.Execute(purgeCommand); err != nil {
+				return fmt.Errorf("unable to remove k3s data: %s", err)
+			}
+		}
+
+		logInfof("k3s has been uninstalled from %s\n", opts.Host)
+
+		return nil
+	}
+
+	return command
+}
+
+// findUninstallScript returns the uninstall script created by the k3s
+// installer, or an empty string when k3s is not installed
+func findUninstallScript(operator kssh.Operator) (string, error) {
+	findCommand := fmt.Sprintf("ls %s %s 2>/dev/null || true", serverUninstallScript, agentUninstallScript)
+	logDebugf("ssh: %s\n", findCommand)
+
+	res, err := operator.ExecuteQuiet(findCommand)
+	if err != nil {
+		return "", fmt.Errorf("unable to find the k3s uninstall script: %s", err)
+	}
+
+	return uninstallScript(string(res.StdOut)), nil
+}
+
+// confirmReinstall asks before k3s is installed again over an existing
+// install on host
+func confirmReinstall(operator kssh.Operator, host string) error {
+	script, err := findUninstallScript(operator)
+	if err != nil || len(script) == 0 {
+		return err
+	}
+
+	return confirm("k3s is already installed on %s, install it again over the existing install?", host)
+}
+
+// uninstallScript picks the uninstall script from the output of ls, a
+// server's script is preferred as it also removes any agent
+func uninstallScript(found string) string {
+	for _, script := range []string{serverUninstallScript, agentUninstallScript} {
+		for _, line := range strings.Split(found, "\n") {
+			if strings.TrimSpace(line) == script {
+				return script
+			}
+		}
+	}
+	return ""
+}
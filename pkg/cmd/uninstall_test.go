@@ -0,0 +1,19 @@
+package cmd
+
+import "testing"
+
+func Test_uninstallScript(t *testing.T) {
+	cases := map[string]string{
+		"/usr/local/bin/k3s-uninstall.sh\n":                                        serverUninstallScript,
+		"/usr/local/bin/k3s-agent-uninstall.sh\n":                                  agentUninstallScript,
+		"/usr/local/bin/k3s-agent-uninstall.sh\n/usr/local/bin/k3s-uninstall.sh\n": serverUninstallScript,
+		"": "",
+	}
+
+	for found, want := range cases {
+		got := uninstallScript(found)
+		if want != got {
+			t.Errorf("found: %q, want: %q, got: %q", found, want, got)
+		}
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
@@ -37,9 +36,9 @@ func untar(r io.Reader, dir string) (err error) {
 	defer func() {
 		td := time.Since(t0)
 		if err == nil {
-			log.Printf("extracted tarball into %s: %d files, %d dirs (%v)", dir, nFiles, len(madeDir), td)
+			logDebugf("extracted tarball into %s: %d files, %d dirs (%v)\n", dir, nFiles, len(madeDir), td)
 		} else {
-			log.Printf("error extracting tarball into %s after %d files, %d dirs, %v: %v", dir, nFiles, len(madeDir), td, err)
+			logDebugf("error extracting tarball into %s after %d files, %d dirs, %v: %v\n", dir, nFiles, len(madeDir), td, err)
 		}
 	}()
 	zr, err := gzip.NewReader(r)
@@ -54,7 +53,7 @@ func untar(r io.Reader, dir string) (err error) {
 			break
 		}
 		if err != nil {
-			log.Printf("tar reading error: %v", err)
+			logDebugf("tar reading error: %v\n", err)
 			return fmt.Errorf("tar error: %v", err)
 		}
 		if !validRelPath(f.Name) {
@@ -62,7 +61,7 @@ func untar(r io.Reader, dir string) (err error) {
 		}
 		baseFile := filepath.Base(f.Name)
 		abs := path.Join(dir, baseFile)
-		fmt.Println(abs, f.Name)
+		logDebugf("%s %s\n", abs, f.Name)
 
 		fi := f.FileInfo()
 		mode := fi.Mode()
@@ -102,7 +101,7 @@ func untar(r io.Reader, dir string) (err error) {
 					// on it anywhere (the gomote push command relies
 					// on digests only), so this is a little pointless
 					// for now.
-					log.Printf("error changing modtime: %v (further Chtimes errors suppressed)", err)
+					logDebugf("error changing modtime: %v (further Chtimes errors suppressed)\n", err)
 					loggedChtimesError = true // once is enough
 				}
 			}
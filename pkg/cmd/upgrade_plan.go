@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// planUpgrade is a node of a plan to upgrade, upgrade runs the installer on
+// it again with the new version. Node is the name it registered with, which
+// is found over SSH with args, its k3s flags.
+type planUpgrade struct {
+	Host    string
+	Role    string
+	Node    string
+	ssh     sshOptions
+	args    string
+	upgrade func() error
+}
+
+func makeUpgradePlan() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "plan",
+		Short: "Upgrade every node of a cluster from a plan file",
+		Long: `Upgrade every node described in the YAML or JSON plan file given to
+k3sup plan, for a rolling upgrade of the whole cluster. The servers are upgraded one at a
+time, in the order of the plan, then the agents, up to --concurrency at a
+time. Each node is drained first, which respects PodDisruptionBudgets, and is
+uncordoned once it is Ready with the new version.
+
+The upgrade stops at the first node which fails, which is left cordoned. Run
+the same command again to resume it, nodes which already run the version are
+skipped.`,
+		Example: `  k3sup upgrade plan --plan cluster.yaml --k3s-version v1.19.5+k3s1
+  k3sup upgrade plan --plan cluster.yaml --k3s-version v1.19.5+k3s1 --concurrency 2`,
+		SilenceUsage: true,
+	}
+
+	command.Flags().String("plan", "", "The plan file the cluster was provisioned from")
+	command.Flags().String("k3s-version", "", "The version of k3s to upgrade to")
+	command.Flags().String("kubeconfig", "kubeconfig", "Local path to the kubeconfig for the cluster")
+	command.Flags().Int("concurrency", 1, "The maximum number of agents to upgrade at the same time")
+	command.Flags().Duration("timeout", 5*time.Minute, "How long to wait for each node to be drained, then to become Ready")
+	command.Flags().String("ssh-key-passphrase-file", "", "File holding the passphrase of the encrypted ssh-keys in the plan, instead of prompting for them")
+	addSSHPasswordFlags(command)
+	addHostKeyFlags(command)
+
+	command.RunE = func(command *cobra.Command, args []string) error {
+		planFile, _ := command.Flags().GetString("plan")
+		data, err := ioutil.ReadFile(planFile)
+		if err != nil {
+			return err
+		}
+
+		plan, err := parsePlan(data)
+		if err != nil {
+			return err
+		}
+
+		if plan.password, err = sshPasswordFromFlags(command); err != nil {
+			return err
+		}
+
+		if plan.keyPassphrase, err = sshKeyPassphraseFromFlags(command); err != nil {
+			return err
+		}
+
+		if plan.hostKeyChecking, plan.knownHosts, err = hostKeyOptionsFromFlags(command); err != nil {
+			return err
+		}
+
+		plan.K3sVersion, _ = command.Flags().GetString("k3s-version")
+		plan.K3sChannel = ""
+		kubeconfigPath, _ := command.Flags().GetString("kubeconfig")
+		kubeconfigPath = expandPath(kubeconfigPath)
+		concurrency, _ := command.Flags().GetInt("concurrency")
+		timeout, _ := command.Flags().GetDuration("timeout")
+
+		servers, agents := plan.upgrades()
+
+		// Every node is found before any is drained, so that a mistake in
+		// the plan does not leave the cluster half upgraded
+		for _, nodes := range [][]planUpgrade{servers, agents} {
+			for i := range nodes {
+				if nodes[i].Node, err = planUpgradeNode(nodes[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Servers one at a time, so that etcd keeps its quorum
+		if err := upgradePlanNodes(servers, 1, kubeconfigPath, plan.K3sVersion, timeout); err != nil {
+			return err
+		}
+
+		if err := upgradePlanNodes(agents, concurrency, kubeconfigPath, plan.K3sVersion, timeout); err != nil {
+			return err
+		}
+
+		logInfof("Upgraded %d server(s) and %d agent(s) to %s\n", len(servers), len(agents), plan.K3sVersion)
+
+		return nil
+	}
+
+	command.PreRunE = func(command *cobra.Command, args []string) error {
+		if planFile, _ := command.Flags().GetString("plan"); len(planFile) == 0 {
+			return fmt.Errorf("give the plan file of the cluster with --plan")
+		}
+
+		// A channel would not say which nodes have been upgraded already
+		if version, _ := command.Flags().GetString("k3s-version"); len(version) == 0 {
+			return fmt.Errorf("give the version to upgrade to with --k3s-version")
+		}
+
+		return nil
+	}
+
+	return command
+}
+
+// upgrades returns the servers and agents of the plan, each of which is
+// upgraded with the options it was installed or joined with by k3sup plan
+func (p *clusterPlan) upgrades() ([]planUpgrade, []planUpgrade) {
+	first := p.node(p.Servers[0])
+	servers := []planUpgrade{{
+		Host: first.Host,
+		Role: "server",
+		ssh:  first.sshOptions(),
+		args: p.serverArgs(first),
+		upgrade: func() error {
+			return upgradeServer(installOptions{
+				SSH:                 first.sshOptions(),
+				PrivilegeEscalation: p.privilegeEscalation(),
+				K3sVersion:          p.K3sVersion,
+				Proxy:               p.Proxy,
+				Registry:            p.Registry,
+				Cache:               p.Cache,
+				SkipPreflight:       true,
+				K3sExtraArgs:        p.serverArgs(first),
+				TLSSANs:             p.TLSSANs,
+				Cluster:             p.Cluster,
+				Datastore:           p.datastore(),
+			})
+		},
+	}}
+
+	join := func(n planNode, server bool) planUpgrade {
+		args, role := n.k3sArgs(), "agent"
+		if server {
+			args, role = p.serverArgs(n), "server"
+		}
+
+		opts := joinOptions{
+			Agent:               n.sshOptions(),
+			Server:              first.sshOptions(),
+			PrivilegeEscalation: p.privilegeEscalation(),
+			JoinAsServer:        server,
+			K3sVersion:          p.K3sVersion,
+			Proxy:               p.Proxy,
+			Registry:            p.Registry,
+			Cache:               p.Cache,
+			SkipPreflight:       true,
+			K3sExtraArgs:        args,
+			Upgrade:             true,
+		}
+		if server {
+			opts.Datastore = p.datastore()
+			opts.TLSSANs = p.TLSSANs
+		}
+
+		return planUpgrade{
+			Host: n.Host,
+			Role: role,
+			ssh:  opts.Agent,
+			args: args,
+			upgrade: func() error {
+				return upgradeAgent(opts, escalationPrefix(p.privilegeEscalation()))
+			},
+		}
+	}
+
+	for _, n := range p.Servers[1:] {
+		servers = append(servers, join(p.node(n), true))
+	}
+
+	agents := []planUpgrade{}
+	for _, n := range p.Agents {
+		agents = append(agents, join(p.node(n), false))
+	}
+
+	return servers, agents
+}
+
+// planUpgradeNode returns the name which the node of u registered with
+func planUpgradeNode(u planUpgrade) (string, error) {
+	operator, err := connectSSH(u.ssh)
+	if err != nil {
+		return "", err
+	}
+	defer operator.Close()
+
+	return k3sNodeName(operator, u.args)
+}
+
+// upgradePlanNodes upgrades nodes, at most concurrency at a time. Once one
+// fails no more are started, so that a broken release is not rolled out to
+// the rest of the cluster.
+func upgradePlanNodes(nodes []planUpgrade, concurrency int, kubeconfigPath, version string, timeout time.Duration) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		lock    sync.Mutex
+		failed  []string
+		stopped bool
+	)
+
+	jobs := make(chan planUpgrade)
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency && w < len(nodes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				start := time.Now()
+				err := upgradePlanNode(n, kubeconfigPath, version, timeout)
+				recordNode(n.Host, n.Role, time.Since(start), err)
+
+				if err != nil {
+					logInfof("[%s] failed after %s: %s\n", n.Host, time.Since(start).Round(time.Second), err)
+
+					lock.Lock()
+					failed = append(failed, fmt.Sprintf("  %s: %s", n.Host, err))
+					stopped = true
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, n := range nodes {
+		lock.Lock()
+		stop := stopped
+		lock.Unlock()
+		if stop {
+			break
+		}
+
+		jobs <- n
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d node(s) failed to upgrade, run the same command again to resume once fixed:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// upgradePlanNode upgrades n unless it already runs version, as after a
+// previous run which failed. A node which was upgraded but then not
+// uncordoned is uncordoned once it is Ready, but only when k3sup cordoned it.
+func upgradePlanNode(n planUpgrade, kubeconfigPath, version string, timeout time.Duration) error {
+	upgraded, cordoned, marked, err := nodeUpgradeState(kubeconfigPath, n.Node, version)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case upgraded && !cordoned:
+		logInfof("[%s] node %s already runs %s\n", n.Host, n.Node, version)
+		return nil
+	case upgraded && !marked:
+		logInfof("[%s] node %s already runs %s, it was not cordoned by k3sup so is left cordoned\n", n.Host, n.Node, version)
+		return nil
+	}
+
+	logInfof("[%s] upgrading %s %s\n", n.Host, n.Role, n.Node)
+
+	if upgraded {
+		return upgradeNode(kubeconfigPath, n.Node, version, timeout, func() error { return nil })
+	}
+	return upgradeNode(kubeconfigPath, n.Node, version, timeout, n.upgrade)
+}
+
+// upgradeState parses "kubeletVersion,unschedulable,annotation" where the
+// annotation is upgradeCordonAnnotation. The version is only compared for
+// releases named like v1.19.5+k3s1, as for nodeReady.
+func upgradeState(output, version string) (upgraded, cordoned, marked bool) {
+	parts := strings.Split(strings.TrimSpace(output), ",")
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+
+	upgraded = strings.Contains(version, "+k3s") && parts[0] == version
+	cordoned = parts[1] == "true"
+	marked = parts[2] == "true"
+	return upgraded, cordoned, marked
+}
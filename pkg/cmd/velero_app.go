@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/alexellis/k3sup/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// veleroOptions configures the backup storage location for velero
+type veleroOptions struct {
+	Bucket string
+	Region string
+	S3URL  string
+	Restic bool
+}
+
+func makeInstallVelero() *cobra.Command {
+	var velero = &cobra.Command{
+		Use:   "velero",
+		Short: "Install velero",
+		Long: `Install velero to back up the cluster's resources, and with --restic the
+contents of its volumes, to a bucket in S3 or an S3-compatible store such as
+minio given by --s3-url.
+
+The credentials are read from --access-key-id and --secret-access-key-file,
+or from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, and saved in the
+velero-credentials secret.`,
+		Example: `  k3sup app install velero --provider s3 --bucket backups --region eu-west-1
+  k3sup app install velero --provider s3 --bucket backups --restic \
+    --s3-url http://minio.default:9000`,
+		SilenceUsage: true,
+	}
+
+	velero.Flags().StringP("namespace", "n", "velero", "The namespace used for installation")
+	velero.Flags().String("version", "", "The version of the velero chart (Default to the latest)")
+	velero.Flags().String("provider", "s3", "The object store for backups, only s3 is supported")
+	velero.Flags().String("bucket", "", "The bucket to store backups in")
+	velero.Flags().String("region", "us-east-1", "The region of the bucket")
+	velero.Flags().String("s3-url", "", "The URL of an S3-compatible store, instead of AWS")
+	velero.Flags().String("access-key-id", "", "The access key ID for the bucket (Default to $AWS_ACCESS_KEY_ID)")
+	velero.Flags().String("secret-access-key-file", "", "File with the secret access key for the bucket (Default to $AWS_SECRET_ACCESS_KEY)")
+	velero.Flags().Bool("restic", false, "Run restic on each node to back up the contents of volumes")
+	velero.Flags().Bool("update-repo", true, "Update the helm repo")
+
+	velero.RunE = func(command *cobra.Command, args []string) error {
+		kubeConfigPath := getDefaultKubeconfig()
+
+		if command.Flags().Changed("kubeconfig") {
+			kubeConfigPath, _ = command.Flags().GetString("kubeconfig")
+		}
+
+		logInfof("Using kubeconfig: %s\n", kubeConfigPath)
+
+		namespace, _ := command.Flags().GetString("namespace")
+		provider, _ := command.Flags().GetString("provider")
+
+		opts := veleroOptions{}
+		opts.Bucket, _ = command.Flags().GetString("bucket")
+		opts.Region, _ = command.Flags().GetString("region")
+		opts.S3URL, _ = command.Flags().GetString("s3-url")
+		opts.Restic, _ = command.Flags().GetBool("restic")
+
+		if provider != "s3" {
+			return fmt.Errorf("only --provider s3 is supported, not: %q", provider)
+		}
+
+		if len(opts.Bucket) == 0 {
+			return fmt.Errorf("--bucket is required, give the bucket to store backups in")
+		}
+
+		dryRun, err := dryRunFromFlags(command)
+		if err != nil {
+			return err
+		}
+
+		credentials := ""
+		if !dryRun {
+			credentials, err = veleroCredentialsFromFlags(command)
+			if err != nil {
+				return err
+			}
+		}
+
+		userPath, err := config.InitUserDir()
+		if err != nil {
+			return err
+		}
+
+		clientArch, clientOS := getClientArch()
+
+		logDebugf("Client: %q, %q\n", clientArch, clientOS)
+		logDebugf("User dir established as: %s\n", userPath)
+
+		os.Setenv("HELM_HOME", path.Join(userPath, ".helm"))
+
+		helm3, _ := command.Flags().GetBool("helm3")
+
+		_, err = tryDownloadHelm(userPath, clientArch, clientOS, helm3)
+		if err != nil {
+			return err
+		}
+
+		err = addHelmRepo("vmware-tanzu", "https://vmware-tanzu.github.io/helm-charts", helm3)
+		if err != nil {
+			return err
+		}
+
+		if updateRepo, _ := command.Flags().GetBool("update-repo"); updateRepo {
+			err = updateHelmRepos(helm3)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !dryRun {
+			if err := createNamespace("velero", namespace); err != nil {
+				return err
+			}
+
+			if err := createVeleroCredentialsSecret(namespace, credentials); err != nil {
+				return err
+			}
+		}
+
+		version, _ := command.Flags().GetString("version")
+		chartPath := path.Join(os.TempDir(), "charts")
+		err = fetchChart(chartPath, "vmware-tanzu/velero", version, helm3)
+		if err != nil {
+			return err
+		}
+
+		outputPath := path.Join(chartPath, "velero/rendered")
+		overrides, userValues, err := chartValuesFromFlags(command, veleroOverrides(opts))
+		if err != nil {
+			return err
+		}
+
+		if helm3 {
+			err = helm3Install(chartPath, "velero",
+				namespace,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if err := recordRelease("velero", "velero", namespace); err != nil {
+				return err
+			}
+		} else {
+			err = templateChart(chartPath, "velero",
+				namespace,
+				outputPath,
+				"values.yaml",
+				overrides, userValues)
+
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				logWarn(`The "velero-credentials" secret is created when velero is installed, so it is not included`)
+				return printManifests(command, outputPath)
+			}
+
+			err = kubectl("-n", namespace, "apply", "-R", "-f", outputPath)
+			if err != nil {
+				return err
+			}
+
+			if err := recordManifest("velero", outputPath); err != nil {
+				return err
+			}
+		}
+
+		if err := recordInstalled("velero", chartVersion(chartPath, "velero")); err != nil {
+			return err
+		}
+
+		logInfo(veleroInstallMsg)
+
+		return nil
+	}
+
+	return velero
+}
+
+// veleroOverrides configures the AWS plugin, which also works with
+// S3-compatible stores. Volume snapshots are turned off as k3s has no
+// snapshot provider, restic is used for volumes instead.
+func veleroOverrides(opts veleroOptions) map[string]string {
+	overrides := map[string]string{
+		"configuration.provider":                            "aws",
+		"configuration.backupStorageLocation.name":          "default",
+		"configuration.backupStorageLocation.bucket":        opts.Bucket,
+		"configuration.backupStorageLocation.config.region": opts.Region,
+		"credentials.existingSecret":                        "velero-credentials",
+		"snapshotsEnabled":                                  "false",
+		"deployRestic":                                      strconv.FormatBool(opts.Restic),
+		"initContainers[0].name":                            "velero-plugin-for-aws",
+		"initContainers[0].image":                           "velero/velero-plugin-for-aws:v1.1.0",
+		"initContainers[0].volumeMounts[0].mountPath":       "/target",
+		"initContainers[0].volumeMounts[0].name":            "plugins",
+	}
+
+	if len(opts.S3URL) > 0 {
+		overrides["configuration.backupStorageLocation.config.s3Url"] = opts.S3URL
+		overrides["configuration.backupStorageLocation.config.s3ForcePathStyle"] = "true"
+	}
+
+	return overrides
+}
+
+// veleroCredentialsFromFlags returns the credentials file for the AWS
+// plugin
+func veleroCredentialsFromFlags(command *cobra.Command) (string, error) {
+	accessKeyID, _ := command.Flags().GetString("access-key-id")
+	secretFile, _ := command.Flags().GetString("secret-access-key-file")
+
+	if len(accessKeyID) == 0 {
+		accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+
+	secret := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if len(secretFile) > 0 {
+		data, err := ioutil.ReadFile(expandPath(secretFile))
+		if err != nil {
+			return "", fmt.Errorf("unable to read --secret-access-key-file: %s", err)
+		}
+		secret = string(data)
+	}
+
+	return veleroCredentials(accessKeyID, secret)
+}
+
+func veleroCredentials(accessKeyID, secret string) (string, error) {
+	accessKeyID = strings.TrimSpace(accessKeyID)
+	secret = strings.TrimSpace(secret)
+
+	if len(accessKeyID) == 0 || len(secret) == 0 {
+		return "", fmt.Errorf("give the credentials for the bucket with --access-key-id and --secret-access-key-file, or $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY")
+	}
+
+	return fmt.Sprintf("[default]\naws_access_key_id=%s\naws_secret_access_key=%s\n", accessKeyID, secret), nil
+}
+
+// createVeleroCredentialsSecret saves the credentials, replacing those
+// from an earlier install
+func createVeleroCredentialsSecret(namespace, credentials string) error {
+	credentialsFile, err := writeTempFile("velero_credentials", []byte(credentials))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(credentialsFile)
+
+	kubectl("delete", "secret", "velero-credentials", "--namespace", namespace, "--ignore-not-found")
+
+	res, err := kubectlTask("create", "secret", "generic", "velero-credentials",
+		"--namespace", namespace,
+		"--from-file", "cloud="+credentialsFile)
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		return fmt.Errorf("unable to create the velero-credentials secret: %s", res.Stderr)
+	}
+
+	return recordSecret("velero", "velero-credentials", namespace)
+}
+
+const veleroInfoMsg = `# Get the velero CLI, then back up the cluster
+velero backup create my-backup
+velero backup describe my-backup
+
+# Volumes are only backed up with --restic, for Pods annotated with:
+# backup.velero.io/backup-volumes: VOLUME_NAME
+
+# Restore from a backup
+velero restore create --from-backup my-backup
+
+# Find out more at:
+# https://velero.io/docs/`
+
+const veleroInstallMsg = `=======================================================================
+= velero has been installed.                                          =
+=======================================================================
+
+` + veleroInfoMsg + `
+
+` + thanksForUsing
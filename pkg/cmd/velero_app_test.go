@@ -0,0 +1,36 @@
+package cmd
+
+import "testing"
+
+func Test_veleroCredentials(t *testing.T) {
+	got, err := veleroCredentials("AKIA", "secret\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[default]\naws_access_key_id=AKIA\naws_secret_access_key=secret\n"
+	if got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+
+	if _, err := veleroCredentials("AKIA", ""); err == nil {
+		t.Errorf("want error without a secret access key")
+	}
+}
+
+func Test_veleroOverrides_S3Compatible(t *testing.T) {
+	overrides := veleroOverrides(veleroOptions{Bucket: "backups", Region: "us-east-1", S3URL: "http://minio:9000", Restic: true})
+
+	want := map[string]string{
+		"configuration.backupStorageLocation.bucket":                  "backups",
+		"configuration.backupStorageLocation.config.s3Url":            "http://minio:9000",
+		"configuration.backupStorageLocation.config.s3ForcePathStyle": "true",
+		"deployRestic": "true",
+	}
+
+	for k, v := range want {
+		if overrides[k] != v {
+			t.Errorf("%s want: %q, got: %q", k, v, overrides[k])
+		}
+	}
+}
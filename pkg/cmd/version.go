@@ -14,24 +14,71 @@ var (
 
 func PrintK3supASCIIArt() {
 	k3supLogo := aec.RedF.Apply(k3supFigletStr)
-	fmt.Print(k3supLogo)
+	fmt.Fprint(textOutput, k3supLogo)
 }
 
 func MakeVersion() *cobra.Command {
 	var command = &cobra.Command{
-		Use:          "version",
-		Short:        "Print the version",
-		Example:      `  k3sup version`,
+		Use:   "version",
+		Short: "Print the version",
+		Long: `Print the version. Give --check to find out whether a newer release of
+k3sup is available from GitHub, and --self-update to download it and replace
+this binary.`,
+		Example: `  k3sup version
+  k3sup version --check
+  sudo k3sup version --self-update`,
 		SilenceUsage: false,
 	}
-	command.Run = func(cmd *cobra.Command, args []string) {
+
+	command.Flags().Bool("check", false, "Check GitHub for a newer release of k3sup")
+	command.Flags().Bool("self-update", false, "Replace this binary with the latest release when it is newer")
+
+	command.RunE = func(cmd *cobra.Command, args []string) error {
+		check, _ := cmd.Flags().GetBool("check")
+		update, _ := cmd.Flags().GetBool("self-update")
+
 		PrintK3supASCIIArt()
 		if len(Version) == 0 {
-			fmt.Println("Version: dev")
+			fmt.Fprintln(textOutput, "Version: dev")
 		} else {
-			fmt.Println("Version:", Version)
+			fmt.Fprintln(textOutput, "Version:", Version)
+		}
+		fmt.Fprintln(textOutput, "Git Commit:", GitCommit)
+
+		updateResult(func(r *commandResult) {
+			r.Version = Version
+		})
+
+		if !check && !update {
+			return nil
+		}
+
+		latest, err := latestK3supVersion()
+		if err != nil {
+			return err
 		}
-		fmt.Println("Git Commit:", GitCommit)
+
+		updateResult(func(r *commandResult) {
+			r.LatestVersion = latest
+		})
+
+		if !newerK3supVersion(Version, latest) {
+			fmt.Fprintln(textOutput, "k3sup is up to date")
+			return nil
+		}
+
+		if !update {
+			fmt.Fprintf(textOutput, "A newer version of k3sup is available: %s, run k3sup version --self-update or see %s\n", latest, k3supReleasesURL)
+			return nil
+		}
+
+		executable, err := selfUpdate(latest)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(textOutput, "Updated %s to %s\n", executable, latest)
+		return nil
 	}
 	return command
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// addWaitReadyFlags adds the flags to wait for a node to become Ready after
+// it has been installed or joined
+func addWaitReadyFlags(command *cobra.Command) {
+	command.Flags().Bool("wait-ready", false, "Wait for the node to report Ready through the Kubernetes API, which needs kubectl")
+	command.Flags().Duration("timeout", 5*time.Minute, "How long to wait with --wait-ready")
+}
+
+// validWaitReadyFlags checks that kubectl can be found for --wait-ready
+func validWaitReadyFlags(command *cobra.Command) error {
+	if wait, _ := command.Flags().GetBool("wait-ready"); !wait {
+		return nil
+	}
+
+	if printCommand, _ := command.Flags().GetBool("print-command"); printCommand {
+		return fmt.Errorf("give either --wait-ready or --print-command, not both")
+	}
+
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		return fmt.Errorf("--wait-ready needs kubectl in your PATH, get it from https://kubernetes.io/docs/tasks/tools/")
+	}
+	return nil
+}
+
+// k3sNodeName returns the name which the node registers with, which is
+// given by --node-name in the extra args or is the node's hostname
+func k3sNodeName(operator kssh.Operator, k3sExtraArgs string) (string, error) {
+	if name := nodeNameFromArgs(k3sExtraArgs); len(name) > 0 {
+		return name, nil
+	}
+
+	logDebugf("ssh: hostname\n")
+	res, err := operator.ExecuteQuiet("hostname")
+	if err != nil {
+		return "", fmt.Errorf("unable to find the hostname of the node: %s", err)
+	}
+
+	// k3s registers the node with its hostname in lower case
+	return strings.ToLower(strings.TrimSpace(string(res.StdOut))), nil
+}
+
+func nodeNameFromArgs(args string) string {
+	fields := strings.Fields(args)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "--node-name=") {
+			return strings.TrimPrefix(field, "--node-name=")
+		}
+		if field == "--node-name" && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import "testing"
+
+func Test_nodeNameFromArgs(t *testing.T) {
+	cases := []struct {
+		args string
+		want string
+	}{
+		{"", ""},
+		{"--no-deploy traefik", ""},
+		{"--node-name worker-1", "worker-1"},
+		{"--docker --node-name=worker-2", "worker-2"},
+		{"--node-name", ""},
+	}
+
+	for _, c := range cases {
+		if got := nodeNameFromArgs(c.args); got != c.want {
+			t.Errorf("args: %q, want: %q, got: %q", c.args, c.want, got)
+		}
+	}
+}
+
+func Test_k3sNodeName_Hostname(t *testing.T) {
+	operator := &nodeOperator{replies: map[string]string{"hostname": "Node-1\n"}}
+
+	got, err := k3sNodeName(operator, "--docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "node-1" {
+		t.Errorf("want: %q, got: %q", "node-1", got)
+	}
+}
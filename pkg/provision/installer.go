@@ -0,0 +1,251 @@
+package provision
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	airgapBinaryPath        = "/usr/local/bin/k3s"
+	airgapImagesDir         = "/var/lib/rancher/k3s/agent/images/"
+	airgapInstallScriptPath = "/tmp/k3sup-install.sh"
+	datastoreTLSDir         = "/etc/rancher/k3s/datastore/"
+)
+
+// Airgap are the local files which are uploaded to a node instead of
+// downloading k3s from the Internet
+type Airgap struct {
+	Enabled       bool
+	Binary        string
+	Images        string
+	InstallScript string
+}
+
+// Datastore configures an external datastore such as MySQL, Postgres or
+// etcd for the servers of an HA cluster. The TLS files are local paths
+// which are uploaded to each server.
+type Datastore struct {
+	Endpoint string `json:"endpoint,omitempty"`
+	CAFile   string `json:"cafile,omitempty"`
+	CertFile string `json:"certfile,omitempty"`
+	KeyFile  string `json:"keyfile,omitempty"`
+}
+
+// redactedEndpoint returns the endpoint with its user and password replaced,
+// i.e. mysql://***@tcp(db:3306)/k3s
+func (d Datastore) redactedEndpoint() string {
+	scheme := strings.Index(d.Endpoint, "://")
+	at := strings.LastIndex(d.Endpoint, "@")
+	if scheme < 0 || at < scheme {
+		return d.Endpoint
+	}
+
+	return d.Endpoint[:scheme+3] + "***" + d.Endpoint[at:]
+}
+
+// Redact replaces the endpoint in s with one which leaves out its
+// credentials, as for a command which is logged or printed
+func (d Datastore) Redact(s string) string {
+	redacted := d.redactedEndpoint()
+	if redacted == d.Endpoint {
+		return s
+	}
+
+	s = strings.Replace(s, shellQuote(d.Endpoint), shellQuote(redacted), -1)
+	return strings.Replace(s, d.Endpoint, redacted, -1)
+}
+
+// RedactToken replaces the join-token in s with ***, as for a command which
+// is logged or printed
+func RedactToken(s, token string) string {
+	if len(token) == 0 {
+		return s
+	}
+
+	s = strings.Replace(s, shellQuote(token), shellQuote("***"), -1)
+	return strings.Replace(s, token, "***", -1)
+}
+
+// installerCommand returns the command which runs the k3s installer on a
+// node with the given environment variables. The installer
+// runs sudo itself when it is not root, so only doas is added here, with env
+// as doas does not keep the environment. The proxy is given to both curl
+// and the installer.
+func installerCommand(env []string, airgap Airgap, proxy Proxy, escalation string) string {
+	if airgap.Enabled {
+		env = append([]string{"INSTALL_K3S_SKIP_DOWNLOAD='true'"}, env...)
+	}
+	env = append(env, proxy.env()...)
+
+	envStr := strings.Join(env, " ")
+	if escalation == EscalateDoas {
+		envStr = strings.TrimSpace("doas env " + envStr)
+	}
+
+	if airgap.Enabled {
+		return fmt.Sprintf("%s sh %s", envStr, airgapInstallScriptPath)
+	}
+
+	curl := strings.TrimSpace(strings.Join(proxy.env(), " ") + " curl -sfL https://get.k3s.io")
+	return fmt.Sprintf("%s | %s sh -s -", curl, envStr)
+}
+
+// installExecEnv returns INSTALL_K3S_EXEC for the installer, which runs k3s
+// with command, i.e. server or agent, and the given flags followed by the
+// extra args
+func installExecEnv(command string, flags ...string) string {
+	exec := strings.Join(append([]string{command}, flags...), " ")
+	return "INSTALL_K3S_EXEC=" + shellQuote(strings.Join(strings.Fields(exec), " "))
+}
+
+// shellQuote quotes s in single quotes for the shell on the node
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// versionEnv returns the installer environment to pin k3s to version, or to
+// follow channel when no version is given. Air-gapped installs use the
+// uploaded binary, so need neither.
+func versionEnv(version, channel string, airgap Airgap) []string {
+	if airgap.Enabled {
+		return []string{}
+	}
+
+	if len(version) > 0 {
+		return []string{fmt.Sprintf("INSTALL_K3S_VERSION='%s'", version)}
+	}
+
+	if len(channel) > 0 {
+		return []string{fmt.Sprintf("INSTALL_K3S_CHANNEL='%s'", channel)}
+	}
+
+	return []string{}
+}
+
+// tlsSANArgs returns the --tls-san flags for k3s, so that the server's
+// certificate is also valid for each of sans
+func tlsSANArgs(sans []string) string {
+	args := []string{}
+	for _, san := range sans {
+		args = append(args, "--tls-san "+san)
+	}
+	return strings.Join(args, " ")
+}
+
+// localPath expands ~ in the path of a local file
+func localPath(p string) string {
+	res, _ := homedir.Expand(p)
+	return res
+}
+
+// uploadAirgap copies the k3s binary, the optional images tarball and the
+// installer script to the paths which k3s expects on the node
+func uploadAirgap(operator kssh.Operator, airgap Airgap, sudoPrefix string) error {
+	if len(airgap.Binary) == 0 || len(airgap.InstallScript) == 0 {
+		return fmt.Errorf("--airgap requires --airgap-binary and --airgap-install-script")
+	}
+
+	uploads := []struct {
+		local  string
+		remote string
+	}{
+		{airgap.Binary, "/tmp/k3sup-k3s"},
+		{airgap.InstallScript, airgapInstallScriptPath},
+	}
+
+	if len(airgap.Images) > 0 {
+		uploads = append(uploads, struct {
+			local  string
+			remote string
+		}{airgap.Images, path.Join("/tmp", path.Base(airgap.Images))})
+	}
+
+	for _, upload := range uploads {
+		log.Infof("Uploading %s to %s\n", upload.local, upload.remote)
+
+		file, err := os.Open(localPath(upload.local))
+		if err != nil {
+			return err
+		}
+
+		err = operator.Upload(file, upload.remote)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	commands := []string{
+		fmt.Sprintf("%sinstall -m 755 /tmp/k3sup-k3s %s", sudoPrefix, airgapBinaryPath),
+		"rm /tmp/k3sup-k3s",
+	}
+
+	if len(airgap.Images) > 0 {
+		commands = append(commands,
+			fmt.Sprintf("%smkdir -p %s", sudoPrefix, airgapImagesDir),
+			fmt.Sprintf("%smv %s %s", sudoPrefix, path.Join("/tmp", path.Base(airgap.Images)), airgapImagesDir))
+	}
+
+	for _, command := range commands {
+		log.Debugf("ssh: %s\n", command)
+		if _, err := operator.Execute(command); err != nil {
+			return fmt.Errorf("unable to place air-gap files: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// uploadDatastoreTLS copies the datastore's TLS files to the node and
+// returns the installer environment which points k3s at the datastore
+func uploadDatastoreTLS(operator kssh.Operator, datastore Datastore, sudoPrefix string) ([]string, error) {
+	env := []string{
+		"K3S_DATASTORE_ENDPOINT=" + shellQuote(datastore.Endpoint),
+	}
+
+	files := []struct {
+		local  string
+		remote string
+		envVar string
+	}{
+		{datastore.CAFile, "ca.crt", "K3S_DATASTORE_CAFILE"},
+		{datastore.CertFile, "client.crt", "K3S_DATASTORE_CERTFILE"},
+		{datastore.KeyFile, "client.key", "K3S_DATASTORE_KEYFILE"},
+	}
+
+	for _, f := range files {
+		if len(f.local) == 0 {
+			continue
+		}
+
+		tmpPath := "/tmp/k3sup-datastore-" + f.remote
+		remotePath := path.Join(datastoreTLSDir, f.remote)
+		log.Infof("Uploading %s to %s\n", f.local, remotePath)
+
+		file, err := os.Open(localPath(f.local))
+		if err != nil {
+			return nil, err
+		}
+
+		err = operator.Upload(file, tmpPath)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		command := fmt.Sprintf("%sinstall -D -m 600 %s %s && rm %s", sudoPrefix, tmpPath, remotePath, tmpPath)
+		log.Debugf("ssh: %s\n", command)
+		if _, err := operator.Execute(command); err != nil {
+			return nil, fmt.Errorf("unable to place datastore TLS file: %s", err)
+		}
+
+		env = append(env, f.envVar+"="+shellQuote(remotePath))
+	}
+
+	return env, nil
+}
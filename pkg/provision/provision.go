@@ -0,0 +1,58 @@
+// Package provision installs k3s on servers and joins agents to them over a
+// kssh.Operator, so that k3sup can be embedded by other Go programs. It does
+// not connect to nodes itself, give it an operator from pkg/ssh.
+package provision
+
+import "fmt"
+
+// Values for PrivilegeEscalation, the tool used to run commands which need
+// root on a node
+const (
+	EscalateSudo = "sudo"
+	EscalateDoas = "doas"
+	EscalateNone = "none"
+)
+
+// Logger receives the progress of an install, Debugf is given each command
+// which is run on the node
+type Logger interface {
+	Infof(format string, a ...interface{})
+	Debugf(format string, a ...interface{})
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Infof(format string, a ...interface{})  {}
+func (discardLogger) Debugf(format string, a ...interface{}) {}
+
+var log Logger = discardLogger{}
+
+// SetLogger sets where progress is written, nothing is written by default
+func SetLogger(logger Logger) {
+	if logger == nil {
+		logger = discardLogger{}
+	}
+	log = logger
+}
+
+// InstallerError is returned when the k3s installer fails on a node
+type InstallerError struct {
+	Host string
+	Err  error
+}
+
+func (e *InstallerError) Error() string {
+	return fmt.Sprintf("the k3s installer failed on %s: %s", e.Host, e.Err)
+}
+
+func (e *InstallerError) Unwrap() error {
+	return e.Err
+}
+
+// escalationPrefix returns the prefix for remote commands which need root
+func escalationPrefix(escalation string) string {
+	if escalation == EscalateNone || len(escalation) == 0 {
+		return ""
+	}
+	return escalation + " "
+}
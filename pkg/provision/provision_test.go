@@ -0,0 +1,156 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// recorder is an operator which records the commands run on it, and fails
+// those which start with fail. A command which starts with a key of stdout
+// prints its value.
+type recorder struct {
+	lines  []string
+	fail   string
+	stdout map[string]string
+}
+
+func (r *recorder) Execute(command string) (kssh.CommandRes, error) {
+	r.lines = append(r.lines, strings.TrimSpace(command))
+	if len(r.fail) > 0 && strings.HasPrefix(command, r.fail) {
+		return kssh.CommandRes{}, &kssh.CommandError{Command: command, ExitStatus: 1}
+	}
+	for prefix, stdout := range r.stdout {
+		if strings.HasPrefix(command, prefix) {
+			return kssh.CommandRes{StdOut: []byte(stdout)}, nil
+		}
+	}
+	return kssh.CommandRes{}, nil
+}
+
+func (r *recorder) ExecuteQuiet(command string) (kssh.CommandRes, error) {
+	return r.Execute(command)
+}
+
+func (r *recorder) Upload(reader io.Reader, remotePath string) error {
+	r.lines = append(r.lines, "upload "+remotePath)
+	return nil
+}
+
+func (r *recorder) Download(remotePath string, writer io.Writer) error {
+	r.lines = append(r.lines, "download "+remotePath)
+	return nil
+}
+
+func (r *recorder) SetOutput(stdout, stderr io.Writer) {}
+
+func (r *recorder) Close() error {
+	return nil
+}
+
+func (r *recorder) script() string {
+	return strings.Join(r.lines, "\n")
+}
+
+func Test_InstallServer(t *testing.T) {
+	operator := &recorder{}
+	err := InstallServer(operator, Server{
+		Host:                "192.168.0.100",
+		PrivilegeEscalation: EscalateSudo,
+		Cluster:             true,
+		K3sVersion:          "v1.19.5+k3s1",
+		TLSSANs:             []string{"k3s.example.com"},
+		Registry:            Registry{Mirrors: []string{"docker.io=https://mirror.example.com"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "upload /tmp/k3sup-registries.yaml\n" +
+		"sudo install -D -m 600 /tmp/k3sup-registries.yaml /etc/rancher/k3s/registries.yaml && rm /tmp/k3sup-registries.yaml\n" +
+		"curl -sfL https://get.k3s.io | INSTALL_K3S_EXEC='server --cluster-init --tls-san 192.168.0.100 --tls-san k3s.example.com' INSTALL_K3S_VERSION='v1.19.5+k3s1' sh -s -"
+	if got := operator.script(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func Test_JoinAgent(t *testing.T) {
+	cases := []struct {
+		opts Agent
+		want string
+	}{
+		{
+			Agent{Host: "192.168.0.101", ServerURL: "https://192.168.0.100:6443", Token: "K10token", K3sChannel: "stable"},
+			"curl -sfL https://get.k3s.io | K3S_URL='https://192.168.0.100:6443' K3S_TOKEN='K10token' INSTALL_K3S_EXEC='agent' INSTALL_K3S_CHANNEL='stable' sh -s -",
+		},
+		{
+			Agent{Host: "192.168.0.102", ServerURL: "https://192.168.0.100:6443", Token: "K10token", JoinAsServer: true},
+			"curl -sfL https://get.k3s.io | K3S_TOKEN='K10token' INSTALL_K3S_EXEC='server --server https://192.168.0.100:6443 --tls-san 192.168.0.102' sh -s -",
+		},
+		{
+			Agent{Host: "192.168.0.101", ServerURL: "https://192.168.0.100:6443", Token: "K10'token"},
+			`curl -sfL https://get.k3s.io | K3S_URL='https://192.168.0.100:6443' K3S_TOKEN='K10'\''token' INSTALL_K3S_EXEC='agent' sh -s -`,
+		},
+	}
+
+	for _, c := range cases {
+		operator := &recorder{}
+		if err := JoinAgent(operator, c.opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := operator.script(); got != c.want {
+			t.Errorf("want: %q, got: %q", c.want, got)
+		}
+	}
+}
+
+type debugLogger struct {
+	lines []string
+}
+
+func (l *debugLogger) Infof(format string, a ...interface{}) {}
+
+func (l *debugLogger) Debugf(format string, a ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, a...))
+}
+
+func Test_JoinAgent_RedactsTokenInLog(t *testing.T) {
+	logger := &debugLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+
+	operator := &recorder{}
+	if err := JoinAgent(operator, Agent{Host: "192.168.0.101", ServerURL: "https://192.168.0.100:6443", Token: "K10token"}); err != nil {
+		t.Fatal(err)
+	}
+
+	logged := strings.Join(logger.lines, "")
+	if strings.Contains(logged, "K10token") {
+		t.Errorf("want the join-token left out of the logged command, got: %q", logged)
+	}
+	if !strings.Contains(logged, "K3S_TOKEN='***'") {
+		t.Errorf("want the redacted token in the logged command, got: %q", logged)
+	}
+}
+
+func Test_JoinAgent_InstallerError(t *testing.T) {
+	operator := &recorder{fail: "curl"}
+	err := JoinAgent(operator, Agent{Host: "192.168.0.101", ServerURL: "https://192.168.0.100:6443", Token: "K10token"})
+
+	installerErr, ok := err.(*InstallerError)
+	if !ok {
+		t.Fatalf("want an *InstallerError, got: %T %v", err, err)
+	}
+	if installerErr.Host != "192.168.0.101" {
+		t.Errorf("want the host in the error, got: %q", installerErr.Host)
+	}
+
+	var commandErr *kssh.CommandError
+	if !errors.As(err, &commandErr) || commandErr.ExitStatus != 1 {
+		t.Errorf("want the command's error to be wrapped, got: %s", fmt.Sprint(err))
+	}
+}
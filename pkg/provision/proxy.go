@@ -0,0 +1,131 @@
+package provision
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// Proxy are the proxies used by the installer to download k3s, and by k3s
+// itself to pull images
+type Proxy struct {
+	HTTP    string `json:"http-proxy,omitempty"`
+	HTTPS   string `json:"https-proxy,omitempty"`
+	NoProxy string `json:"no-proxy,omitempty"`
+}
+
+// Validate returns an error for a proxy which is not a URL, or a no-proxy
+// list with spaces
+func (p Proxy) Validate() error {
+	for _, proxy := range []struct {
+		flag  string
+		value string
+	}{
+		{"http-proxy", p.HTTP},
+		{"https-proxy", p.HTTPS},
+	} {
+		if len(proxy.value) == 0 {
+			continue
+		}
+
+		u, err := url.Parse(proxy.value)
+		if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return fmt.Errorf("invalid --%s %q, give a URL such as http://proxy.example.com:3128", proxy.flag, proxy.value)
+		}
+	}
+
+	if strings.ContainsAny(p.NoProxy, " \t") {
+		return fmt.Errorf("invalid --no-proxy %q, separate hosts with commas", p.NoProxy)
+	}
+
+	return nil
+}
+
+// Enabled reports whether an HTTP or HTTPS proxy is set
+func (p Proxy) Enabled() bool {
+	return len(p.HTTP) > 0 || len(p.HTTPS) > 0
+}
+
+// vars returns the proxy variables in both cases, as curl only reads
+// http_proxy in lower case
+func (p Proxy) vars() [][2]string {
+	vars := [][2]string{}
+	if !p.Enabled() {
+		return vars
+	}
+
+	for _, v := range []struct {
+		name  string
+		value string
+	}{
+		{"HTTP_PROXY", p.HTTP},
+		{"HTTPS_PROXY", p.HTTPS},
+		{"NO_PROXY", p.NoProxy},
+	} {
+		if len(v.value) > 0 {
+			vars = append(vars, [2]string{v.name, v.value}, [2]string{strings.ToLower(v.name), v.value})
+		}
+	}
+	return vars
+}
+
+// env returns the proxy variables for the installer's environment
+func (p Proxy) env() []string {
+	env := []string{}
+	for _, v := range p.vars() {
+		env = append(env, v[0]+"="+shellQuote(v[1]))
+	}
+	return env
+}
+
+// envFileLines returns the proxy variables as quoted lines for the
+// environment file of the k3s service
+func (p Proxy) envFileLines() []string {
+	lines := []string{}
+	for _, v := range p.vars() {
+		lines = append(lines, shellQuote(v[0]+"="+v[1]))
+	}
+	return lines
+}
+
+// serviceEnvFile returns the environment file which the installer wrote for
+// the k3s service, and the command which restarts it. The installer uses
+// openrc when /sbin/openrc-run exists, as on Alpine, and otherwise systemd.
+func serviceEnvFile(operator kssh.Operator, service string) (string, string, error) {
+	res, err := operator.ExecuteQuiet("if [ -x /sbin/openrc-run ]; then echo openrc; fi")
+	if err != nil {
+		return "", "", fmt.Errorf("unable to find the init system of the node: %s", err)
+	}
+
+	if strings.TrimSpace(string(res.StdOut)) == "openrc" {
+		return fmt.Sprintf("/etc/rancher/k3s/%s.env", service), "rc-service " + service + " restart", nil
+	}
+
+	return fmt.Sprintf("/etc/systemd/system/%s.service.env", service), "systemctl restart " + service, nil
+}
+
+// writeProxyEnv adds the proxy to the environment file of the k3s service,
+// replacing any proxy written by the installer, then restarts the service so
+// that the proxy is used to pull images
+func writeProxyEnv(operator kssh.Operator, proxy Proxy, service, sudoPrefix string) error {
+	if !proxy.Enabled() {
+		return nil
+	}
+
+	envFile, restart, err := serviceEnvFile(operator, service)
+	if err != nil {
+		return err
+	}
+
+	command := fmt.Sprintf(`%ssed -i '/^\(HTTP_PROXY\|HTTPS_PROXY\|NO_PROXY\|http_proxy\|https_proxy\|no_proxy\)=/d' %s && printf '%%s\n' %s | %stee -a %s > /dev/null && %s%s`,
+		sudoPrefix, envFile, strings.Join(proxy.envFileLines(), " "), sudoPrefix, envFile, sudoPrefix, restart)
+
+	log.Debugf("ssh: %s\n", command)
+	if _, err := operator.Execute(command); err != nil {
+		return fmt.Errorf("unable to add the proxy to %s: %s", envFile, err)
+	}
+
+	return nil
+}
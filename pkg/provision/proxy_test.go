@@ -0,0 +1,66 @@
+package provision
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_installerCommand_Proxy(t *testing.T) {
+	proxy := Proxy{HTTPS: "http://proxy:3128", NoProxy: "10.0.0.0/8"}
+	got := installerCommand([]string{"INSTALL_K3S_EXEC='server'"}, Airgap{}, proxy, EscalateSudo)
+
+	want := "HTTPS_PROXY='http://proxy:3128' https_proxy='http://proxy:3128' NO_PROXY='10.0.0.0/8' no_proxy='10.0.0.0/8' curl -sfL https://get.k3s.io | " +
+		"INSTALL_K3S_EXEC='server' HTTPS_PROXY='http://proxy:3128' https_proxy='http://proxy:3128' NO_PROXY='10.0.0.0/8' no_proxy='10.0.0.0/8' sh -s -"
+	if got != want {
+		t.Errorf("want: %q, got: %q", want, got)
+	}
+}
+
+func Test_writeProxyEnv(t *testing.T) {
+	script := &recorder{}
+	if err := writeProxyEnv(script, Proxy{HTTP: "http://proxy:3128"}, "k3s-agent", "sudo "); err != nil {
+		t.Fatal(err)
+	}
+
+	got := script.script()
+	for _, want := range []string{
+		"sudo sed -i",
+		"printf '%s\\n' 'HTTP_PROXY=http://proxy:3128' 'http_proxy=http://proxy:3128' | sudo tee -a /etc/systemd/system/k3s-agent.service.env",
+		"sudo systemctl restart k3s-agent",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %q", want, got)
+		}
+	}
+
+	script = &recorder{stdout: map[string]string{"if [ -x /sbin/openrc-run ]": "openrc\n"}}
+	if err := writeProxyEnv(script, Proxy{HTTP: "http://proxy:3128"}, "k3s-agent", "doas "); err != nil {
+		t.Fatal(err)
+	}
+
+	got = script.script()
+	for _, want := range []string{
+		"| doas tee -a /etc/rancher/k3s/k3s-agent.env",
+		"doas rc-service k3s-agent restart",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q with openrc in: %q", want, got)
+		}
+	}
+
+	script = &recorder{}
+	writeProxyEnv(script, Proxy{}, "k3s", "sudo ")
+	if len(script.lines) != 0 {
+		t.Errorf("want nothing written without a proxy, got: %v", script.lines)
+	}
+}
+
+func Test_Proxy_Validate(t *testing.T) {
+	if err := (Proxy{HTTP: "proxy:3128"}).Validate(); err == nil {
+		t.Errorf("want an error for a proxy without a scheme")
+	}
+
+	if err := (Proxy{HTTPS: "http://proxy:3128", NoProxy: "localhost, 10.0.0.0/8"}).Validate(); err == nil {
+		t.Errorf("want an error for a no-proxy list with spaces")
+	}
+}
@@ -0,0 +1,107 @@
+package provision
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// RegistriesPath is where k3s reads the registry mirrors for containerd
+// from when it starts
+const RegistriesPath = "/etc/rancher/k3s/registries.yaml"
+
+// Registry configures the registries which containerd pulls from, either
+// with a registries.yaml file or with registry=endpoint mirrors
+type Registry struct {
+	File    string   `json:"registries-file,omitempty"`
+	Mirrors []string `json:"registry-mirrors,omitempty"`
+}
+
+// Validate returns an error when both a file and mirrors are given, or for
+// a mirror which is not registry=endpoint
+func (r Registry) Validate() error {
+	if len(r.File) > 0 && len(r.Mirrors) > 0 {
+		return fmt.Errorf("give either --registry-mirrors-file or --registry-mirror, not both")
+	}
+
+	_, err := RegistriesYAML(r.Mirrors)
+	return err
+}
+
+// Enabled reports whether a file or mirrors are set
+func (r Registry) Enabled() bool {
+	return len(r.File) > 0 || len(r.Mirrors) > 0
+}
+
+// RegistriesYAML writes a registries.yaml with the endpoints of each
+// registry=endpoint mirror, in the order they were given
+func RegistriesYAML(mirrors []string) ([]byte, error) {
+	registries := []string{}
+	endpoints := map[string][]string{}
+
+	for _, mirror := range mirrors {
+		parts := strings.SplitN(mirror, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			return nil, fmt.Errorf("invalid --registry-mirror %q, give registry=endpoint", mirror)
+		}
+
+		u, err := url.Parse(parts[1])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return nil, fmt.Errorf("invalid endpoint in --registry-mirror %q, give a URL such as https://mirror.example.com:5000", mirror)
+		}
+
+		if _, ok := endpoints[parts[0]]; !ok {
+			registries = append(registries, parts[0])
+		}
+		endpoints[parts[0]] = append(endpoints[parts[0]], parts[1])
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("mirrors:\n")
+	for _, registry := range registries {
+		fmt.Fprintf(buf, "  %q:\n    endpoint:\n", registry)
+		for _, endpoint := range endpoints[registry] {
+			fmt.Fprintf(buf, "      - %q\n", endpoint)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// uploadRegistries places registries.yaml on the node before k3s is
+// installed, so that containerd uses the mirrors from the start
+func uploadRegistries(operator kssh.Operator, registry Registry, sudoPrefix string) error {
+	if !registry.Enabled() {
+		return nil
+	}
+
+	var data []byte
+	var err error
+	if len(registry.File) > 0 {
+		data, err = ioutil.ReadFile(localPath(registry.File))
+	} else {
+		data, err = RegistriesYAML(registry.Mirrors)
+	}
+	if err != nil {
+		return err
+	}
+
+	tmpPath := "/tmp/k3sup-registries.yaml"
+	log.Infof("Uploading registry mirrors to %s\n", RegistriesPath)
+
+	if err := operator.Upload(bytes.NewReader(data), tmpPath); err != nil {
+		return err
+	}
+
+	command := fmt.Sprintf("%sinstall -D -m 600 %s %s && rm %s", sudoPrefix, tmpPath, RegistriesPath, tmpPath)
+	log.Debugf("ssh: %s\n", command)
+	if _, err := operator.Execute(command); err != nil {
+		return fmt.Errorf("unable to place %s: %s", RegistriesPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package provision
+
+import "testing"
+
+func Test_RegistriesYAML(t *testing.T) {
+	got, err := RegistriesYAML([]string{
+		"docker.io=https://mirror.example.com:5000",
+		"registry.example.com=http://10.0.0.5:5000",
+		"docker.io=https://registry-1.docker.io",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `mirrors:
+  "docker.io":
+    endpoint:
+      - "https://mirror.example.com:5000"
+      - "https://registry-1.docker.io"
+  "registry.example.com":
+    endpoint:
+      - "http://10.0.0.5:5000"
+`
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	for _, mirror := range []string{"docker.io", "=https://mirror", "docker.io=mirror:5000"} {
+		if _, err := RegistriesYAML([]string{mirror}); err == nil {
+			t.Errorf("want an error for %q", mirror)
+		}
+	}
+}
+
+func Test_Registry_Validate_FileAndMirrors(t *testing.T) {
+	registry := Registry{File: "registries.yaml", Mirrors: []string{"docker.io=https://mirror"}}
+	if err := registry.Validate(); err == nil {
+		t.Errorf("want an error for a file and mirrors")
+	}
+}
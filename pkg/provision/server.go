@@ -0,0 +1,150 @@
+package provision
+
+import (
+	kssh "github.com/alexellis/k3sup/pkg/ssh"
+)
+
+// Server are the options for installing k3s on a server. Host is the
+// address which the server's certificate is valid for, along with TLSSANs.
+type Server struct {
+	Host                string
+	PrivilegeEscalation string
+
+	// Cluster creates an HA cluster with embedded etcd, or Datastore
+	// points the server at an external datastore
+	Cluster   bool
+	Datastore Datastore
+
+	K3sVersion   string
+	K3sChannel   string
+	K3sExtraArgs string
+	TLSSANs      []string
+
+	Airgap   Airgap
+	Proxy    Proxy
+	Registry Registry
+}
+
+// InstallServer runs the k3s installer for a server on the node, which must
+// already be checked to be one which can run k3s
+func InstallServer(operator kssh.Operator, opts Server) error {
+	sudoPrefix := escalationPrefix(opts.PrivilegeEscalation)
+
+	if opts.Airgap.Enabled {
+		if err := uploadAirgap(operator, opts.Airgap, sudoPrefix); err != nil {
+			return err
+		}
+	}
+
+	if err := uploadRegistries(operator, opts.Registry, sudoPrefix); err != nil {
+		return err
+	}
+
+	clusterStr := ""
+	if opts.Cluster {
+		clusterStr = "--cluster-init"
+	}
+
+	env := []string{
+		installExecEnv("server", clusterStr, "--tls-san", opts.Host, tlsSANArgs(opts.TLSSANs), opts.K3sExtraArgs),
+	}
+	env = append(env, versionEnv(opts.K3sVersion, opts.K3sChannel, opts.Airgap)...)
+
+	if len(opts.Datastore.Endpoint) > 0 {
+		datastoreEnv, err := uploadDatastoreTLS(operator, opts.Datastore, sudoPrefix)
+		if err != nil {
+			return err
+		}
+		env = append(env, datastoreEnv...)
+	}
+
+	installCommand := installerCommand(env, opts.Airgap, opts.Proxy, opts.PrivilegeEscalation)
+
+	log.Debugf("ssh: %s\n", opts.Datastore.Redact(installCommand))
+	res, err := operator.Execute(installCommand)
+	if err != nil {
+		return &InstallerError{Host: opts.Host, Err: err}
+	}
+
+	log.Debugf("Result: %s %s\n", string(res.StdOut), string(res.StdErr))
+
+	return writeProxyEnv(operator, opts.Proxy, "k3s", sudoPrefix)
+}
+
+// Agent are the options for joining a node to a server as an agent, or as
+// another server with JoinAsServer. ServerURL is the Kubernetes API of the
+// server such as https://192.168.0.100:6443, which is not needed by a
+// server which uses an external Datastore.
+type Agent struct {
+	Host                string
+	ServerURL           string
+	Token               string
+	PrivilegeEscalation string
+
+	JoinAsServer bool
+	Datastore    Datastore
+
+	K3sVersion   string
+	K3sChannel   string
+	K3sExtraArgs string
+	TLSSANs      []string
+
+	Airgap   Airgap
+	Proxy    Proxy
+	Registry Registry
+}
+
+// JoinAgent runs the k3s installer for an agent, or a server with
+// JoinAsServer, on the node
+func JoinAgent(operator kssh.Operator, opts Agent) error {
+	sudoPrefix := escalationPrefix(opts.PrivilegeEscalation)
+
+	if opts.Airgap.Enabled {
+		if err := uploadAirgap(operator, opts.Airgap, sudoPrefix); err != nil {
+			return err
+		}
+	}
+
+	if err := uploadRegistries(operator, opts.Registry, sudoPrefix); err != nil {
+		return err
+	}
+
+	env := []string{
+		"K3S_TOKEN=" + shellQuote(opts.Token),
+	}
+
+	if opts.JoinAsServer && len(opts.Datastore.Endpoint) > 0 {
+		datastoreEnv, err := uploadDatastoreTLS(operator, opts.Datastore, sudoPrefix)
+		if err != nil {
+			return err
+		}
+		env = append(env, datastoreEnv...)
+
+		env = append(env, installExecEnv("server", "--tls-san", opts.Host, tlsSANArgs(opts.TLSSANs), opts.K3sExtraArgs))
+	} else if opts.JoinAsServer {
+		env = append(env, installExecEnv("server", "--server", opts.ServerURL, "--tls-san", opts.Host, tlsSANArgs(opts.TLSSANs), opts.K3sExtraArgs))
+	} else {
+		env = append([]string{"K3S_URL=" + shellQuote(opts.ServerURL)}, env...)
+		env = append(env, installExecEnv("agent", opts.K3sExtraArgs))
+	}
+	env = append(env, versionEnv(opts.K3sVersion, opts.K3sChannel, opts.Airgap)...)
+
+	installCommand := installerCommand(env, opts.Airgap, opts.Proxy, opts.PrivilegeEscalation)
+	log.Debugf("ssh: %s\n", RedactToken(opts.Datastore.Redact(installCommand), opts.Token))
+
+	res, err := operator.Execute(installCommand)
+	if err != nil {
+		return &InstallerError{Host: opts.Host, Err: err}
+	}
+
+	if len(res.StdErr) > 0 {
+		log.Debugf("Logs: %s", res.StdErr)
+	}
+	log.Debugf("Output: %s", string(res.StdOut))
+
+	service := "k3s-agent"
+	if opts.JoinAsServer {
+		service = "k3s"
+	}
+	return writeProxyEnv(operator, opts.Proxy, service, sudoPrefix)
+}
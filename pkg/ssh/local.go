@@ -0,0 +1,108 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// LocalOperator runs commands on this machine with /bin/sh, for installing
+// k3s without SSH
+type LocalOperator struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func NewLocalOperator() *LocalOperator {
+	return &LocalOperator{}
+}
+
+// SetOutput sets where Execute copies the output of commands, instead of
+// os.Stdout and os.Stderr
+func (l *LocalOperator) SetOutput(stdout, stderr io.Writer) {
+	l.stdout = stdout
+	l.stderr = stderr
+}
+
+func (l *LocalOperator) Close() error {
+	return nil
+}
+
+// Execute runs command, its output is copied to os.Stdout and os.Stderr, or
+// the writers given to SetOutput, as well as being returned
+func (l *LocalOperator) Execute(command string) (CommandRes, error) {
+	stdout, stderr := l.stdout, l.stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	return l.execute(command, stdout, stderr)
+}
+
+// ExecuteQuiet runs command and only returns its output
+func (l *LocalOperator) ExecuteQuiet(command string) (CommandRes, error) {
+	return l.execute(command, ioutil.Discard, ioutil.Discard)
+}
+
+func (l *LocalOperator) execute(command string, stdout, stderr io.Writer) (CommandRes, error) {
+	output := bytes.Buffer{}
+	errorOutput := bytes.Buffer{}
+
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stdout = io.MultiWriter(stdout, &output)
+	cmd.Stderr = io.MultiWriter(stderr, &errorOutput)
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return CommandRes{}, &CommandError{
+				Command:    command,
+				ExitStatus: exitErr.ExitCode(),
+				StdErr:     errorOutput.Bytes(),
+			}
+		}
+		return CommandRes{}, err
+	}
+
+	return CommandRes{
+		StdErr: errorOutput.Bytes(),
+		StdOut: output.Bytes(),
+	}, nil
+}
+
+// Upload copies the contents of r to remotePath, the file is only readable
+// by the current user
+func (l *LocalOperator) Upload(r io.Reader, remotePath string) error {
+	file, err := os.OpenFile(filepath.Clean(remotePath), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to upload to %s: %s", remotePath, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return fmt.Errorf("unable to upload to %s: %s", remotePath, err)
+	}
+
+	return nil
+}
+
+// Download copies the contents of remotePath to w
+func (l *LocalOperator) Download(remotePath string, w io.Writer) error {
+	file, err := os.Open(filepath.Clean(remotePath))
+	if err != nil {
+		return fmt.Errorf("unable to download %s: %s", remotePath, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("unable to download %s: %s", remotePath, err)
+	}
+
+	return nil
+}
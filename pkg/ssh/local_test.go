@@ -0,0 +1,78 @@
+package ssh
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_LocalOperator_Execute(t *testing.T) {
+	operator := NewLocalOperator()
+	operator.SetOutput(ioutil.Discard, ioutil.Discard)
+
+	res, err := operator.Execute("echo $((1 + 2)); echo warning >&2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(res.StdOut) != "3\n" || string(res.StdErr) != "warning\n" {
+		t.Errorf("want: %q and %q, got: %q and %q", "3\n", "warning\n", res.StdOut, res.StdErr)
+	}
+
+	_, err = operator.ExecuteQuiet("echo failed >&2; exit 3")
+	commandErr, ok := err.(*CommandError)
+	if !ok {
+		t.Fatalf("want a CommandError, got: %v", err)
+	}
+	if commandErr.ExitStatus != 3 || string(commandErr.StdErr) != "failed\n" {
+		t.Errorf("want exit status 3 with stderr, got: %d %q", commandErr.ExitStatus, commandErr.StdErr)
+	}
+}
+
+func Test_LocalOperator_Upload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "install.sh")
+	if err := NewLocalOperator().Upload(strings.NewReader("#!/bin/sh\n"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("want mode 0600, got: %o", info.Mode().Perm())
+	}
+}
+
+func Test_LocalOperator_Download(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot")
+	if err := ioutil.WriteFile(path, []byte("etcd"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := NewLocalOperator().Download(path, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "etcd" {
+		t.Errorf("want %q, got: %q", "etcd", out.String())
+	}
+
+	if err := NewLocalOperator().Download(filepath.Join(dir, "missing"), &out); err == nil {
+		t.Errorf("want an error for a missing file")
+	}
+}
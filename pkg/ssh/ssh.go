@@ -2,26 +2,71 @@ package ssh
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"strings"
 	"sync"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
+// Operator runs commands on a node, over SSH with SSHOperator or on this
+// machine with LocalOperator
+type Operator interface {
+	Execute(command string) (CommandRes, error)
+	ExecuteQuiet(command string) (CommandRes, error)
+	Upload(r io.Reader, remotePath string) error
+	Download(remotePath string, w io.Writer) error
+	SetOutput(stdout, stderr io.Writer)
+	Close() error
+}
+
 type SSHOperator struct {
 	conn *ssh.Client
+	jump *ssh.Client
+
+	stdout io.Writer
+	stderr io.Writer
+
+	forwardAgent bool
+	agentConn    io.Closer
+}
+
+// SetOutput sets where Execute copies the output of commands, instead of
+// os.Stdout and os.Stderr
+func (s *SSHOperator) SetOutput(stdout, stderr io.Writer) {
+	s.stdout = stdout
+	s.stderr = stderr
 }
 
 func (s *SSHOperator) Close() error {
+	err := s.conn.Close()
+
+	if s.agentConn != nil {
+		s.agentConn.Close()
+	}
+
+	if s.jump != nil {
+		if jumpErr := s.jump.Close(); jumpErr != nil && err == nil {
+			err = jumpErr
+		}
+	}
 
-	return s.conn.Close()
+	return err
 }
 
 func NewSSHOperator(address string, config *ssh.ClientConfig) (*SSHOperator, error) {
 	conn, err := ssh.Dial("tcp", address, config)
 	if err != nil {
-		return nil, err
+		return nil, &ConnectionError{
+			Address: address,
+			Reason:  connectionFailureReason(err),
+			Err:     err,
+		}
 	}
 
 	operator := SSHOperator{
@@ -31,25 +76,104 @@ func NewSSHOperator(address string, config *ssh.ClientConfig) (*SSHOperator, err
 	return &operator, nil
 }
 
-func (s *SSHOperator) Execute(command string) (commandRes, error) {
+// NewSSHOperatorWithJump connects to address by first connecting to
+// jumpAddress and then tunnelling through it, like ssh -J
+func NewSSHOperatorWithJump(jumpAddress string, jumpConfig *ssh.ClientConfig, address string, config *ssh.ClientConfig) (*SSHOperator, error) {
+	jump, err := ssh.Dial("tcp", jumpAddress, jumpConfig)
+	if err != nil {
+		return nil, &ConnectionError{
+			Address: jumpAddress,
+			Reason:  connectionFailureReason(err),
+			Err:     err,
+		}
+	}
+
+	tunnel, err := jump.Dial("tcp", address)
+	if err != nil {
+		jump.Close()
+		return nil, &ConnectionError{
+			Address: address,
+			Reason:  connectionFailureReason(err),
+			Err:     err,
+		}
+	}
 
+	clientConn, chans, reqs, err := ssh.NewClientConn(tunnel, address, config)
+	if err != nil {
+		tunnel.Close()
+		jump.Close()
+		return nil, &ConnectionError{
+			Address: address,
+			Reason:  connectionFailureReason(err),
+			Err:     err,
+		}
+	}
+
+	operator := SSHOperator{
+		conn: ssh.NewClient(clientConn, chans, reqs),
+		jump: jump,
+	}
+
+	return &operator, nil
+}
+
+// ForwardAgent forwards keyring to the host for the commands run by Execute,
+// like ssh -A. conn is the connection to the agent, which is closed along
+// with the operator.
+func (s *SSHOperator) ForwardAgent(keyring agent.Agent, conn io.Closer) error {
+	if err := agent.ForwardToAgent(s.conn, keyring); err != nil {
+		return err
+	}
+
+	s.forwardAgent = true
+	s.agentConn = conn
+	return nil
+}
+
+// Execute runs command on the host, its output is copied to os.Stdout and
+// os.Stderr, or the writers given to SetOutput, as well as being returned
+func (s *SSHOperator) Execute(command string) (CommandRes, error) {
+	stdout, stderr := s.stdout, s.stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	return s.execute(command, stdout, stderr)
+}
+
+// ExecuteQuiet runs command on the host and only returns its output, for
+// commands which print secrets or whose output is to be parsed
+func (s *SSHOperator) ExecuteQuiet(command string) (CommandRes, error) {
+	return s.execute(command, ioutil.Discard, ioutil.Discard)
+}
+
+func (s *SSHOperator) execute(command string, stdout, stderr io.Writer) (CommandRes, error) {
 	sess, err := s.conn.NewSession()
 	if err != nil {
-		return commandRes{}, err
+		return CommandRes{}, err
 	}
 
 	defer sess.Close()
 
+	if s.forwardAgent {
+		if err := agent.RequestAgentForwarding(sess); err != nil {
+			return CommandRes{}, err
+		}
+	}
+
 	sessStdOut, err := sess.StdoutPipe()
 	if err != nil {
-		return commandRes{}, err
+		return CommandRes{}, err
 	}
 
 	output := bytes.Buffer{}
 
 	wg := sync.WaitGroup{}
 
-	stdOutWriter := io.MultiWriter(os.Stdout, &output)
+	stdOutWriter := io.MultiWriter(stdout, &output)
 	wg.Add(1)
 	go func() {
 		io.Copy(stdOutWriter, sessStdOut)
@@ -57,11 +181,11 @@ func (s *SSHOperator) Execute(command string) (commandRes, error) {
 	}()
 	sessStderr, err := sess.StderrPipe()
 	if err != nil {
-		return commandRes{}, err
+		return CommandRes{}, err
 	}
 
 	errorOutput := bytes.Buffer{}
-	stdErrWriter := io.MultiWriter(os.Stderr, &errorOutput)
+	stdErrWriter := io.MultiWriter(stderr, &errorOutput)
 	wg.Add(1)
 	go func() {
 		io.Copy(stdErrWriter, sessStderr)
@@ -73,21 +197,117 @@ func (s *SSHOperator) Execute(command string) (commandRes, error) {
 	wg.Wait()
 
 	if err != nil {
-		return commandRes{}, err
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			return CommandRes{}, &CommandError{
+				Command:    command,
+				ExitStatus: exitErr.ExitStatus(),
+				StdErr:     errorOutput.Bytes(),
+			}
+		}
+		return CommandRes{}, err
 	}
 
-	return commandRes{
+	return CommandRes{
 		StdErr: errorOutput.Bytes(),
 		StdOut: output.Bytes(),
 	}, nil
 }
 
-type commandRes struct {
+// ConnectionError is returned by NewSSHOperator when a connection could not
+// be established, Reason gives a short explanation such as
+// "authentication failed" or "connection refused"
+type ConnectionError struct {
+	Address string
+	Reason  string
+	Err     error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("unable to connect to %s over ssh, %s: %s", e.Address, e.Reason, e.Err)
+}
+
+// CommandError is returned by Execute when the remote command exits with a
+// non-zero status
+type CommandError struct {
+	Command    string
+	ExitStatus int
+	StdErr     []byte
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command exited with status %d: %s", e.ExitStatus, strings.TrimSpace(e.Command))
+}
+
+func connectionFailureReason(err error) string {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return "timed out"
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "unable to authenticate"):
+		return "authentication failed"
+	case strings.Contains(msg, "connection refused"):
+		return "connection refused"
+	case strings.Contains(msg, "no route to host"), strings.Contains(msg, "network is unreachable"):
+		return "host unreachable"
+	case strings.Contains(msg, "no such host"):
+		return "unknown host"
+	case strings.Contains(msg, "i/o timeout"):
+		return "timed out"
+	}
+	return "connection failed"
+}
+
+// Upload copies the contents of r to remotePath on the host, the file is
+// only readable by the SSH user
+func (s *SSHOperator) Upload(r io.Reader, remotePath string) error {
+	sess, err := s.conn.NewSession()
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	errorOutput := bytes.Buffer{}
+	sess.Stdin = r
+	sess.Stderr = &errorOutput
+
+	if err := sess.Run(fmt.Sprintf("umask 077 && cat > '%s'", remotePath)); err != nil {
+		return fmt.Errorf("unable to upload to %s: %s %s", remotePath, err, strings.TrimSpace(errorOutput.String()))
+	}
+
+	return nil
+}
+
+// Download copies the contents of remotePath on the host to w, the file has
+// to be readable by the SSH user
+func (s *SSHOperator) Download(remotePath string, w io.Writer) error {
+	sess, err := s.conn.NewSession()
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	errorOutput := bytes.Buffer{}
+	sess.Stdout = w
+	sess.Stderr = &errorOutput
+
+	if err := sess.Run(fmt.Sprintf("cat '%s'", remotePath)); err != nil {
+		return fmt.Errorf("unable to download %s: %s %s", remotePath, err, strings.TrimSpace(errorOutput.String()))
+	}
+
+	return nil
+}
+
+// CommandRes is the output of a command run by Execute or ExecuteQuiet
+type CommandRes struct {
 	StdOut []byte
 	StdErr []byte
 }
 
-func executeCommand(cmd string) (commandRes, error) {
+func executeCommand(cmd string) (CommandRes, error) {
 
-	return commandRes{}, nil
+	return CommandRes{}, nil
 }
@@ -0,0 +1,23 @@
+package ssh
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_connectionFailureReason(t *testing.T) {
+	cases := map[string]string{
+		"ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey]": "authentication failed",
+		"dial tcp 192.168.0.100:22: connect: connection refused":                                 "connection refused",
+		"dial tcp 192.168.0.100:22: connect: no route to host":                                   "host unreachable",
+		"dial tcp: lookup node-1: no such host":                                                  "unknown host",
+		"ssh: handshake failed: EOF":                                                             "connection failed",
+	}
+
+	for msg, want := range cases {
+		got := connectionFailureReason(errors.New(msg))
+		if want != got {
+			t.Errorf("%q, want: %q, got: %q", msg, want, got)
+		}
+	}
+}